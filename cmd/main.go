@@ -4,11 +4,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
 	"github.com/ga4gh/htsget-refserver/internal/htsserver"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
 )
 
 // main program entrypoint
@@ -21,6 +28,24 @@ func main() {
 		panic(configLoadError.Error())
 	}
 
+	// write structured logs to the configured logfile
+	err := htslog.Configure(
+		htsconfig.GetLogfile(),
+		htsconfig.GetLogLevel(),
+		htsconfig.GetLogMaxSize(),
+		htsconfig.GetLogMaxBackups(),
+	)
+	if err != nil {
+		panic("Problem setting up logging: " + err.Error())
+	}
+
+	// export trace spans to the configured OTLP collector, if any
+	htstrace.Configure(htsconfig.GetTracingEndpoint(), htsconfig.GetTracingServiceName())
+
+	if htsconfig.GetCommand() == htsconfig.CommandCheck {
+		os.Exit(check())
+	}
+
 	// load server routes
 	router, err := htsserver.SetRouter()
 	if err != nil {
@@ -29,6 +54,84 @@ func main() {
 
 	// start server
 	port := htsconfig.GetPort()
+	server, err := htsserver.NewServer(router)
+	if err != nil {
+		panic("Problem setting up TLS: " + err.Error())
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- htsserver.ListenAndServe(server)
+	}()
 	fmt.Printf("Server started on port %s!\n", port)
-	http.ListenAndServe(":"+port, router)
+	htslog.Info("server started", htslog.Fields{"port": port, "tls": htsconfig.IsTLSEnabled()})
+
+	// warm the header/index cache for the ids in the manifest, if any, while
+	// serving. the service is not ready until the warm-up has completed
+	if htsconfig.GetManifest() != "" {
+		entries, err := readManifest(htsconfig.GetManifest())
+		if err != nil {
+			panic("Problem reading manifest: " + err.Error())
+		}
+		htscache.StartWarmup(entries)
+	}
+
+	// on SIGTERM/SIGINT, drain in-flight requests before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err = <-serverErr:
+		htslog.Error("server stopped", htslog.Fields{"error": err})
+	case sig := <-signals:
+		htslog.Info("shutting down", htslog.Fields{"signal": sig.String()})
+		err = htsserver.Shutdown(server, htsconfig.GetShutdownTimeout())
+		htslog.Info("server stopped", htslog.Fields{"error": err})
+	}
+
+	// flush any remaining trace spans
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	htstrace.Shutdown(ctx)
+}
+
+// readManifest reads a manifest of ids
+func readManifest(path string) ([]*htscache.ManifestEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return htscache.ReadManifest(file)
+}
+
+// check runs the check command: each id in the manifest is resolved and the
+// header/index of its object loaded, and the outcome reported per id. nothing
+// is kept once the command exits, the cache is only warmed by a server started
+// with -manifest
+//
+// Returns
+//	(int): exit code, non-zero if the manifest could not be read or any id failed
+func check() int {
+	if htsconfig.GetManifest() == "" {
+		fmt.Fprintln(os.Stderr, "check: -manifest is required")
+		return 2
+	}
+	entries, err := readManifest(htsconfig.GetManifest())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "check: "+err.Error())
+		return 2
+	}
+
+	report := htscache.Warm(context.Background(), entries)
+	for _, result := range report.Results {
+		if result.Err != nil {
+			fmt.Printf("FAIL\t%s\t%s\t%s\n", result.Entry.Type, result.Entry.ID, result.Err.Error())
+		} else {
+			fmt.Printf("OK\t%s\t%s\t%s\n", result.Entry.Type, result.Entry.ID, result.Path)
+		}
+	}
+	fmt.Printf("resolved %d of %d ids\n", len(report.Results)-report.NumFailed(), len(report.Results))
+	if report.NumFailed() > 0 {
+		return 1
+	}
+	return 0
 }
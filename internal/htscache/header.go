@@ -0,0 +1,204 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests
+//
+// Module header parses the text header of a reads (SAM/BAM/CRAM) or variants
+// (VCF/BCF) object into its reference dictionary, read groups, programs,
+// samples and field definitions
+package htscache
+
+import (
+	"bufio"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Reference is a reference sequence declared in a header
+//
+// Attributes
+//	Name (string): reference sequence name
+//	Length (int64): reference sequence length, 0 if not declared
+type Reference struct {
+	Name   string `json:"name"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// HeaderRecord is a structured header line, as a map of its keys to their
+// values, e.g. the tags of a SAM @RG line (ID, SM, ...) or the keys of a VCF
+// ##INFO line (ID, Number, Type, Description, ...)
+type HeaderRecord map[string]string
+
+// Header is the parsed header of a reads (SAM/BAM/CRAM) or variants
+// (VCF/BCF) object. it is shared between requests and must not be modified
+//
+// Attributes
+//	Text (string): the full header, as text
+//	ReferenceNames ([]string): names of the reference sequences in the header, in order
+//	References ([]*Reference): reference sequences in the header, in order (@SQ or ##contig lines)
+//	ReadGroups ([]HeaderRecord): read groups of a reads header (@RG lines)
+//	Programs ([]HeaderRecord): programs of a reads header (@PG lines)
+//	Samples ([]string): sample names of a variants header (#CHROM line)
+//	Info ([]HeaderRecord): INFO field definitions of a variants header (##INFO lines)
+//	Format ([]HeaderRecord): FORMAT field definitions of a variants header (##FORMAT lines)
+type Header struct {
+	Text           string
+	ReferenceNames []string
+	References     []*Reference
+	ReadGroups     []HeaderRecord
+	Programs       []HeaderRecord
+	Samples        []string
+	Info           []HeaderRecord
+	Format         []HeaderRecord
+}
+
+// parseHeader parses the reference dictionary and other structured lines out
+// of a text header
+//
+// Arguments
+//	text (string): the full header, as text
+//	referenceRegex (*regexp.Regexp): matches a header line declaring a reference, capturing its name
+// Returns
+//	(*Header): the parsed header
+func parseHeader(text string, referenceRegex *regexp.Regexp) *Header {
+	header := new(Header)
+	header.Text = text
+	header.ReferenceNames = []string{}
+	header.References = []*Reference{}
+	header.ReadGroups = []HeaderRecord{}
+	header.Programs = []HeaderRecord{}
+	header.Samples = []string{}
+	header.Info = []HeaderRecord{}
+	header.Format = []HeaderRecord{}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 65536), len(text)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		submatches := referenceRegex.FindStringSubmatch(line)
+		if len(submatches) > 1 {
+			header.ReferenceNames = append(header.ReferenceNames, submatches[1])
+		}
+		header.parseLine(line)
+	}
+	return header
+}
+
+// parseLine adds a structured header line to the parsed header
+//
+//	Type: Header
+// Arguments
+//	line (string): header line
+func (header *Header) parseLine(line string) {
+	switch {
+	case strings.HasPrefix(line, "@SQ\t"):
+		record := parseSAMHeaderRecord(line)
+		header.References = append(header.References, newReference(record["SN"], record["LN"]))
+	case strings.HasPrefix(line, "@RG\t"):
+		header.ReadGroups = append(header.ReadGroups, parseSAMHeaderRecord(line))
+	case strings.HasPrefix(line, "@PG\t"):
+		header.Programs = append(header.Programs, parseSAMHeaderRecord(line))
+	case strings.HasPrefix(line, "##contig=<"):
+		record := parseVCFHeaderRecord(line[len("##contig="):])
+		header.References = append(header.References, newReference(record["ID"], record["length"]))
+	case strings.HasPrefix(line, "##INFO=<"):
+		header.Info = append(header.Info, parseVCFHeaderRecord(line[len("##INFO="):]))
+	case strings.HasPrefix(line, "##FORMAT=<"):
+		header.Format = append(header.Format, parseVCFHeaderRecord(line[len("##FORMAT="):]))
+	case strings.HasPrefix(line, "#CHROM\t"):
+		columns := strings.Split(line, "\t")
+		if len(columns) > 9 {
+			header.Samples = columns[9:]
+		}
+	}
+}
+
+// SampleReadGroups gets the ids of the read groups of some samples, as
+// declared by the SM tag of the @RG lines of a reads header
+//
+//	Type: Header
+// Arguments
+//	samples ([]string): sample names
+// Returns
+//	([]string): ids of the read groups of the samples, in header order
+func (header *Header) SampleReadGroups(samples []string) []string {
+	readGroups := []string{}
+	for _, readGroup := range header.ReadGroups {
+		for _, sample := range samples {
+			if readGroup["SM"] == sample {
+				readGroups = append(readGroups, readGroup["ID"])
+				break
+			}
+		}
+	}
+	return readGroups
+}
+
+// newReference creates a reference from its declared name and length
+func newReference(name string, length string) *Reference {
+	reference := new(Reference)
+	reference.Name = name
+	reference.Length, _ = strconv.ParseInt(length, 10, 64)
+	return reference
+}
+
+// parseSAMHeaderRecord parses the TAG:value fields of a SAM header line, e.g.
+// "@RG\tID:rg1\tSM:sample1"
+//
+// Arguments
+//	line (string): SAM header line
+// Returns
+//	(HeaderRecord): value of each tag
+func parseSAMHeaderRecord(line string) HeaderRecord {
+	record := HeaderRecord{}
+	for _, field := range strings.Split(line, "\t")[1:] {
+		if i := strings.Index(field, ":"); i > 0 {
+			record[field[:i]] = field[i+1:]
+		}
+	}
+	return record
+}
+
+// parseVCFHeaderRecord parses the key=value pairs of a structured VCF meta
+// line value, e.g. `<ID=DP,Number=1,Type=Integer,Description="Depth, total">`.
+// quoted values may contain commas and escaped quotes, and are unquoted
+//
+// Arguments
+//	value (string): structured value, enclosed in angle brackets
+// Returns
+//	(HeaderRecord): value of each key
+func parseVCFHeaderRecord(value string) HeaderRecord {
+	record := HeaderRecord{}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+	for len(value) > 0 {
+		i := strings.Index(value, "=")
+		if i < 0 {
+			break
+		}
+		key := value[:i]
+		value = value[i+1:]
+
+		var builder strings.Builder
+		if strings.HasPrefix(value, "\"") {
+			j := 1
+			for ; j < len(value) && value[j] != '"'; j++ {
+				if value[j] == '\\' && j+1 < len(value) {
+					j++
+				}
+				builder.WriteByte(value[j])
+			}
+			if j < len(value) {
+				j++
+			}
+			value = value[j:]
+		} else {
+			j := strings.Index(value, ",")
+			if j < 0 {
+				j = len(value)
+			}
+			builder.WriteString(value[:j])
+			value = value[j:]
+		}
+		record[key] = builder.String()
+		value = strings.TrimPrefix(value, ",")
+	}
+	return record
+}
@@ -0,0 +1,68 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests
+//
+// Module header_test tests header
+package htscache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadsHeader(t *testing.T) {
+	text := "@HD\tVN:1.6\tSO:coordinate\n" +
+		"@SQ\tSN:chr1\tLN:248956422\n" +
+		"@SQ\tSN:chrUn\tAS:GRCh38\n" +
+		"@RG\tID:rg1\tSM:sample1\tPL:ILLUMINA\n" +
+		"@PG\tID:STAR\tPN:STAR\tVN:2.5.1b\tCL:STAR --runThreadN 8\n" +
+		"@CO\tuser command line\n"
+	header := parseHeader(text, readsReferenceRegex)
+
+	assert.Equal(t, []*Reference{{"chr1", 248956422}, {"chrUn", 0}}, header.References)
+	assert.Equal(t, []HeaderRecord{{"ID": "rg1", "SM": "sample1", "PL": "ILLUMINA"}}, header.ReadGroups)
+	assert.Equal(t, []HeaderRecord{{"ID": "STAR", "PN": "STAR", "VN": "2.5.1b", "CL": "STAR --runThreadN 8"}}, header.Programs)
+	assert.Equal(t, []string{}, header.Samples)
+	assert.Equal(t, []HeaderRecord{}, header.Info)
+}
+
+func TestParseVariantsHeader(t *testing.T) {
+	text := "##fileformat=VCFv4.1\n" +
+		"##contig=<ID=1,length=249250621,assembly=b37>\n" +
+		"##contig=<ID=X>\n" +
+		"##INFO=<ID=DP,Number=1,Type=Integer,Description=\"Total depth, over all samples\">\n" +
+		"##INFO=<ID=DB,Number=0,Type=Flag,Description=\"dbSNP \\\"membership\\\"\">\n" +
+		"##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">\n" +
+		"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tHG00096\tHG00097\n"
+	header := parseHeader(text, variantsReferenceRegex)
+
+	assert.Equal(t, []*Reference{{"1", 249250621}, {"X", 0}}, header.References)
+	assert.Equal(t, []string{"HG00096", "HG00097"}, header.Samples)
+	assert.Equal(t, []HeaderRecord{
+		{"ID": "DP", "Number": "1", "Type": "Integer", "Description": "Total depth, over all samples"},
+		{"ID": "DB", "Number": "0", "Type": "Flag", "Description": "dbSNP \"membership\""},
+	}, header.Info)
+	assert.Equal(t, []HeaderRecord{{"ID": "GT", "Number": "1", "Type": "String", "Description": "Genotype"}}, header.Format)
+	assert.Equal(t, []HeaderRecord{}, header.ReadGroups)
+}
+
+var headerSampleReadGroupsTC = []struct {
+	samples []string
+	exp     []string
+}{
+	{[]string{"NA12878"}, []string{"RG1", "RG2"}},
+	{[]string{"NA12891", "NA12878"}, []string{"RG1", "RG2", "RG3"}},
+	{[]string{"NA00000"}, []string{}},
+}
+
+func TestHeaderSampleReadGroups(t *testing.T) {
+	text := "@HD\tVN:1.6\tSO:coordinate\n" +
+		"@RG\tID:RG1\tSM:NA12878\tPL:ILLUMINA\n" +
+		"@RG\tID:RG2\tSM:NA12878\n" +
+		"@RG\tID:RG3\tSM:NA12891\n" +
+		"@RG\tID:RG4\n"
+	header := parseHeader(text, readsReferenceRegex)
+	for _, tc := range headerSampleReadGroupsTC {
+		assert.Equal(t, tc.exp, header.SampleReadGroups(tc.samples), tc.samples)
+	}
+}
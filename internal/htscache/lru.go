@@ -0,0 +1,140 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests, so that
+// repeated requests on the same object do not run samtools/bcftools again
+//
+// Module lru contains a size-bounded, least recently used cache whose entries
+// expire after a time to live
+package htscache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// now gets the current time, replaced in tests
+var now = time.Now
+
+// cacheEntry a value held in the cache
+//
+// Attributes
+//	key (string): key the value is stored under
+//	value (interface{}): cached value
+//	expires (time.Time): time after which the value is no longer used
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// Cache is a least recently used cache holding up to a maximum number of
+// entries, each of which expires after a time to live. safe for concurrent use
+//
+// Attributes
+//	maxEntries (int): maximum number of entries, the least recently used are evicted first
+//	ttl (time.Duration): time an entry is used for after being set
+//	order (*list.List): entries, from most to least recently used
+//	entries (map[string]*list.Element): elements of order by key
+type Cache struct {
+	maxEntries int
+	ttl        time.Duration
+	mutex      sync.Mutex
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+// newCache instantiates an empty cache
+//
+// Arguments
+//	maxEntries (int): maximum number of entries held
+//	ttl (time.Duration): time an entry is used for after being set
+// Returns
+//	(*Cache): the empty cache
+func newCache(maxEntries int, ttl time.Duration) *Cache {
+	cache := new(Cache)
+	cache.maxEntries = maxEntries
+	cache.ttl = ttl
+	cache.order = list.New()
+	cache.entries = map[string]*list.Element{}
+	return cache
+}
+
+// Get gets the value stored under a key, if present and not expired. the
+// entry becomes the most recently used
+//
+//	Type: Cache
+// Arguments
+//	key (string): key the value is stored under
+// Returns
+//	(interface{}): the cached value
+//	(bool): true if the value was found
+func (cache *Cache) Get(key string) (interface{}, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if now().After(entry.expires) {
+		cache.removeElement(element)
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// Set stores a value under a key, replacing any existing value. the least
+// recently used entries are evicted to stay within the maximum size
+//
+//	Type: Cache
+// Arguments
+//	key (string): key to store the value under
+//	value (interface{}): value to store
+func (cache *Cache) Set(key string, value interface{}) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.maxEntries <= 0 {
+		return
+	}
+	expires := now().Add(cache.ttl)
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(&cacheEntry{key, value, expires})
+	for cache.order.Len() > cache.maxEntries {
+		cache.removeElement(cache.order.Back())
+	}
+}
+
+// Len gets the number of entries in the cache, including expired entries not
+// yet evicted
+//
+//	Type: Cache
+// Returns
+//	(int): number of entries
+func (cache *Cache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
+
+// Purge removes all entries from the cache
+//
+//	Type: Cache
+func (cache *Cache) Purge() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.order.Init()
+	cache.entries = map[string]*list.Element{}
+}
+
+// removeElement removes an entry, the cache mutex must be held
+func (cache *Cache) removeElement(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,123 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests
+//
+// Module lru_test tests lru
+package htscache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setNow fixes the current time seen by the cache, returning a function
+// restoring the real clock
+func setNow(t time.Time) func() {
+	now = func() time.Time { return t }
+	return func() { now = time.Now }
+}
+
+func TestCacheGetSet(t *testing.T) {
+	cache := newCache(2, time.Minute)
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// replacing a value keeps a single entry
+	cache.Set("a", 10)
+	value, _ = cache.Get("a")
+	assert.Equal(t, 10, value)
+	assert.Equal(t, 2, cache.Len())
+
+	cache.Purge()
+	assert.Equal(t, 0, cache.Len())
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
+
+var cacheEvictionTC = []struct {
+	maxEntries int
+	sets       []string
+	gets       []string
+	expPresent []string
+	expAbsent  []string
+}{
+	// least recently set entry is evicted
+	{2, []string{"a", "b", "c"}, []string{}, []string{"b", "c"}, []string{"a"}},
+	// getting an entry makes it the most recently used
+	{2, []string{"a", "b", "c"}, []string{"a"}, []string{"a", "c"}, []string{"b"}},
+	// no entries are held if the maximum is zero
+	{0, []string{"a"}, []string{}, []string{}, []string{"a"}},
+}
+
+func TestCacheEviction(t *testing.T) {
+	for _, tc := range cacheEvictionTC {
+		cache := newCache(tc.maxEntries, time.Minute)
+		for i, key := range tc.sets[:len(tc.sets)-1] {
+			cache.Set(key, i)
+		}
+		for _, key := range tc.gets {
+			cache.Get(key)
+		}
+		cache.Set(tc.sets[len(tc.sets)-1], 0)
+		for _, key := range tc.expPresent {
+			_, ok := cache.Get(key)
+			assert.True(t, ok, key)
+		}
+		for _, key := range tc.expAbsent {
+			_, ok := cache.Get(key)
+			assert.False(t, ok, key)
+		}
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	start := time.Date(2020, 9, 1, 0, 0, 0, 0, time.UTC)
+	restore := setNow(start)
+	defer restore()
+
+	cache := newCache(10, time.Minute)
+	cache.Set("a", 1)
+
+	setNow(start.Add(59 * time.Second))
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+
+	// expired entries are removed when looked up
+	setNow(start.Add(61 * time.Second))
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, cache.Len())
+
+	// setting again renews the entry
+	cache.Set("a", 2)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	cache := newCache(50, time.Minute)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := strconv.Itoa((g * i) % 100)
+				cache.Set(key, i)
+				cache.Get(key)
+			}
+		}(g)
+	}
+	wg.Wait()
+	assert.True(t, cache.Len() <= 50)
+}
@@ -0,0 +1,306 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests, so that
+// repeated requests on the same object do not run samtools/bcftools again
+//
+// Module metadata loads each kind of object metadata, through the cache
+package htscache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/biogo/hts/bam"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// kinds of object metadata, each cached under its own key
+const (
+	kindReadsHeader    = "reads_header"
+	kindVariantsHeader = "variants_header"
+	kindBAMHeader      = "bam_header"
+	kindBAMIndex       = "bam_index"
+	kindObjectIDs      = "object_ids"
+)
+
+// readsReferenceRegex matches the reference sequence name of an @SQ line
+var readsReferenceRegex = regexp.MustCompile("^@SQ\tSN:(.+?)\t.+?$")
+
+// variantsReferenceRegex matches the contig id of a ##contig line
+var variantsReferenceRegex = regexp.MustCompile("^##contig=<.*?ID=(.+?)[,>]")
+
+// cacheSingleton cache shared by all requests, created from the configuration
+// on first use
+var cacheSingleton *Cache
+
+// cacheSingletonMutex guards cacheSingleton
+var cacheSingletonMutex sync.Mutex
+
+// getCache gets the shared cache, creating it if needed
+func getCache() *Cache {
+	cacheSingletonMutex.Lock()
+	defer cacheSingletonMutex.Unlock()
+	if cacheSingleton == nil {
+		cacheSingleton = newCache(htsconfig.GetCacheMaxEntries(), htsconfig.GetCacheTTL())
+	}
+	return cacheSingleton
+}
+
+// resetCache discards the shared cache, it is created again from the current
+// configuration on next use
+func resetCache() {
+	cacheSingletonMutex.Lock()
+	defer cacheSingletonMutex.Unlock()
+	cacheSingleton = nil
+}
+
+// cached gets the metadata of an object from the cache, or loads and caches
+// it. if caching is disabled, or the object version cannot be determined, the
+// metadata is loaded without caching. load errors are never cached
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the object
+//	kind (string): kind of metadata
+//	load (func() (interface{}, error)): loads the metadata from the object
+// Returns
+//	(interface{}): the metadata
+//	(error): if not nil, the metadata could not be loaded
+func cached(ctx context.Context, path string, kind string, load func() (interface{}, error)) (interface{}, error) {
+	return cachedObjects(ctx, []string{path}, kind, load)
+}
+
+// cachedObjects gets metadata read from several objects, e.g. a BAM object
+// and its index, from the cache, or loads and caches it. the metadata is
+// keyed on the version of every object, so that replacing any of them loads
+// it again
+//
+// Arguments
+//	ctx (context.Context): request context
+//	paths ([]string): local file paths or urls of the objects
+//	kind (string): kind of metadata
+//	load (func() (interface{}, error)): loads the metadata from the objects
+// Returns
+//	(interface{}): the metadata
+//	(error): if not nil, the metadata could not be loaded
+func cachedObjects(ctx context.Context, paths []string, kind string, load func() (interface{}, error)) (interface{}, error) {
+	if !htsconfig.IsCacheEnabled() {
+		return load()
+	}
+	key := kind
+	for _, path := range paths {
+		version, err := objectVersion(ctx, path)
+		if err != nil {
+			return load()
+		}
+		key += "|" + path + "|" + version
+	}
+
+	cache := getCache()
+	if value, ok := cache.Get(key); ok {
+		htsmetrics.ObserveCacheLookup(kind, true)
+		return value, nil
+	}
+	htsmetrics.ObserveCacheLookup(kind, false)
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(key, value)
+	return value, nil
+}
+
+// ObjectIDs gets the ids of the objects listed for an endpoint from the
+// cache, or lists and caches them. unlike object metadata, listings have no
+// version to check, they are used until the cache ttl has passed
+//
+// Arguments
+//	endpoint (string): route of the listing endpoint
+//	load (func() ([]string, error)): lists the ids of all objects served by the endpoint
+// Returns
+//	([]string): the listed ids
+//	(error): if not nil, the ids could not be listed
+func ObjectIDs(endpoint string, load func() ([]string, error)) ([]string, error) {
+	if !htsconfig.IsCacheEnabled() {
+		return load()
+	}
+	key := kindObjectIDs + "|" + endpoint
+	cache := getCache()
+	if value, ok := cache.Get(key); ok {
+		htsmetrics.ObserveCacheLookup(kindObjectIDs, true)
+		return value.([]string), nil
+	}
+	htsmetrics.ObserveCacheLookup(kindObjectIDs, false)
+	ids, err := load()
+	if err != nil {
+		return nil, err
+	}
+	cache.Set(key, ids)
+	return ids, nil
+}
+
+// loadHeader runs a tool writing the header of an object as text, and
+// parses its output
+func loadHeader(ctx context.Context, referenceRegex *regexp.Regexp, tool string, args ...string) (*Header, error) {
+	cmd := htsexec.Command(ctx, tool, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	err := cmd.Run()
+	if err != nil {
+		return nil, htserror.NewToolFailure("could not read object header", err)
+	}
+	return parseHeader(output.String(), referenceRegex), nil
+}
+
+// ReadsHeader gets the header of a reads (SAM/BAM/CRAM) object
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the object
+// Returns
+//	(*Header): the parsed header
+//	(error): if not nil, the header could not be read
+func ReadsHeader(ctx context.Context, path string) (*Header, error) {
+	value, err := cached(ctx, path, kindReadsHeader, func() (interface{}, error) {
+		return loadHeader(ctx, readsReferenceRegex, "samtools", "view", "-H", path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Header), nil
+}
+
+// VariantsHeader gets the header of a variants (VCF/BCF) object
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the object
+// Returns
+//	(*Header): the parsed header
+//	(error): if not nil, the header could not be read
+func VariantsHeader(ctx context.Context, path string) (*Header, error) {
+	value, err := cached(ctx, path, kindVariantsHeader, func() (interface{}, error) {
+		return loadHeader(ctx, variantsReferenceRegex, "bcftools", "view", "-h", path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*Header), nil
+}
+
+// BAMHeader gets the header of a BAM object as encoded by samtools, that is,
+// the BGZF compressed bytes the data endpoint serves for a header block
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the object
+// Returns
+//	([]byte): encoded header, ending with the BAM EOF marker
+//	(error): if not nil, the header could not be encoded
+func BAMHeader(ctx context.Context, path string) ([]byte, error) {
+	value, err := cached(ctx, path, kindBAMHeader, func() (interface{}, error) {
+		cmd := htsexec.Command(ctx, "samtools", "view", "-H", "-b", path)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		err := cmd.Run()
+		if err != nil {
+			return nil, htserror.NewToolFailure("could not encode object header", err)
+		}
+		return output.Bytes(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// BAMIndex gets the BAI index of a BAM object, read from the index file
+// alongside it ({path}.bai). the index is cached under the versions of both
+// the object and the index file, as an index may be rebuilt without the
+// object changing
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the BAM object
+// Returns
+//	(*bam.Index): the parsed index
+//	(error): if not nil, the index could not be read (not found if the object does not exist, unsupported index if the index is missing or unreadable)
+func BAMIndex(ctx context.Context, path string) (*bam.Index, error) {
+	value, err := cachedObjects(ctx, []string{path, path + ".bai"}, kindBAMIndex, func() (interface{}, error) {
+		reader, err := openObject(ctx, path+".bai")
+		if err != nil {
+			if htserror.KindOf(err) != htserror.KindNotFound {
+				return nil, err
+			}
+			// a missing index is only unsupported if the object exists
+			if objErr := statObject(ctx, path); objErr != nil {
+				return nil, objErr
+			}
+			return nil, htserror.NewUnsupportedIndex("object has no BAI index", err)
+		}
+		defer reader.Close()
+		index, err := bam.ReadIndex(bufio.NewReader(reader))
+		if err != nil {
+			return nil, htserror.NewUnsupportedIndex("could not read BAI index", err)
+		}
+		if index == nil {
+			// the index of an object without reference sequences is read
+			// as nil
+			index = new(bam.Index)
+		}
+		return index, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*bam.Index), nil
+}
+
+// openObject opens a local file or url for reading
+func openObject(ctx context.Context, path string) (io.ReadCloser, error) {
+	if !htsutils.IsValidURL(path) {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil, htserror.NewNotFound("object not found", err)
+		}
+		return file, err
+	}
+	res, err := htsutils.GetObject(ctx, path)
+	if err != nil {
+		return nil, htserror.NewUpstreamUnavailable("could not get object", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, htserror.NewHTTPStatusError(path, res.StatusCode, res.Status)
+	}
+	return res.Body, nil
+}
+
+// statObject checks that a local file or url exists
+func statObject(ctx context.Context, path string) error {
+	if !htsutils.IsValidURL(path) {
+		_, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return htserror.NewNotFound("object not found", err)
+		}
+		return err
+	}
+	res, err := htsutils.HeadObject(ctx, path)
+	if err != nil {
+		return htserror.NewUpstreamUnavailable("could not get object", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return htserror.NewHTTPStatusError(path, res.StatusCode, res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests
+//
+// Module metadata_test tests metadata and object
+package htscache
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/stretchr/testify/assert"
+)
+
+// setCacheProps loads a configuration with the given cache properties, and
+// discards the shared cache
+func setCacheProps(cacheProps map[string]interface{}) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{"cache": cacheProps},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	resetCache()
+}
+
+// resetCacheProps loads the default configuration, and discards the shared
+// cache
+func resetCacheProps() {
+	htsconfig.SetConfigFile(nil)
+	htsconfig.LoadConfig()
+	resetCache()
+}
+
+// countingLoader gets a load function counting how many times it is called
+func countingLoader(calls *int) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		*calls++
+		return *calls, nil
+	}
+}
+
+var parseHeaderTC = []struct {
+	text              string
+	variants          bool
+	expReferenceNames []string
+}{
+	{
+		"@HD\tVN:1.6\tSO:coordinate\n@SQ\tSN:chr1\tLN:248956422\n@SQ\tSN:chr2\tLN:242193529\n@PG\tID:bwa\n",
+		false,
+		[]string{"chr1", "chr2"},
+	},
+	{
+		"##fileformat=VCFv4.1\n##contig=<ID=1,length=249250621>\n##contig=<ID=X>\n#CHROM\tPOS\tID\n",
+		true,
+		[]string{"1", "X"},
+	},
+	{
+		"@HD\tVN:1.6\n",
+		false,
+		[]string{},
+	},
+}
+
+func TestParseHeader(t *testing.T) {
+	for _, tc := range parseHeaderTC {
+		referenceRegex := readsReferenceRegex
+		if tc.variants {
+			referenceRegex = variantsReferenceRegex
+		}
+		header := parseHeader(tc.text, referenceRegex)
+		assert.Equal(t, tc.text, header.Text)
+		assert.Equal(t, tc.expReferenceNames, header.ReferenceNames)
+	}
+}
+
+func TestCachedLocalObject(t *testing.T) {
+	resetCacheProps()
+	defer resetCacheProps()
+	dir, _ := ioutil.TempDir("", "htscache-test-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "object.bam")
+	ioutil.WriteFile(path, []byte("version 1"), 0644)
+
+	calls := 0
+	load := countingLoader(&calls)
+	ctx := context.Background()
+
+	// the second request on the same object is served from the cache
+	value, err := cached(ctx, path, kindReadsHeader, load)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+	value, _ = cached(ctx, path, kindReadsHeader, load)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, calls)
+
+	// each kind of metadata is cached separately
+	value, _ = cached(ctx, path, kindBAMHeader, load)
+	assert.Equal(t, 2, value)
+
+	// a replaced object is loaded again
+	ioutil.WriteFile(path, []byte("version two"), 0644)
+	value, _ = cached(ctx, path, kindReadsHeader, load)
+	assert.Equal(t, 3, value)
+
+	// objects whose version cannot be determined are not cached
+	missing := filepath.Join(dir, "missing.bam")
+	cached(ctx, missing, kindReadsHeader, load)
+	cached(ctx, missing, kindReadsHeader, load)
+	assert.Equal(t, 5, calls)
+}
+
+func TestCachedLoadError(t *testing.T) {
+	resetCacheProps()
+	defer resetCacheProps()
+	dir, _ := ioutil.TempDir("", "htscache-test-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "object.bam")
+	ioutil.WriteFile(path, []byte("object"), 0644)
+
+	calls := 0
+	failing := func() (interface{}, error) {
+		calls++
+		return nil, os.ErrNotExist
+	}
+	_, err := cached(context.Background(), path, kindReadsHeader, failing)
+	assert.Equal(t, os.ErrNotExist, err)
+	_, err = cached(context.Background(), path, kindReadsHeader, failing)
+	assert.Equal(t, os.ErrNotExist, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestObjectIDs(t *testing.T) {
+	resetCacheProps()
+	defer resetCacheProps()
+	calls := 0
+	load := func() ([]string, error) {
+		calls++
+		return []string{"a", "b"}, nil
+	}
+	for i := 0; i < 2; i++ {
+		ids, err := ObjectIDs("/reads", load)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b"}, ids)
+	}
+	assert.Equal(t, 1, calls)
+	ObjectIDs("/variants", load)
+	assert.Equal(t, 2, calls)
+}
+
+var cachePropsTC = []struct {
+	cacheProps map[string]interface{}
+	expCalls   int
+}{
+	{map[string]interface{}{}, 1},
+	{map[string]interface{}{"enabled": false}, 3},
+	{map[string]interface{}{"ttl": 1}, 1},
+}
+
+func TestCacheProps(t *testing.T) {
+	defer resetCacheProps()
+	dir, _ := ioutil.TempDir("", "htscache-test-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "object.bam")
+	ioutil.WriteFile(path, []byte("object"), 0644)
+
+	for _, tc := range cachePropsTC {
+		setCacheProps(tc.cacheProps)
+		calls := 0
+		load := countingLoader(&calls)
+		for i := 0; i < 3; i++ {
+			cached(context.Background(), path, kindReadsHeader, load)
+		}
+		assert.Equal(t, tc.expCalls, calls)
+	}
+
+	setCacheProps(map[string]interface{}{"maxEntries": 5, "ttl": 30})
+	cache := getCache()
+	assert.Equal(t, 5, cache.maxEntries)
+	assert.Equal(t, 30*time.Second, cache.ttl)
+}
+
+var urlObjectVersionTC = []struct {
+	headers   map[string]string
+	status    int
+	expError  bool
+	expSuffix string
+}{
+	{map[string]string{"ETag": "\"abc\""}, http.StatusOK, false, "etag=\"abc\";mtime="},
+	{map[string]string{"Last-Modified": "Tue, 01 Sep 2020 00:00:00 GMT"}, http.StatusOK, false, "etag=;mtime=Tue, 01 Sep 2020 00:00:00 GMT"},
+	{map[string]string{}, http.StatusOK, true, ""},
+	{map[string]string{"ETag": "\"abc\""}, http.StatusNotFound, true, ""},
+}
+
+func TestURLObjectVersion(t *testing.T) {
+	for _, tc := range urlObjectVersionTC {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			for name, value := range tc.headers {
+				writer.Header().Set(name, value)
+			}
+			writer.WriteHeader(tc.status)
+		}))
+		version, err := objectVersion(context.Background(), server.URL+"/object.bam")
+		server.Close()
+		if tc.expError {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+			assert.Contains(t, version, tc.expSuffix)
+		}
+	}
+}
+
+func TestBAMIndex(t *testing.T) {
+	resetCacheProps()
+	defer resetCacheProps()
+	wd, _ := os.Getwd()
+	path := filepath.Join(filepath.Dir(filepath.Dir(wd)), "data", "test", "sources", "tabulamuris", "A1-B000168-3_57_F-1-1_R2.mus.Aligned.out.sorted.bam")
+
+	index, err := BAMIndex(context.Background(), path)
+	assert.Nil(t, err)
+	assert.True(t, index.NumRefs() > 0)
+
+	// the same parsed index is returned by later requests
+	cachedIndex, _ := BAMIndex(context.Background(), path)
+	assert.True(t, index == cachedIndex)
+
+	// a rebuilt index is read again, although the object has not changed
+	dir, _ := ioutil.TempDir("", "htscache-test-")
+	defer os.RemoveAll(dir)
+	rebuilt := filepath.Join(dir, "rebuilt.bam")
+	bamBytes, _ := ioutil.ReadFile(path)
+	baiBytes, _ := ioutil.ReadFile(path + ".bai")
+	ioutil.WriteFile(rebuilt, bamBytes, 0644)
+	ioutil.WriteFile(rebuilt+".bai", baiBytes, 0644)
+	_, err = BAMIndex(context.Background(), rebuilt)
+	assert.Nil(t, err)
+	ioutil.WriteFile(rebuilt+".bai", []byte("not an index"), 0644)
+	_, err = BAMIndex(context.Background(), rebuilt)
+	assert.Equal(t, htserror.KindUnsupportedIndex, htserror.KindOf(err))
+
+	// a missing object is not found, rather than missing its index
+	_, err = BAMIndex(context.Background(), path+".missing")
+	assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err))
+
+	// an object without an index, and an index file that is not a BAI index
+	noIndex := filepath.Join(dir, "noindex.bam")
+	ioutil.WriteFile(noIndex, []byte("object"), 0644)
+	_, err = BAMIndex(context.Background(), noIndex)
+	assert.Equal(t, htserror.KindUnsupportedIndex, htserror.KindOf(err))
+	unindexed := filepath.Join(dir, "object.bam")
+	ioutil.WriteFile(unindexed, []byte("object"), 0644)
+	ioutil.WriteFile(unindexed+".bai", []byte("not an index"), 0644)
+	_, err = BAMIndex(context.Background(), unindexed)
+	assert.Equal(t, htserror.KindUnsupportedIndex, htserror.KindOf(err))
+
+	// an object url without an index
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.URL.Path, ".bai") || strings.Contains(request.URL.Path, "missing") {
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	_, err = BAMIndex(context.Background(), server.URL+"/object.bam")
+	assert.Equal(t, htserror.KindUnsupportedIndex, htserror.KindOf(err))
+	_, err = BAMIndex(context.Background(), server.URL+"/missing.bam")
+	assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err))
+}
+
+var openObjectTC = []struct {
+	status  int
+	expKind htserror.Kind
+}{
+	{http.StatusOK, 0},
+	{http.StatusNotFound, htserror.KindNotFound},
+	{http.StatusServiceUnavailable, htserror.KindUpstreamUnavailable},
+}
+
+func TestOpenObject(t *testing.T) {
+	for _, tc := range openObjectTC {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(tc.status)
+		}))
+		reader, err := openObject(context.Background(), server.URL+"/object.bam")
+		assert.Equal(t, tc.expKind, htserror.KindOf(err), http.StatusText(tc.status))
+		if err == nil {
+			reader.Close()
+		}
+		server.Close()
+
+		// the storage cannot be reached once the server is closed
+		_, err = openObject(context.Background(), server.URL+"/object.bam")
+		assert.Equal(t, htserror.KindUpstreamUnavailable, htserror.KindOf(err))
+	}
+
+	_, err := openObject(context.Background(), filepath.Join(os.TempDir(), "htscache-missing.bam"))
+	assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err))
+}
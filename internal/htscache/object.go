@@ -0,0 +1,56 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests, so that
+// repeated requests on the same object do not run samtools/bcftools again
+//
+// Module object identifies the version of an object, so that metadata cached
+// for an object is not used once the object has changed
+package htscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// objectVersion gets a string identifying the current version of an object.
+// it changes whenever the object is replaced: for a local file, it is made
+// from the size and modification time, for a url, from the size, ETag and
+// Last-Modified headers of a HEAD response
+//
+// Arguments
+//	ctx (context.Context): request context, bounds the HEAD request
+//	path (string): local file path or url of the object
+// Returns
+//	(string): object version
+//	(error): if not nil, the version could not be determined
+func objectVersion(ctx context.Context, path string) (string, error) {
+	if htsutils.IsValidURL(path) {
+		return urlObjectVersion(ctx, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("size=%d;mtime=%d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// urlObjectVersion gets the version of an object served at a url
+func urlObjectVersion(ctx context.Context, objURL string) (string, error) {
+	res, err := htsutils.HeadObject(ctx, objURL)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", errors.New("could not determine object version: " + res.Status)
+	}
+	etag := res.Header.Get("ETag")
+	lastModified := res.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return "", errors.New("could not determine object version: neither ETag nor Last-Modified returned")
+	}
+	return fmt.Sprintf("size=%d;etag=%s;mtime=%s", res.ContentLength, etag, lastModified), nil
+}
@@ -0,0 +1,259 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests, so that
+// repeated requests on the same object do not run samtools/bcftools again
+//
+// Module warm pre-populates the cache with the metadata of the objects listed
+// in a manifest, so that the first request against each object is not slowed
+// down by fetching its header and index
+package htscache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+)
+
+// warmConcurrency maximum number of objects warmed at the same time
+var warmConcurrency = 4
+
+// manifestTypeReads manifest type of reads (BAM/CRAM) ids
+const manifestTypeReads = "reads"
+
+// manifestTypeVariants manifest type of variants (VCF/BCF) ids
+const manifestTypeVariants = "variants"
+
+// manifestEndpoints ticket endpoint resolving the ids of each manifest type
+var manifestEndpoints = map[string]htsconstants.APIEndpoint{
+	manifestTypeReads:    htsconstants.APIEndpointReadsTicket,
+	manifestTypeVariants: htsconstants.APIEndpointVariantsTicket,
+}
+
+// ManifestEntry an id listed in a manifest, along with the type of object it
+// identifies
+//
+// Attributes
+//	Type (string): "reads" or "variants"
+//	ID (string): object id, as requested from the ticket endpoint
+type ManifestEntry struct {
+	Type string
+	ID   string
+}
+
+// ReadManifest parses a manifest of object ids. each line holds an id,
+// optionally preceded by its type ("reads" or "variants") and whitespace.
+// ids without a type are reads. blank lines and lines starting with '#' are
+// ignored
+//
+// Arguments
+//	reader (io.Reader): manifest contents
+// Returns
+//	([]*ManifestEntry): ids in manifest order
+//	(error): if not nil, the manifest could not be read, or a line is invalid
+func ReadManifest(reader io.Reader) ([]*ManifestEntry, error) {
+	entries := []*ManifestEntry{}
+	scanner := bufio.NewScanner(reader)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		entry := new(ManifestEntry)
+		switch len(fields) {
+		case 1:
+			entry.Type = manifestTypeReads
+			entry.ID = fields[0]
+		case 2:
+			entry.Type = fields[0]
+			entry.ID = fields[1]
+		default:
+			return nil, fmt.Errorf("manifest line %d: expected '[type] id', got '%s'", lineNumber, line)
+		}
+		if _, ok := manifestEndpoints[entry.Type]; !ok {
+			return nil, fmt.Errorf("manifest line %d: type '%s' not supported", lineNumber, entry.Type)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// WarmResult the outcome of warming the cache for a single manifest entry
+//
+// Attributes
+//	Entry (*ManifestEntry): the warmed manifest entry
+//	Path (string): object path or url the id resolved to, empty if it did not resolve
+//	Err (error): if not nil, the reason the entry could not be warmed
+type WarmResult struct {
+	Entry *ManifestEntry
+	Path  string
+	Err   error
+}
+
+// WarmReport the outcome of warming the cache for all entries of a manifest
+//
+// Attributes
+//	Results ([]*WarmResult): result of each entry, in manifest order
+type WarmReport struct {
+	Results []*WarmResult
+}
+
+// NumFailed gets the number of entries that could not be warmed
+//
+//	Type: WarmReport
+// Returns
+//	(int): number of failed entries
+func (report *WarmReport) NumFailed() int {
+	nFailed := 0
+	for _, result := range report.Results {
+		if result.Err != nil {
+			nFailed++
+		}
+	}
+	return nFailed
+}
+
+// isBAM indicates whether an object path or url points to a BAM file
+func isBAM(path string) bool {
+	path = strings.SplitN(path, "?", 2)[0]
+	return strings.HasSuffix(strings.ToLower(path), ".bam")
+}
+
+// warmEntry resolves a manifest entry to its object, and loads the object
+// metadata into the cache: the header of all objects, and additionally the
+// header byte length and index of BAM objects
+//
+// Arguments
+//	ctx (context.Context): bounds the time spent loading metadata
+//	entry (*ManifestEntry): entry to warm
+// Returns
+//	(*WarmResult): outcome of warming the entry
+func warmEntry(ctx context.Context, entry *ManifestEntry) *WarmResult {
+	result := &WarmResult{Entry: entry}
+	endpoint := manifestEndpoints[entry.Type]
+	if !htsconfig.IsEndpointEnabled(endpoint) {
+		result.Err = errors.New(entry.Type + " endpoint is not enabled")
+		return result
+	}
+	path, err := htsconfig.GetObjectPath(endpoint, entry.ID)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Path = path
+
+	if entry.Type == manifestTypeVariants {
+		_, result.Err = VariantsHeader(ctx, path)
+		return result
+	}
+	_, result.Err = ReadsHeader(ctx, path)
+	if result.Err == nil && isBAM(path) {
+		_, result.Err = BAMHeader(ctx, path)
+	}
+	if result.Err == nil && isBAM(path) {
+		_, result.Err = BAMIndex(ctx, path)
+	}
+	return result
+}
+
+// Warm loads the metadata of each manifest entry into the cache, a few
+// entries at a time. failures are reported per entry, and do not stop the
+// remaining entries from being warmed
+//
+// Arguments
+//	ctx (context.Context): bounds the time spent warming
+//	entries ([]*ManifestEntry): entries to warm
+// Returns
+//	(*WarmReport): outcome of warming each entry
+func Warm(ctx context.Context, entries []*ManifestEntry) *WarmReport {
+	report := new(WarmReport)
+	report.Results = make([]*WarmResult, len(entries))
+	slots := make(chan bool, warmConcurrency)
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		wg.Add(1)
+		slots <- true
+		go func(i int, entry *ManifestEntry) {
+			defer wg.Done()
+			report.Results[i] = warmEntry(ctx, entry)
+			<-slots
+		}(i, entry)
+	}
+	wg.Wait()
+	return report
+}
+
+// warmupState progress of the warm-up run when the server starts
+//
+// Attributes
+//	started (bool): true once a startup warm-up has been started
+//	done (bool): true once the startup warm-up has completed
+//	report (*WarmReport): outcome of the completed warm-up
+type warmupState struct {
+	mutex   sync.Mutex
+	started bool
+	done    bool
+	report  *WarmReport
+}
+
+// startupWarmup progress of the startup warm-up
+var startupWarmup = new(warmupState)
+
+// StartWarmup warms the cache for all manifest entries in the background,
+// while the server starts serving requests. failures are logged per entry
+//
+// Arguments
+//	entries ([]*ManifestEntry): entries to warm
+func StartWarmup(entries []*ManifestEntry) {
+	startupWarmup.mutex.Lock()
+	startupWarmup.started = true
+	startupWarmup.done = false
+	startupWarmup.report = nil
+	startupWarmup.mutex.Unlock()
+
+	go func() {
+		report := Warm(context.Background(), entries)
+		for _, result := range report.Results {
+			if result.Err != nil {
+				htslog.Warn("cache warm-up failed", htslog.Fields{
+					"type":  result.Entry.Type,
+					"id":    result.Entry.ID,
+					"error": result.Err.Error(),
+				})
+			}
+		}
+		htslog.Info("cache warm-up completed", htslog.Fields{
+			"ids":    len(report.Results),
+			"failed": report.NumFailed(),
+		})
+
+		startupWarmup.mutex.Lock()
+		startupWarmup.done = true
+		startupWarmup.report = report
+		startupWarmup.mutex.Unlock()
+	}()
+}
+
+// GetWarmupStatus gets the progress of the startup warm-up
+//
+// Returns
+//	(bool): true if a startup warm-up was started
+//	(bool): true if the startup warm-up has completed
+//	(*WarmReport): outcome of the completed warm-up, nil until it has completed
+func GetWarmupStatus() (bool, bool, *WarmReport) {
+	startupWarmup.mutex.Lock()
+	defer startupWarmup.mutex.Unlock()
+	return startupWarmup.started, startupWarmup.done, startupWarmup.report
+}
@@ -0,0 +1,146 @@
+// Package htscache caches metadata parsed from objects (headers, reference
+// dictionaries, header byte lengths and indexes) between requests
+//
+// Module warm_test tests warm
+package htscache
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setWarmConfig loads a configuration serving reads from the test sources,
+// with variants disabled
+func setWarmConfig() {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"reads": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]string{
+						{
+							"pattern": "^tabulamuris\\.(?P<accession>.*)$",
+							"path":    "../../data/test/sources/tabulamuris/{accession}.mus.Aligned.out.sorted.bam",
+						},
+					},
+				},
+			},
+			"variants": map[string]interface{}{"enabled": false},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	resetCache()
+}
+
+var readManifestTC = []struct {
+	manifest   string
+	expEntries []*ManifestEntry
+	expError   bool
+}{
+	{
+		"# cohort\ntabulamuris.A1\n\nreads tabulamuris.A2\nvariants\t1000genomes.chr1\n",
+		[]*ManifestEntry{
+			&ManifestEntry{"reads", "tabulamuris.A1"},
+			&ManifestEntry{"reads", "tabulamuris.A2"},
+			&ManifestEntry{"variants", "1000genomes.chr1"},
+		},
+		false,
+	},
+	{"", []*ManifestEntry{}, false},
+	{"sequences tabulamuris.A1\n", nil, true},
+	{"reads tabulamuris.A1 extra\n", nil, true},
+}
+
+func TestReadManifest(t *testing.T) {
+	for _, tc := range readManifestTC {
+		entries, err := ReadManifest(strings.NewReader(tc.manifest))
+		if tc.expError {
+			assert.NotNil(t, err)
+		} else {
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expEntries, entries)
+		}
+	}
+}
+
+var isBAMTC = []struct {
+	path string
+	exp  bool
+}{
+	{"/data/sample.bam", true},
+	{"https://example.org/sample.BAM?token=abc", true},
+	{"/data/sample.cram", false},
+	{"https://example.org/sample.cram?file=x.bam", false},
+}
+
+func TestIsBAM(t *testing.T) {
+	for _, tc := range isBAMTC {
+		assert.Equal(t, tc.exp, isBAM(tc.path))
+	}
+}
+
+func TestWarmFailures(t *testing.T) {
+	setWarmConfig()
+	defer resetCacheProps()
+
+	entries := []*ManifestEntry{
+		&ManifestEntry{"reads", "tabulamuris.missing"},
+		&ManifestEntry{"reads", "unknown.id"},
+		&ManifestEntry{"variants", "1000genomes.chr1"},
+	}
+	report := Warm(context.Background(), entries)
+
+	// every entry is reported, in manifest order
+	assert.Equal(t, 3, len(report.Results))
+	assert.Equal(t, 3, report.NumFailed())
+	for i, result := range report.Results {
+		assert.Equal(t, entries[i], result.Entry)
+	}
+	assert.Contains(t, report.Results[0].Path, "missing.mus.Aligned.out.sorted.bam")
+	assert.Equal(t, "", report.Results[1].Path)
+	assert.EqualError(t, report.Results[2].Err, "variants endpoint is not enabled")
+}
+
+func TestWarm(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools not found on PATH")
+	}
+	setWarmConfig()
+	defer resetCacheProps()
+
+	entries := []*ManifestEntry{&ManifestEntry{"reads", "tabulamuris.A1-B000168-3_57_F-1-1_R2"}}
+	report := Warm(context.Background(), entries)
+	assert.Equal(t, 0, report.NumFailed())
+
+	// header, encoded header and index are all cached
+	assert.Equal(t, 3, getCache().Len())
+}
+
+func TestStartWarmup(t *testing.T) {
+	setWarmConfig()
+	defer resetCacheProps()
+
+	StartWarmup([]*ManifestEntry{&ManifestEntry{"reads", "unknown.id"}})
+	started, _, _ := GetWarmupStatus()
+	assert.True(t, started)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, done, _ := GetWarmupStatus(); done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, done, report := GetWarmupStatus()
+	assert.True(t, done)
+	assert.Equal(t, 1, report.NumFailed())
+}
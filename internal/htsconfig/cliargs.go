@@ -7,12 +7,22 @@ package htsconfig
 
 import (
 	"flag"
+	"os"
 	"sync"
 )
 
+// CommandServe command serving htsget requests, run if no command is given
+const CommandServe = "serve"
+
+// CommandCheck command checking that each id in a manifest resolves to an
+// object whose header and index can be loaded, reporting failures per id
+const CommandCheck = "check"
+
 // cliArgs contains all properties that can be specified on the command line
 type cliArgs struct {
+	command    string
 	configFile string
+	manifest   string
 }
 
 // cliargs (*cliArgs): singleton of settings loaded from command line
@@ -21,13 +31,33 @@ var cliargs *cliArgs
 // cliargsLoaded (sync.Once): indicates whether the singleton has been loaded or not
 var cliargsLoaded sync.Once
 
+// splitCommand separates the command (e.g. "check") from the options/flags
+// following it. if the first argument is not a command, the serve command is
+// implied
+//
+// Arguments
+//	args ([]string): command line arguments, excluding the program name
+// Returns
+//	(string): the command
+//	([]string): the options/flags
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 && (args[0] == CommandServe || args[0] == CommandCheck) {
+		return args[0], args[1:]
+	}
+	return CommandServe, args
+}
+
 // parseCliArgs parse all cli options/flags and returns it as a new cliArgs
 // instance
 func parseCliArgs() *cliArgs {
+	command, args := splitCommand(os.Args[1:])
 	configFilePtr := flag.String("config", "", "path to json config file")
-	flag.Parse()
+	manifestPtr := flag.String("manifest", "", "path to manifest of ids whose headers and indexes are cached at startup (serve), or checked (check)")
+	flag.CommandLine.Parse(args)
 	newCliargs := new(cliArgs)
+	newCliargs.command = command
 	newCliargs.configFile = *configFilePtr
+	newCliargs.manifest = *manifestPtr
 	return newCliargs
 }
 
@@ -45,3 +75,14 @@ func getCliArgs() *cliArgs {
 	})
 	return cliargs
 }
+
+// GetCommand gets the command given on the command line, "serve" if none
+func GetCommand() string {
+	return getCliArgs().command
+}
+
+// GetManifest gets the path to the manifest of ids to warm the cache for, or
+// to check, empty if none was given
+func GetManifest() string {
+	return getCliArgs().manifest
+}
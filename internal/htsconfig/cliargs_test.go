@@ -29,3 +29,22 @@ func TestLoadCliArgs(t *testing.T) {
 		assert.Equal(t, tc.expCliArgs.configFile, actualCliArgs.configFile)
 	}
 }
+
+var splitCommandTC = []struct {
+	args       []string
+	expCommand string
+	expArgs    []string
+}{
+	{[]string{}, CommandServe, []string{}},
+	{[]string{"-config", "config.json"}, CommandServe, []string{"-config", "config.json"}},
+	{[]string{"serve", "-config", "config.json"}, CommandServe, []string{"-config", "config.json"}},
+	{[]string{"check", "-manifest", "ids.txt"}, CommandCheck, []string{"-manifest", "ids.txt"}},
+}
+
+func TestSplitCommand(t *testing.T) {
+	for _, tc := range splitCommandTC {
+		command, args := splitCommand(tc.args)
+		assert.Equal(t, tc.expCommand, command)
+		assert.Equal(t, tc.expArgs, args)
+	}
+}
@@ -7,11 +7,13 @@ package htsconfig
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
-	"path/filepath"
 	"reflect"
+	"time"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 
@@ -33,16 +35,54 @@ type configurationContainer struct {
 }
 
 type configurationServerProps struct {
-	Port    string `json:"port"`
-	Host    string `json:"host"`
-	Tempdir string `json:"tempdir"`
-	Logfile string `json:"logfile"`
+	Port            string                `json:"port"`
+	Host            string                `json:"host"`
+	Tempdir         string                `json:"tempdir"`
+	Logfile         string                `json:"logfile"`
+	LogLevel        string                `json:"loglevel"`
+	LogMaxSize      int                   `json:"logmaxsize"`
+	LogMaxBackups   *int                  `json:"logmaxbackups"`
+	ReadTimeout     *int                  `json:"readtimeout"`
+	WriteTimeout    *int                  `json:"writetimeout"`
+	IdleTimeout     *int                  `json:"idletimeout"`
+	ShutdownTimeout *int                  `json:"shutdowntimeout"`
+	Cache           *configurationCache   `json:"cache"`
+	TLS             *configurationTLS     `json:"tls"`
+	Tracing         *configurationTracing `json:"tracing"`
+}
+
+type configurationCache struct {
+	Enabled    *bool `json:"enabled"`
+	MaxEntries int   `json:"maxEntries"`
+	TTL        int   `json:"ttl"`
+}
+
+type configurationTLS struct {
+	Cert         string   `json:"cert"`
+	Key          string   `json:"key"`
+	MinVersion   string   `json:"minVersion"`
+	CipherSuites []string `json:"cipherSuites"`
+	ClientCA     string   `json:"clientCA"`
+	ClientAuth   string   `json:"clientAuth"`
+}
+
+type configurationTracing struct {
+	Endpoint    string `json:"endpoint"`
+	ServiceName string `json:"serviceName"`
 }
 
 type configurationEndpoint struct {
-	Enabled            *bool               `json:"enabled,true" default:"true"`
-	DataSourceRegistry *DataSourceRegistry `json:"dataSourceRegistry"`
-	ServiceInfo        *ServiceInfo        `json:"serviceInfo"`
+	Enabled            *bool                 `json:"enabled,true" default:"true"`
+	Listing            *configurationListing `json:"listing"`
+	BlockSize          int                   `json:"blockSize"`
+	Partitions         int                   `json:"partitions"`
+	InlineHeaderSize   int                   `json:"inlineHeaderSize"`
+	DataSourceRegistry *DataSourceRegistry   `json:"dataSourceRegistry"`
+	ServiceInfo        *ServiceInfo          `json:"serviceInfo"`
+}
+
+type configurationListing struct {
+	Enabled *bool `json:"enabled"`
 }
 
 var configurationSingleton *Configuration
@@ -61,7 +101,10 @@ func patchConfiguration(defR reflect.Value, patchR reflect.Value) {
 
 		typesToPatch := []string{
 			"string",
+			"int",
+			"[]string",
 			"*bool",
+			"*int",
 			"*htsconfig.DataSourceRegistry",
 		}
 
@@ -80,10 +123,23 @@ func patchConfiguration(defR reflect.Value, patchR reflect.Value) {
 				if patchString != "" {
 					defR.Field(i).Set(patchR.Field(i))
 				}
+			} else if defRType == "int" {
+				if patchR.Field(i).Int() != 0 {
+					defR.Field(i).Set(patchR.Field(i))
+				}
+			} else if defRType == "[]string" {
+				if !patchR.Field(i).IsNil() {
+					defR.Field(i).Set(patchR.Field(i))
+				}
 			} else if defRType == "*bool" {
 				if !patchR.Field(i).IsNil() {
 					defR.Field(i).Set(patchR.Field(i))
 				}
+			} else if defRType == "*int" {
+				// set, even to zero, in the config file
+				if !patchR.Field(i).IsNil() {
+					defR.Field(i).Set(patchR.Field(i))
+				}
 			} else if defRType == "*htsconfig.DataSourceRegistry" {
 				if !patchR.Field(i).IsNil() {
 					defR.Field(i).Set(patchR.Field(i))
@@ -147,15 +203,26 @@ func GetTempdir() string {
 	return htsutils.AddTrailingSlash(getServerProps().Tempdir)
 }
 
-func GetTempfilePath(filename string) string {
-	return filepath.Join(GetTempdir(), filename)
-}
-
+// CreateTempfile creates a new temporary file in the tempdir. the filename
+// is only a prefix, a random suffix makes the name unique, so concurrent
+// callers passing the same filename never share a file
+//
+// Arguments
+//	filename (string): prefix of the temporary file name
+// Returns
+//	(*os.File): the created file, open for reading and writing
+//	(error): if not nil, the file could not be created
 func CreateTempfile(filename string) (*os.File, error) {
-	return os.Create(GetTempfilePath(filename))
+	file, err := ioutil.TempFile(GetTempdir(), filename+"-*")
+	if err != nil {
+		return nil, err
+	}
+	registerTempfile(file.Name())
+	return file, nil
 }
 
 func RemoveTempfile(file *os.File) error {
+	deregisterTempfile(file.Name())
 	return os.Remove(file.Name())
 }
 
@@ -163,6 +230,123 @@ func GetLogfile() string {
 	return getServerProps().Logfile
 }
 
+// GetLogLevel gets the minimum level of log entries written to the logfile
+func GetLogLevel() string {
+	return getServerProps().LogLevel
+}
+
+// GetLogMaxSize gets the size (in megabytes) at which the logfile is rotated
+func GetLogMaxSize() int {
+	return getServerProps().LogMaxSize
+}
+
+// GetLogMaxBackups gets the number of rotated logfiles to keep. zero means
+// all are kept
+func GetLogMaxBackups() int {
+	return *getServerProps().LogMaxBackups
+}
+
+// GetReadTimeout gets the maximum duration for reading an entire request.
+// zero means no timeout
+func GetReadTimeout() time.Duration {
+	return time.Duration(*getServerProps().ReadTimeout) * time.Second
+}
+
+// GetWriteTimeout gets the maximum duration before timing out writes of a
+// response. zero means no timeout, so that large data streams are not cut off
+func GetWriteTimeout() time.Duration {
+	return time.Duration(*getServerProps().WriteTimeout) * time.Second
+}
+
+// GetIdleTimeout gets the maximum duration to wait for the next request on
+// a keep-alive connection. zero means the read timeout is used
+func GetIdleTimeout() time.Duration {
+	return time.Duration(*getServerProps().IdleTimeout) * time.Second
+}
+
+// GetShutdownTimeout gets the maximum duration in-flight requests are given
+// to complete once the server is asked to shut down. zero means they are
+// stopped at once
+func GetShutdownTimeout() time.Duration {
+	return time.Duration(*getServerProps().ShutdownTimeout) * time.Second
+}
+
+func getCache() *configurationCache {
+	return getServerProps().Cache
+}
+
+// IsCacheEnabled indicates whether object header metadata is cached between
+// requests
+func IsCacheEnabled() bool {
+	return *getCache().Enabled
+}
+
+// GetCacheMaxEntries gets the maximum number of entries held in the object
+// metadata cache, the least recently used are evicted first
+func GetCacheMaxEntries() int {
+	return getCache().MaxEntries
+}
+
+// GetCacheTTL gets the maximum duration an object metadata cache entry is
+// used for before it is loaded again
+func GetCacheTTL() time.Duration {
+	return time.Duration(getCache().TTL) * time.Second
+}
+
+func getTLS() *configurationTLS {
+	return getServerProps().TLS
+}
+
+// IsTLSEnabled indicates whether the server is configured to serve HTTPS,
+// that is, both a certificate and private key have been configured
+func IsTLSEnabled() bool {
+	return getTLS().Cert != "" && getTLS().Key != ""
+}
+
+// GetTLSCertFile gets the path to the PEM-encoded server certificate (chain)
+func GetTLSCertFile() string {
+	return getTLS().Cert
+}
+
+// GetTLSKeyFile gets the path to the PEM-encoded server private key
+func GetTLSKeyFile() string {
+	return getTLS().Key
+}
+
+// GetTLSMinVersion gets the minimum TLS protocol version accepted (e.g. "1.2")
+func GetTLSMinVersion() string {
+	return getTLS().MinVersion
+}
+
+// GetTLSCipherSuites gets the names of the cipher suites allowed for TLS 1.2
+// and earlier connections. if empty, the Go defaults are used
+func GetTLSCipherSuites() []string {
+	return getTLS().CipherSuites
+}
+
+// GetTLSClientCAFile gets the path to the PEM-encoded CA bundle client
+// certificates are verified against
+func GetTLSClientCAFile() string {
+	return getTLS().ClientCA
+}
+
+// GetTLSClientAuth gets the client certificate policy: "none", "optional"
+// (verified if presented) or "require"
+func GetTLSClientAuth() string {
+	return getTLS().ClientAuth
+}
+
+// GetTracingEndpoint gets the base url of the OTLP/HTTP collector that trace
+// spans are exported to. tracing is disabled if empty
+func GetTracingEndpoint() string {
+	return getServerProps().Tracing.Endpoint
+}
+
+// GetTracingServiceName gets the service name attached to exported spans
+func GetTracingServiceName() string {
+	return getServerProps().Tracing.ServiceName
+}
+
 func getEndpointConfig(ep htsconstants.APIEndpoint) *configurationEndpoint {
 	reads := getContainer().ReadsConfig
 	variants := getContainer().VariantsConfig
@@ -170,9 +354,13 @@ func getEndpointConfig(ep htsconstants.APIEndpoint) *configurationEndpoint {
 		htsconstants.APIEndpointReadsTicket:         reads,
 		htsconstants.APIEndpointReadsData:           reads,
 		htsconstants.APIEndpointReadsServiceInfo:    reads,
+		htsconstants.APIEndpointReadsList:           reads,
+		htsconstants.APIEndpointReadsMetadata:       reads,
 		htsconstants.APIEndpointVariantsTicket:      variants,
 		htsconstants.APIEndpointVariantsData:        variants,
 		htsconstants.APIEndpointVariantsServiceInfo: variants,
+		htsconstants.APIEndpointVariantsList:        variants,
+		htsconstants.APIEndpointVariantsMetadata:    variants,
 	}
 	return configs[ep]
 }
@@ -181,6 +369,57 @@ func IsEndpointEnabled(ep htsconstants.APIEndpoint) bool {
 	return *getEndpointConfig(ep).Enabled
 }
 
+// IsListingEnabled indicates whether the ids of an endpoint's objects can be
+// listed
+func IsListingEnabled(ep htsconstants.APIEndpoint) bool {
+	return *getEndpointConfig(ep).Listing.Enabled
+}
+
+// GetBlockSize gets the suggested byte size of the response from a single
+// ticket url for an object: the block size of the data source serving the id
+// if set, otherwise the block size of the endpoint
+func GetBlockSize(ep htsconstants.APIEndpoint, id string) int64 {
+	source, err := GetDataSourceRegistry(ep).findFirstMatch(id)
+	if err == nil && source.BlockSize > 0 {
+		return int64(source.BlockSize)
+	}
+	if blockSize := getEndpointConfig(ep).BlockSize; blockSize > 0 {
+		return int64(blockSize)
+	}
+	return int64(htsconstants.DfltBlockSize)
+}
+
+// GetPartitions gets the minimum number of body blocks a region ticket of an
+// endpoint is partitioned into, 0 if regions are only split by block size
+func GetPartitions(ep htsconstants.APIEndpoint) int {
+	return getEndpointConfig(ep).Partitions
+}
+
+// GetInlineHeaderSize gets the maximum byte size of a header block an
+// endpoint inlines in its tickets as a data uri, 0 if header blocks are not
+// inlined
+func GetInlineHeaderSize(ep htsconstants.APIEndpoint) int64 {
+	return int64(getEndpointConfig(ep).InlineHeaderSize)
+}
+
+// GetAllowedSamples gets the samples of a variants object a client may get,
+// from the sample access of the data source serving the id
+//
+// Arguments
+//	ep (htsconstants.APIEndpoint): requested endpoint
+//	id (string): requested object id
+//	principal (string): subject of the client certificate, empty if none
+// Returns
+//	([]string): names of the samples the client may get
+//	(bool): true if the client's samples are restricted, false if it may get all samples
+func GetAllowedSamples(ep htsconstants.APIEndpoint, id string, principal string) ([]string, bool) {
+	source, err := GetDataSourceRegistry(ep).findFirstMatch(id)
+	if err != nil {
+		return nil, false
+	}
+	return source.allowedSamples(principal)
+}
+
 func GetDataSourceRegistry(ep htsconstants.APIEndpoint) *DataSourceRegistry {
 	return getEndpointConfig(ep).DataSourceRegistry
 }
@@ -189,6 +428,39 @@ func GetObjectPath(ep htsconstants.APIEndpoint, id string) (string, error) {
 	return GetDataSourceRegistry(ep).GetMatchingPath(id)
 }
 
+// GetFileDataSource gets the data source of a local file served by the file
+// bytes endpoint. the file must be the one the object id resolves to in the
+// registry of an enabled reads or variants endpoint, so that only files of
+// registered objects are served
+//
+// Arguments
+//	id (string): object id the ticket was issued for
+//	filePath (string): local file path of the ticket url
+// Returns
+//	(*DataSource): data source resolving the id to the file
+//	(error): if not nil, no registered data source resolves the id to the file
+func GetFileDataSource(id string, filePath string) (*DataSource, error) {
+	endpoints := []htsconstants.APIEndpoint{
+		htsconstants.APIEndpointReadsTicket,
+		htsconstants.APIEndpointVariantsTicket,
+	}
+	for _, ep := range endpoints {
+		if !IsEndpointEnabled(ep) {
+			continue
+		}
+		registry := GetDataSourceRegistry(ep)
+		source, err := registry.findFirstMatch(id)
+		if err != nil {
+			continue
+		}
+		path, err := source.evaluatePath(id)
+		if err == nil && path == filePath && !htsutils.IsValidURL(path) {
+			return source, nil
+		}
+	}
+	return nil, htserror.NewNotFound("id: "+id+" does not resolve to a registered local file", nil)
+}
+
 func GetServiceInfo(ep htsconstants.APIEndpoint) *ServiceInfo {
 	return getEndpointConfig(ep).ServiceInfo
 }
@@ -0,0 +1,213 @@
+// Package htsconfig allows the program to be configured with modifiable
+// properties, affecting runtime properties. also contains program constants
+//
+// Module configuration_test tests module configuration
+package htsconfig
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var getBlockSizeTC = []struct {
+	ep  htsconstants.APIEndpoint
+	id  string
+	exp int64
+}{
+	{htsconstants.APIEndpointReadsTicket, "small.A1", 1000},
+	{htsconstants.APIEndpointReadsTicket, "large.A1", 200000},
+	{htsconstants.APIEndpointReadsTicket, "unknown.A1", 200000},
+	{htsconstants.APIEndpointVariantsTicket, "1000genomes.A1", int64(htsconstants.DfltBlockSize)},
+}
+
+func TestGetBlockSize(t *testing.T) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"reads": map[string]interface{}{
+				"blockSize":  200000,
+				"partitions": 4,
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{
+						{"pattern": "^small\\.(?P<id>.*)$", "path": "./{id}.bam", "blockSize": 1000},
+						{"pattern": "^large\\.(?P<id>.*)$", "path": "./{id}.bam"},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	SetConfigFile(newConfig)
+	LoadConfig()
+	defer func() {
+		SetConfigFile(nil)
+		LoadConfig()
+	}()
+
+	for _, tc := range getBlockSizeTC {
+		assert.Equal(t, tc.exp, GetBlockSize(tc.ep, tc.id), tc.id)
+	}
+	assert.Equal(t, 4, GetPartitions(htsconstants.APIEndpointReadsTicket))
+	assert.Equal(t, 0, GetPartitions(htsconstants.APIEndpointVariantsTicket))
+}
+
+var patchServerPropsTC = []struct {
+	props                                              map[string]interface{}
+	expLogMaxBackups                                   int
+	expReadTimeout, expIdleTimeout, expShutdownTimeout int
+}{
+	{map[string]interface{}{}, 5, 60, 120, 30},
+	{map[string]interface{}{"logmaxbackups": 2, "readtimeout": 10}, 2, 10, 120, 30},
+	{
+		map[string]interface{}{"logmaxbackups": 0, "readtimeout": 0, "idletimeout": 0, "shutdowntimeout": 0},
+		0, 0, 0, 0,
+	},
+}
+
+func TestPatchServerProps(t *testing.T) {
+	defer func() {
+		SetConfigFile(nil)
+		LoadConfig()
+	}()
+
+	// settings absent from the config file keep their defaults, while
+	// settings set to zero (off) override them
+	for _, tc := range patchServerPropsTC {
+		configJSON, _ := json.Marshal(map[string]interface{}{
+			"htsgetconfig": map[string]interface{}{"props": tc.props},
+		})
+		newConfig := new(Configuration)
+		json.Unmarshal(configJSON, newConfig)
+		SetConfigFile(newConfig)
+		LoadConfig()
+		assert.Equal(t, tc.expLogMaxBackups, GetLogMaxBackups())
+		assert.Equal(t, time.Duration(tc.expReadTimeout)*time.Second, GetReadTimeout())
+		assert.Equal(t, time.Duration(tc.expIdleTimeout)*time.Second, GetIdleTimeout())
+		assert.Equal(t, time.Duration(tc.expShutdownTimeout)*time.Second, GetShutdownTimeout())
+	}
+	assert.Equal(t, 5, htsconstants.DfltServerPropsLogMaxBackups)
+}
+
+func TestGetObjectPathNotFound(t *testing.T) {
+	_, err := GetObjectPath(htsconstants.APIEndpointReadsTicket, "NoDataSource.00001")
+	assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err))
+}
+
+var getAllowedSamplesTC = []struct {
+	id            string
+	principal     string
+	expSamples    []string
+	expRestricted bool
+}{
+	{"open.A1", "CN=alice", nil, false},
+	{"cohort.A1", "", []string{"S1"}, true},
+	{"cohort.A1", "CN=alice", []string{"S1", "S2", "S3"}, true},
+	{"cohort.A1", "CN=bob", []string{"S1"}, true},
+	{"private.A1", "", []string{}, true},
+	{"private.A1", "CN=bob", []string{"S4"}, true},
+	{"unknown.A1", "CN=alice", nil, false},
+}
+
+func TestGetAllowedSamples(t *testing.T) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"variants": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{
+						{"pattern": "^open\\.(?P<id>.*)$", "path": "./{id}.vcf.gz"},
+						{
+							"pattern": "^cohort\\.(?P<id>.*)$",
+							"path":    "./{id}.vcf.gz",
+							"sampleAccess": map[string][]string{
+								"*":        {"S1"},
+								"CN=alice": {"S2", "S3"},
+							},
+						},
+						{
+							"pattern": "^private\\.(?P<id>.*)$",
+							"path":    "./{id}.vcf.gz",
+							"sampleAccess": map[string][]string{
+								"CN=bob": {"S4"},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	SetConfigFile(newConfig)
+	LoadConfig()
+	defer func() {
+		SetConfigFile(nil)
+		LoadConfig()
+	}()
+
+	for _, tc := range getAllowedSamplesTC {
+		samples, restricted := GetAllowedSamples(htsconstants.APIEndpointVariantsData, tc.id, tc.principal)
+		assert.Equal(t, tc.expSamples, samples, tc.id+" "+tc.principal)
+		assert.Equal(t, tc.expRestricted, restricted, tc.id+" "+tc.principal)
+	}
+}
+
+var getFileDataSourceTC = []struct {
+	id       string
+	filePath string
+	expFound bool
+}{
+	{"local.A1", "./A1.bam", true},
+	{"local.A1", "./A2.bam", false},
+	{"local.A1", "/etc/passwd", false},
+	{"remote.A1", "https://example.org/A1.bam", false},
+	{"1000genomes.A1", "./A1.vcf.gz", true},
+	{"1000genomes.A1", "./A1.bam", false},
+	{"unknown.A1", "./A1.bam", false},
+	{"", "", false},
+}
+
+func TestGetFileDataSource(t *testing.T) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"reads": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{
+						{"pattern": "^local\\.(?P<id>.*)$", "path": "./{id}.bam"},
+						{"pattern": "^remote\\.(?P<id>.*)$", "path": "https://example.org/{id}.bam"},
+					},
+				},
+			},
+			"variants": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{
+						{"pattern": "^1000genomes\\.(?P<id>.*)$", "path": "./{id}.vcf.gz"},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	SetConfigFile(newConfig)
+	LoadConfig()
+	defer func() {
+		SetConfigFile(nil)
+		LoadConfig()
+	}()
+
+	for _, tc := range getFileDataSourceTC {
+		source, err := GetFileDataSource(tc.id, tc.filePath)
+		if tc.expFound {
+			assert.Nil(t, err, tc.filePath)
+			assert.NotNil(t, source, tc.filePath)
+		} else {
+			assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err), tc.filePath)
+		}
+	}
+}
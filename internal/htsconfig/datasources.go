@@ -8,10 +8,10 @@
 package htsconfig
 
 import (
-	"errors"
 	"regexp"
 	"strings"
 
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
 
@@ -30,11 +30,20 @@ type DataSourceRegistry struct {
 // Attributes
 //	Pattern (string): regex pattern indicating criteria for an ID to match the data source
 //	Path (string): path template, indicating how matching ids can be resolved to an exact location (path or url)
+//	Manifest (string): optional path to a file listing the ids served by the data source, one per line
+//	BlockSize (int): optional suggested byte size of the response from a single ticket url, overriding the endpoint's
+//	SampleAccess (map[string][]string): optional samples of variants objects each client may get, by client certificate subject ("*" for all clients)
 type DataSource struct {
-	Pattern string `json:"pattern"`
-	Path    string `json:"path"`
+	Pattern      string              `json:"pattern"`
+	Path         string              `json:"path"`
+	Manifest     string              `json:"manifest"`
+	BlockSize    int                 `json:"blockSize"`
+	SampleAccess map[string][]string `json:"sampleAccess"`
 }
 
+// sampleAccessAllClients key of the samples any client may get
+const sampleAccessAllClients = "*"
+
 // newDataSourceRegistry instantiates a data source registry
 //
 // Returns
@@ -84,6 +93,28 @@ func (dataSource *DataSource) evaluatePath(id string) (string, error) {
 	return finalPath, nil
 }
 
+// allowedSamples gets the samples of the data source's variants objects a
+// client may get: those listed for the client, and those listed for all
+// clients
+//
+//	Type: DataSource
+// Arguments
+//	principal (string): subject of the client certificate, empty if the client did not present one
+// Returns
+//	([]string): names of the samples the client may get
+//	(bool): true if the client's samples are restricted, false if it may get all samples
+func (dataSource *DataSource) allowedSamples(principal string) ([]string, bool) {
+	if dataSource.SampleAccess == nil {
+		return nil, false
+	}
+	allowed := []string{}
+	allowed = append(allowed, dataSource.SampleAccess[sampleAccessAllClients]...)
+	if principal != "" {
+		allowed = append(allowed, dataSource.SampleAccess[principal]...)
+	}
+	return allowed, true
+}
+
 // newDataSource creates a data source with the given pattern and path template
 //
 // Arguments
@@ -125,7 +156,7 @@ func (registry *DataSourceRegistry) findFirstMatch(id string) (*DataSource, erro
 			return registry.Sources[i], nil
 		}
 	}
-	return nil, errors.New("id: " + id + " did not match any registered data sources")
+	return nil, htserror.NewNotFound("id: "+id+" did not match any registered data sources", nil)
 }
 
 // GetMatchingPath gets the correct path to the object from the requested id
@@ -15,24 +15,57 @@ var defaultServiceType = &ServiceType{
 	Version:  htsconstants.ServiceInfoTypeVersion,
 }
 
+var defaultCacheEnabled = true
+
 var defaultEnabledReads = true
+var defaultListingEnabledReads = false
 var defaultFieldsParameterEffectiveReads = true
 var defaultTagsParametersEffectiveReads = true
 
 var defaultEnabledVariants = true
+var defaultListingEnabledVariants = false
 var defaultFieldsParameterEffectiveVariants = false
-var defaultTagsParametersEffectiveVariants = false
+var defaultTagsParametersEffectiveVariants = true
 
 var DefaultConfiguration = &Configuration{
 	Container: &configurationContainer{
 		ServerProps: &configurationServerProps{
-			Port:    htsconstants.DfltServerPropsPort,
-			Host:    htsconstants.DfltServerPropsHost,
-			Tempdir: htsconstants.DfltServerPropsTempdir,
-			Logfile: htsconstants.DfltServerPropsLogfile,
+			Port:            htsconstants.DfltServerPropsPort,
+			Host:            htsconstants.DfltServerPropsHost,
+			Tempdir:         htsconstants.DfltServerPropsTempdir,
+			Logfile:         htsconstants.DfltServerPropsLogfile,
+			LogLevel:        htsconstants.DfltServerPropsLogLevel,
+			LogMaxSize:      htsconstants.DfltServerPropsLogMaxSize,
+			LogMaxBackups:   &htsconstants.DfltServerPropsLogMaxBackups,
+			ReadTimeout:     &htsconstants.DfltServerPropsReadTimeout,
+			WriteTimeout:    &htsconstants.DfltServerPropsWriteTimeout,
+			IdleTimeout:     &htsconstants.DfltServerPropsIdleTimeout,
+			ShutdownTimeout: &htsconstants.DfltServerPropsShutdownTimeout,
+			Cache: &configurationCache{
+				Enabled:    &defaultCacheEnabled,
+				MaxEntries: htsconstants.DfltServerPropsCacheMaxEntries,
+				TTL:        htsconstants.DfltServerPropsCacheTTL,
+			},
+			TLS: &configurationTLS{
+				Cert:         htsconstants.DfltServerPropsTLSCert,
+				Key:          htsconstants.DfltServerPropsTLSKey,
+				MinVersion:   htsconstants.DfltServerPropsTLSMinVersion,
+				CipherSuites: htsconstants.DfltServerPropsTLSCipherSuites,
+				ClientCA:     htsconstants.DfltServerPropsTLSClientCA,
+				ClientAuth:   htsconstants.DfltServerPropsTLSClientAuth,
+			},
+			Tracing: &configurationTracing{
+				Endpoint:    htsconstants.DfltServerPropsTracingEndpoint,
+				ServiceName: htsconstants.DfltServerPropsTracingServiceName,
+			},
 		},
 		ReadsConfig: &configurationEndpoint{
 			Enabled: &defaultEnabledReads,
+			Listing: &configurationListing{
+				Enabled: &defaultListingEnabledReads,
+			},
+			BlockSize:        htsconstants.DfltBlockSize,
+			InlineHeaderSize: htsconstants.DfltInlineHeaderSize,
 			DataSourceRegistry: &DataSourceRegistry{
 				Sources: []*DataSource{
 					&DataSource{
@@ -65,11 +98,17 @@ var DefaultConfiguration = &Configuration{
 					Formats:                  htsconstants.APIEndpointReadsTicket.AllowedFormats(),
 					FieldsParameterEffective: &defaultFieldsParameterEffectiveReads,
 					TagsParametersEffective:  &defaultTagsParametersEffectiveReads,
+					ExtensionParameters:      htsconstants.HtsgetExtensionParametersReads,
 				},
 			},
 		},
 		VariantsConfig: &configurationEndpoint{
 			Enabled: &defaultEnabledVariants,
+			Listing: &configurationListing{
+				Enabled: &defaultListingEnabledVariants,
+			},
+			BlockSize:        htsconstants.DfltBlockSize,
+			InlineHeaderSize: htsconstants.DfltInlineHeaderSize,
 			DataSourceRegistry: &DataSourceRegistry{
 				Sources: []*DataSource{
 					&DataSource{
@@ -98,6 +137,7 @@ var DefaultConfiguration = &Configuration{
 					Formats:                  htsconstants.APIEndpointVariantsTicket.AllowedFormats(),
 					FieldsParameterEffective: &defaultFieldsParameterEffectiveVariants,
 					TagsParametersEffective:  &defaultTagsParametersEffectiveVariants,
+					ExtensionParameters:      htsconstants.HtsgetExtensionParametersVariants,
 				},
 			},
 		},
@@ -21,10 +21,25 @@ func TestDefaults(t *testing.T) {
 	// SERVER PROPS
 	assert.Equal(t, props.Host, htsconstants.DfltServerPropsHost)
 	assert.Equal(t, props.Port, htsconstants.DfltServerPropsPort)
+	assert.Equal(t, props.LogLevel, htsconstants.DfltServerPropsLogLevel)
+	assert.Equal(t, props.LogMaxSize, htsconstants.DfltServerPropsLogMaxSize)
+	assert.Equal(t, *props.LogMaxBackups, htsconstants.DfltServerPropsLogMaxBackups)
+	assert.Equal(t, *props.ReadTimeout, htsconstants.DfltServerPropsReadTimeout)
+	assert.Equal(t, *props.WriteTimeout, htsconstants.DfltServerPropsWriteTimeout)
+	assert.Equal(t, *props.IdleTimeout, htsconstants.DfltServerPropsIdleTimeout)
+	assert.Equal(t, *props.ShutdownTimeout, htsconstants.DfltServerPropsShutdownTimeout)
+	assert.Equal(t, *props.Cache.Enabled, true)
+	assert.Equal(t, props.Cache.MaxEntries, htsconstants.DfltServerPropsCacheMaxEntries)
+	assert.Equal(t, props.Cache.TTL, htsconstants.DfltServerPropsCacheTTL)
+	assert.Equal(t, props.TLS.MinVersion, htsconstants.DfltServerPropsTLSMinVersion)
+	assert.Equal(t, props.TLS.ClientAuth, htsconstants.DfltServerPropsTLSClientAuth)
 
 	// READS DATA SOURCE REGISTRY
 	assert.Equal(t, *reads.Enabled, true)
+	assert.Equal(t, reads.BlockSize, htsconstants.DfltBlockSize)
+	assert.Equal(t, reads.InlineHeaderSize, htsconstants.DfltInlineHeaderSize)
 
 	// VARIANTS DATA SOURCE REGISTRY
 	assert.Equal(t, *variants.Enabled, true)
+	assert.Equal(t, variants.BlockSize, htsconstants.DfltBlockSize)
 }
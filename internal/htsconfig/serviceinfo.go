@@ -31,4 +31,5 @@ type HtsgetExtension struct {
 	Formats                  []string `json:"formats"`
 	FieldsParameterEffective *bool    `json:"fieldsParameterEffective"`
 	TagsParametersEffective  *bool    `json:"tagsParametersEffective"`
+	ExtensionParameters      []string `json:"extensionParameters,omitempty"`
 }
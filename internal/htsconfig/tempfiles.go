@@ -0,0 +1,56 @@
+// Package htsconfig allows the program to be configured with modifiable
+// properties, affecting runtime properties. also contains program constants
+//
+// Module tempfiles keeps track of the temporary files created in the
+// tempdir, so that any files left behind by interrupted requests can be
+// removed when the server shuts down
+package htsconfig
+
+import (
+	"os"
+	"sync"
+)
+
+// tempfileRegistry paths of temporary files that have been created, but not
+// yet removed
+var tempfileRegistry = map[string]bool{}
+
+// tempfileRegistryMutex guards tempfileRegistry
+var tempfileRegistryMutex sync.Mutex
+
+// registerTempfile records that a temporary file exists at a path
+func registerTempfile(path string) {
+	tempfileRegistryMutex.Lock()
+	defer tempfileRegistryMutex.Unlock()
+	tempfileRegistry[path] = true
+}
+
+// deregisterTempfile records that the temporary file at a path was removed
+func deregisterTempfile(path string) {
+	tempfileRegistryMutex.Lock()
+	defer tempfileRegistryMutex.Unlock()
+	delete(tempfileRegistry, path)
+}
+
+// RemoveAllTempfiles removes all temporary files that have been created but
+// not yet removed
+//
+// Returns
+//	(int): number of temporary files removed
+func RemoveAllTempfiles() int {
+	tempfileRegistryMutex.Lock()
+	paths := []string{}
+	for path := range tempfileRegistry {
+		paths = append(paths, path)
+		delete(tempfileRegistry, path)
+	}
+	tempfileRegistryMutex.Unlock()
+
+	nRemoved := 0
+	for _, path := range paths {
+		if os.Remove(path) == nil {
+			nRemoved++
+		}
+	}
+	return nRemoved
+}
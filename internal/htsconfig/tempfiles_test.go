@@ -0,0 +1,44 @@
+// Package htsconfig allows the program to be configured with modifiable
+// properties, affecting runtime properties. also contains program constants
+//
+// Module tempfiles_test tests module tempfiles
+package htsconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTempfileUnique(t *testing.T) {
+	first, err := CreateTempfile("htsconfig-test-unique")
+	assert.Nil(t, err)
+	second, err := CreateTempfile("htsconfig-test-unique")
+	assert.Nil(t, err)
+
+	// the same filename never yields the same file
+	assert.NotEqual(t, first.Name(), second.Name())
+	assert.Equal(t, filepath.Clean(GetTempdir()), filepath.Dir(first.Name()))
+	assert.True(t, strings.HasPrefix(filepath.Base(first.Name()), "htsconfig-test-unique-"))
+	assert.Nil(t, RemoveTempfile(first))
+	assert.Nil(t, RemoveTempfile(second))
+}
+
+func TestRemoveAllTempfiles(t *testing.T) {
+	removed, _ := CreateTempfile("htsconfig-test-removed")
+	left1, _ := CreateTempfile("htsconfig-test-left1")
+	left2, _ := CreateTempfile("htsconfig-test-left2")
+	left1.Close()
+	left2.Close()
+
+	assert.Nil(t, RemoveTempfile(removed))
+	assert.Equal(t, 2, RemoveAllTempfiles())
+	for _, path := range []string{removed.Name(), left1.Name(), left2.Name()} {
+		_, err := os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	}
+	assert.Equal(t, 0, RemoveAllTempfiles())
+}
@@ -14,8 +14,20 @@ import (
 
 var StartupTime = time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC).UTC().Format(time.RFC3339)
 
-// SingleBlockByteSize suggested byte size of response from a single ticket url
-var SingleBlockByteSize = int64(5e8)
+// DfltBlockSize suggested byte size of response from a single ticket url, if
+// not configured for the endpoint or data source
+var DfltBlockSize = 500000000
+
+// DfltInlineHeaderSize maximum byte size of a header block inlined in a ticket
+// as a data uri, 0 if header blocks are not inlined
+var DfltInlineHeaderSize = 0
+
+// DfltListPageSize number of ids returned by a listing request if no page
+// size is requested
+var DfltListPageSize = 100
+
+// MaxListPageSize maximum number of ids a listing request may ask for
+var MaxListPageSize = 1000
 
 // BamFieldsN canonical number of fields in SAM/BAM (excluding tags)
 var BamFieldsN = 11
@@ -71,6 +83,35 @@ var FileByteRangeURLPath = "file-bytes"
 // FormatBam canonical htsget format string for .bam files
 var FormatBam = "BAM"
 
+// MediaTypeBam media type of BAM data, e.g. inlined in a ticket as a data uri
+var MediaTypeBam = "application/vnd.ga4gh.bam"
+
+// MediaTypeCram media type of CRAM data
+var MediaTypeCram = "application/vnd.ga4gh.cram"
+
+// MediaTypeVcf media type of VCF data
+var MediaTypeVcf = "application/vnd.ga4gh.vcf"
+
+// MediaTypeBcf media type of BCF data
+var MediaTypeBcf = "application/vnd.ga4gh.bcf"
+
+// formatMediaTypes (map[string]string): media type of the data of each format
+var formatMediaTypes = map[string]string{
+	FormatBam:  MediaTypeBam,
+	FormatCram: MediaTypeCram,
+	FormatVcf:  MediaTypeVcf,
+	FormatBcf:  MediaTypeBcf,
+}
+
+// MediaType gets the media type of the data of a format, or
+// application/octet-stream for an unknown format
+func MediaType(format string) string {
+	if mediaType, ok := formatMediaTypes[format]; ok {
+		return mediaType
+	}
+	return "application/octet-stream"
+}
+
 // FormatCram canonical htsget format string for .cram files
 var FormatCram = "CRAM"
 
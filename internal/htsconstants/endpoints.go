@@ -15,6 +15,13 @@ const (
 	APIEndpointVariantsData        APIEndpoint = 4
 	APIEndpointVariantsServiceInfo APIEndpoint = 5
 	APIEndpointFileBytes           APIEndpoint = 6
+	APIEndpointMetrics             APIEndpoint = 7
+	APIEndpointHealthz             APIEndpoint = 8
+	APIEndpointReadyz              APIEndpoint = 9
+	APIEndpointReadsList           APIEndpoint = 10
+	APIEndpointVariantsList        APIEndpoint = 11
+	APIEndpointReadsMetadata       APIEndpoint = 12
+	APIEndpointVariantsMetadata    APIEndpoint = 13
 )
 
 // maps enum int values to string representation
@@ -26,6 +33,13 @@ var htsEndpointStringMap = map[APIEndpoint]string{
 	APIEndpointVariantsData:        "/variants/data/{id}",
 	APIEndpointVariantsServiceInfo: "/variants/service-info",
 	APIEndpointFileBytes:           "/file-bytes",
+	APIEndpointMetrics:             "/metrics",
+	APIEndpointHealthz:             "/healthz",
+	APIEndpointReadyz:              "/readyz",
+	APIEndpointReadsList:           "/reads",
+	APIEndpointVariantsList:        "/variants",
+	APIEndpointReadsMetadata:       "/reads/{id}/metadata",
+	APIEndpointVariantsMetadata:    "/variants/{id}/metadata",
 }
 
 // maps ticket endpoints to their corresponding data endpoint prefixes
@@ -17,6 +17,13 @@ var endpointsTC = []struct {
 	{APIEndpointReadsData, "/reads/data/{id}"},
 	{APIEndpointVariantsServiceInfo, "/variants/service-info"},
 	{APIEndpointFileBytes, "/file-bytes"},
+	{APIEndpointMetrics, "/metrics"},
+	{APIEndpointHealthz, "/healthz"},
+	{APIEndpointReadyz, "/readyz"},
+	{APIEndpointReadsList, "/reads"},
+	{APIEndpointVariantsList, "/variants"},
+	{APIEndpointReadsMetadata, "/reads/{id}/metadata"},
+	{APIEndpointVariantsMetadata, "/variants/{id}/metadata"},
 }
 
 func TestEndpoints(t *testing.T) {
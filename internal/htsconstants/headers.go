@@ -40,16 +40,19 @@ type ContentTypeHeaderValue int
 // enum values for ContentTypeHeaderValue
 const (
 	ContentTypeHeaderHtsgetJSON ContentTypeHeaderValue = 0
+	ContentTypeHeaderJSON       ContentTypeHeaderValue = 1
 )
 
 // string representations of ContentTypeHeaderValue enum
 const (
 	contentTypeHeaderHtsgetJSONString string = "application/vnd.ga4gh.htsget.v1.2.0+json; charset=utf-8"
+	contentTypeHeaderJSONString       string = "application/json; charset=utf-8"
 )
 
 // contentTypeStringMap maps ContentTypeHeaderValue enum values to string representation
 var contentTypeStringMap = map[ContentTypeHeaderValue]string{
 	ContentTypeHeaderHtsgetJSON: contentTypeHeaderHtsgetJSONString,
+	ContentTypeHeaderJSON:       contentTypeHeaderJSONString,
 }
 
 // String gets the string representation of a ContentTypeHeaderValue enum instance
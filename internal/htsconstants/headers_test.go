@@ -21,6 +21,7 @@ var contentTypeTC = []struct {
 	exp string
 }{
 	{ContentTypeHeaderHtsgetJSON, "application/vnd.ga4gh.htsget.v1.2.0+json; charset=utf-8"},
+	{ContentTypeHeaderJSON, "application/json; charset=utf-8"},
 }
 
 func TestHeaderName(t *testing.T) {
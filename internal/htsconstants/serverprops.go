@@ -12,6 +12,40 @@ var DfltServerPropsTempdir = "."
 
 var DfltServerPropsLogfile = "htsget-refserver.log"
 
+var DfltServerPropsLogLevel = "info"
+
+var DfltServerPropsLogMaxSize = 100
+
+var DfltServerPropsLogMaxBackups = 5
+
+var DfltServerPropsTracingEndpoint = ""
+
+var DfltServerPropsTracingServiceName = "htsget-refserver"
+
+var DfltServerPropsReadTimeout = 60
+
+var DfltServerPropsWriteTimeout = 0
+
+var DfltServerPropsIdleTimeout = 120
+
+var DfltServerPropsShutdownTimeout = 30
+
+var DfltServerPropsCacheMaxEntries = 1000
+
+var DfltServerPropsCacheTTL = 600
+
+var DfltServerPropsTLSCert = ""
+
+var DfltServerPropsTLSKey = ""
+
+var DfltServerPropsTLSMinVersion = "1.2"
+
+var DfltServerPropsTLSCipherSuites = []string{}
+
+var DfltServerPropsTLSClientCA = ""
+
+var DfltServerPropsTLSClientAuth = "none"
+
 /* **************************************************
  * READS DATA SOURCE REGISTRY
  * ************************************************** */
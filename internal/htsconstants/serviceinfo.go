@@ -57,3 +57,11 @@ var DfltServiceInfoVariantsDescription = "Stream variant files (VCF/BCF) accordi
 var HtsgetExtensionDatatypeReads = "reads"
 
 var HtsgetExtensionDatatypeVariants = "variants"
+
+// HtsgetExtensionParametersReads query parameters of /reads/{id} beyond the
+// htsget specification: multiple regions, and filters of the returned reads
+var HtsgetExtensionParametersReads = []string{"regions", "requiredFlags", "excludedFlags", "minMappingQuality", "readGroups", "samples"}
+
+// HtsgetExtensionParametersVariants query parameters of /variants/{id} beyond
+// the htsget specification
+var HtsgetExtensionParametersVariants = []string{"regions", "samples"}
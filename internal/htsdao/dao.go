@@ -2,8 +2,10 @@ package htsdao
 
 import "github.com/ga4gh/htsget-refserver/internal/htsticket"
 
+// DataAccessObject locates the bytes of an object. failures are returned as
+// classified htserror errors (e.g. not found, upstream unavailable)
 type DataAccessObject interface {
-	GetContentLength() int64
-	GetByteRangeUrls() []*htsticket.URL
+	GetContentLength() (int64, error)
+	GetByteRangeUrls() ([]*htsticket.URL, error)
 	String() string
 }
@@ -0,0 +1,58 @@
+package htsdao
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilePathDaoByteRangeUrls(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htsdao-test-")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "object.bam")
+	ioutil.WriteFile(path, make([]byte, 250), 0644)
+
+	urls, err := NewFilePathDao("object", path, 100).GetByteRangeUrls()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(urls))
+	assert.Equal(t, "bytes=200-249", urls[2].Headers.Range)
+	assert.Equal(t, "object", urls[2].Headers.ObjectID)
+
+	_, err = NewFilePathDao("missing", filepath.Join(dir, "missing.bam"), 100).GetByteRangeUrls()
+	assert.Equal(t, htserror.KindNotFound, htserror.KindOf(err))
+}
+
+var urlDaoTC = []struct {
+	status  int
+	expKind htserror.Kind
+}{
+	{http.StatusOK, 0},
+	{http.StatusNotFound, htserror.KindNotFound},
+	{http.StatusBadGateway, htserror.KindUpstreamUnavailable},
+}
+
+func TestURLDaoByteRangeUrls(t *testing.T) {
+	for _, tc := range urlDaoTC {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Length", "250")
+			writer.WriteHeader(tc.status)
+		}))
+		urls, err := NewURLDao(context.Background(), "object", server.URL+"/object.bam", 100).GetByteRangeUrls()
+		assert.Equal(t, tc.expKind, htserror.KindOf(err), http.StatusText(tc.status))
+		if err == nil {
+			assert.Equal(t, 3, len(urls))
+		}
+		server.Close()
+	}
+
+	// the storage cannot be reached
+	_, err := NewURLDao(context.Background(), "object", "http://127.0.0.1:1/object.bam", 100).GetByteRangeUrls()
+	assert.Equal(t, htserror.KindUpstreamUnavailable, htserror.KindOf(err))
+}
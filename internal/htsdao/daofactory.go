@@ -1,23 +1,31 @@
 package htsdao
 
 import (
+	"context"
+
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
 
-func getMatchingDao(id string, registry *htsconfig.DataSourceRegistry) (DataAccessObject, error) {
+func getMatchingDao(ctx context.Context, id string, registry *htsconfig.DataSourceRegistry, blockSize int64) (DataAccessObject, error) {
+	_, span := htstrace.Start(ctx, "resolve data source", htstrace.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("htsget.id", id)
 	path, err := registry.GetMatchingPath(id)
 	if err != nil {
+		span.SetError(err)
 		return nil, err
 	}
 	if htsutils.IsValidURL(path) {
-		return NewURLDao(id, path), nil
+		return NewURLDao(ctx, id, path, blockSize), nil
 	}
-	return NewFilePathDao(id, path), nil
+	return NewFilePathDao(id, path, blockSize), nil
 }
 
 func GetDao(req *htsrequest.HtsgetRequest) (DataAccessObject, error) {
 	registry := req.GetDataSourceRegistry()
-	return getMatchingDao(req.ID(), registry)
+	blockSize := htsconfig.GetBlockSize(req.GetEndpoint(), req.ID())
+	return getMatchingDao(req.Context(), req.ID(), registry, blockSize)
 }
@@ -6,24 +6,30 @@ import (
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
 	"github.com/ga4gh/htsget-refserver/internal/htsticket"
 )
 
 type FilePathDao struct {
-	id       string
-	filePath string
+	id        string
+	filePath  string
+	blockSize int64
 }
 
-func NewFilePathDao(id string, filePath string) *FilePathDao {
+func NewFilePathDao(id string, filePath string, blockSize int64) *FilePathDao {
 	dao := new(FilePathDao)
 	dao.id = id
 	dao.filePath = filePath
+	dao.blockSize = blockSize
 	return dao
 }
 
-func (dao *FilePathDao) GetContentLength() int64 {
-	fileInfo, _ := os.Stat(dao.filePath)
-	return fileInfo.Size()
+func (dao *FilePathDao) GetContentLength() (int64, error) {
+	fileInfo, err := os.Stat(dao.filePath)
+	if err != nil {
+		return 0, htserror.NewNotFound("object not found", err)
+	}
+	return fileInfo.Size(), nil
 }
 
 func (dao *FilePathDao) constructByteRangeURL(start int64, end int64) *htsticket.URL {
@@ -32,15 +38,19 @@ func (dao *FilePathDao) constructByteRangeURL(start int64, end int64) *htsticket
 	headers := htsticket.NewHeaders()
 	headers.SetRangeHeader(start, end)
 	headers.SetFilePathHeader(dao.filePath)
+	headers.SetObjectIDHeader(dao.id)
 	url := htsticket.NewURL()
 	url.SetURL(path)
 	url.SetHeaders(headers)
 	return url
 }
 
-func (dao *FilePathDao) GetByteRangeUrls() []*htsticket.URL {
-	numBytes := dao.GetContentLength()
-	blockSize := htsconstants.SingleBlockByteSize
+func (dao *FilePathDao) GetByteRangeUrls() ([]*htsticket.URL, error) {
+	numBytes, err := dao.GetContentLength()
+	if err != nil {
+		return nil, err
+	}
+	blockSize := dao.blockSize
 	var start, end int64 = 0, 0
 	numBlocks := int(math.Ceil(float64(numBytes) / float64(blockSize)))
 	urls := []*htsticket.URL{}
@@ -53,7 +63,7 @@ func (dao *FilePathDao) GetByteRangeUrls() []*htsticket.URL {
 		start = end + 1
 		urls = append(urls, url)
 	}
-	return urls
+	return urls, nil
 }
 
 func (dao *FilePathDao) String() string {
@@ -1,34 +1,49 @@
 package htsdao
 
 import (
+	"context"
 	"math"
 	"net/http"
 
-	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
 	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
 
 type URLDao struct {
-	id  string
-	url string
+	ctx       context.Context
+	id        string
+	url       string
+	blockSize int64
 }
 
-func NewURLDao(id string, url string) *URLDao {
+func NewURLDao(ctx context.Context, id string, url string, blockSize int64) *URLDao {
 	dao := new(URLDao)
+	dao.ctx = ctx
 	dao.id = id
 	dao.url = url
+	dao.blockSize = blockSize
 	return dao
 }
 
-func (dao *URLDao) GetContentLength() int64 {
-	res, _ := http.Head(dao.url)
-	return res.ContentLength
+func (dao *URLDao) GetContentLength() (int64, error) {
+	res, err := htsutils.HeadObject(dao.ctx, dao.url)
+	if err != nil {
+		return 0, htserror.NewUpstreamUnavailable("could not get object", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return 0, htserror.NewHTTPStatusError(dao.url, res.StatusCode, res.Status)
+	}
+	return res.ContentLength, nil
 }
 
-func (dao *URLDao) GetByteRangeUrls() []*htsticket.URL {
+func (dao *URLDao) GetByteRangeUrls() ([]*htsticket.URL, error) {
 
-	numBytes := dao.GetContentLength()
-	blockSize := htsconstants.SingleBlockByteSize
+	numBytes, err := dao.GetContentLength()
+	if err != nil {
+		return nil, err
+	}
+	blockSize := dao.blockSize
 	var start, end int64 = 0, 0
 	numBlocks := int(math.Ceil(float64(numBytes) / float64(blockSize)))
 	urls := []*htsticket.URL{}
@@ -45,7 +60,7 @@ func (dao *URLDao) GetByteRangeUrls() []*htsticket.URL {
 		start = end + 1
 		urls = append(urls, url)
 	}
-	return urls
+	return urls, nil
 }
 
 func (dao *URLDao) String() string {
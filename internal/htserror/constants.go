@@ -26,6 +26,10 @@ const codeNotFound = http.StatusNotFound
 // codeInternalServerError (int): status code for unspecified server-side error
 const codeInternalServerError = http.StatusInternalServerError
 
+// codeUpstreamUnavailable (int): status code for storage that could not be
+// reached
+const codeUpstreamUnavailable = http.StatusServiceUnavailable
+
 /* Error Names: htsget canonical error names */
 
 // errorBadRequestUnsupportedFormat (string): error name for unsupported format
@@ -49,6 +53,10 @@ const errorNotFound = "NotFound"
 // errorInternalServerError (string): error name for unspecified server errors
 const errorInternalServerError = "InternalServerError"
 
+// errorUpstreamUnavailable (string): error name for storage that could not be
+// reached
+const errorUpstreamUnavailable = "UpstreamUnavailable"
+
 /* Default Messages: default error message by error name */
 
 // dfltMsgBadRequestUnsupportedFormat (string): default unsupported format message
@@ -72,6 +80,9 @@ const dfltMsgNotFound = "The resource requested was not found"
 // dfltMsgInternalServerError (string): default message for unspecified errors
 const dfltMsgInternalServerError = "Internal server error"
 
+// dfltMsgUpstreamUnavailable (string): default message for unreachable storage
+const dfltMsgUpstreamUnavailable = "The storage serving the resource is unavailable"
+
 // errorInfoMap (map[string]map[string]string) maps error name to status code
 // and default message
 var errorInfoMap = map[string]map[string]string{
@@ -103,4 +114,8 @@ var errorInfoMap = map[string]map[string]string{
 		"code":    strconv.Itoa(codeInternalServerError),
 		"dfltMsg": dfltMsgInternalServerError,
 	},
+	errorUpstreamUnavailable: {
+		"code":    strconv.Itoa(codeUpstreamUnavailable),
+		"dfltMsg": dfltMsgUpstreamUnavailable,
+	},
 }
@@ -12,10 +12,12 @@ import (
 )
 
 // htsgetError contains attributes to write an error as an HTTP response,
-// including response code and JSON body container
+// including response code and JSON body container. the detail of the error
+// (e.g. its cause) is recorded, but not written to the client
 type htsgetError struct {
 	Code   int
 	Htsget errorContainer `json:"htsget"`
+	detail string
 }
 
 // errorContainer contains attributes for the main htsget error response body
@@ -29,6 +31,14 @@ func (err *htsgetError) Error() string {
 	return fmt.Sprint(err.Htsget.Error + ": " + err.Htsget.Message)
 }
 
+// ErrorRecorder is implemented by HTTP ResponseWriters that keep track of the
+// htsget error written to them, so that it can be reported (e.g. logged) once
+// the response is complete. the message is the one written to the client,
+// and the detail the full description of the error, for the server only
+type ErrorRecorder interface {
+	RecordHtsgetError(err string, message string, detail string)
+}
+
 // newHtsgetError instantiates a new htsgetError instance
 func newHtsgetError(code int, err string, message string) *htsgetError {
 	htsgetError := &htsgetError{
@@ -37,6 +47,7 @@ func newHtsgetError(code int, err string, message string) *htsgetError {
 			err,
 			message,
 		},
+		detail: message,
 	}
 	return htsgetError
 }
@@ -44,6 +55,9 @@ func newHtsgetError(code int, err string, message string) *htsgetError {
 // writeHTTPError writes an htsgetError code and body to the HTTP ResponseWriter
 func writeHTTPError(writer http.ResponseWriter, err error) {
 	if err, ok := err.(*htsgetError); ok {
+		if recorder, ok := writer.(ErrorRecorder); ok {
+			recorder.RecordHtsgetError(err.Htsget.Error, err.Htsget.Message, err.detail)
+		}
 		writer.Header().Set("Content-Type", "application/json")
 		writer.WriteHeader(err.Code)
 		json.NewEncoder(writer).Encode(map[string]interface{}{
@@ -108,6 +122,11 @@ func NotFound(writer http.ResponseWriter, msgPtr *string) {
 	htsgetErrorTemplate(writer, errorNotFound, msgPtr)
 }
 
+// UpstreamUnavailable writes an UpstreamUnavailable error to the HTTP ResponseWriter
+func UpstreamUnavailable(writer http.ResponseWriter, msgPtr *string) {
+	htsgetErrorTemplate(writer, errorUpstreamUnavailable, msgPtr)
+}
+
 // InternalServerError writes an InternalServerError error to the HTTP ResponseWriter
 func InternalServerError(writer http.ResponseWriter, msgPtr *string) {
 	htsgetErrorTemplate(writer, errorInternalServerError, msgPtr)
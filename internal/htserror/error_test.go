@@ -68,6 +68,12 @@ var errorsTC = []struct {
 		"InternalServerError: Internal server error",
 		codeInternalServerError,
 	},
+	{
+		UpstreamUnavailable,
+		nil,
+		"UpstreamUnavailable: The storage serving the resource is unavailable",
+		codeUpstreamUnavailable,
+	},
 }
 
 func TestErrors(t *testing.T) {
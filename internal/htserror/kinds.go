@@ -0,0 +1,179 @@
+// Package htserror write HTTP errors with various codes, response bodies
+//
+// Module kinds classifies the failures of resolving and reading objects
+// (object not found, upstream unavailable, unsupported index, tool failure,
+// input the object cannot satisfy, internal failure),
+// so that they are returned by DAOs and handlers as typed errors, and written
+// as htsget errors with a single mapping to error names and status codes
+package htserror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind classifies a failure, determining the htsget error and status code it
+// is reported with
+type Kind int
+
+const (
+	// KindNotFound the requested object does not exist, or no data source
+	// serves its id
+	KindNotFound Kind = iota + 1
+	// KindUpstreamUnavailable the storage serving the object could not be
+	// reached, or failed to respond
+	KindUpstreamUnavailable
+	// KindUnsupportedIndex the object has no index, or an index that cannot
+	// be read, so that it cannot be queried by region
+	KindUnsupportedIndex
+	// KindToolFailure a tool (samtools/bcftools) processing the object failed
+	KindToolFailure
+	// KindInvalidInput the request asks for data the object does not hold,
+	// e.g. a sample it has no genotypes for
+	KindInvalidInput
+	// KindInternal the server failed, for a reason unrelated to the object
+	// or the request
+	KindInternal
+)
+
+// kindInfo htsget error name and status code a kind of failure is reported
+// with
+type kindInfo struct {
+	errorName string
+	code      int
+}
+
+// kindInfoMap (map[Kind]kindInfo): maps each kind of failure to the htsget
+// error written for it
+var kindInfoMap = map[Kind]kindInfo{
+	KindNotFound:            {errorNotFound, codeNotFound},
+	KindUpstreamUnavailable: {errorUpstreamUnavailable, codeUpstreamUnavailable},
+	KindUnsupportedIndex:    {errorBadRequestUnsupportedFormat, codeBadRequest},
+	KindToolFailure:         {errorInternalServerError, codeInternalServerError},
+	KindInvalidInput:        {errorBadRequestInvalidInput, codeBadRequest},
+	KindInternal:            {errorInternalServerError, codeInternalServerError},
+}
+
+// Error is a classified failure, wrapping its cause
+//
+// Attributes
+//	Kind (Kind): classification of the failure
+//	Message (string): description of the failure, reported to the client. it
+//		must not disclose server details (paths, urls, tool output)
+//	Err (error): underlying cause, if any. only logged
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+// Error describes the failure and its cause
+//
+//	Type: Error
+// Returns
+//	(string): description of the failure
+func (err *Error) Error() string {
+	if err.Err == nil {
+		return err.Message
+	}
+	return err.Message + ": " + err.Err.Error()
+}
+
+// Unwrap gets the cause of the failure
+//
+//	Type: Error
+// Returns
+//	(error): underlying cause, nil if none
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// newError instantiates a classified failure
+func newError(kind Kind, message string, cause error) *Error {
+	return &Error{Kind: kind, Message: message, Err: cause}
+}
+
+// NewNotFound creates a failure for an object that does not exist
+func NewNotFound(message string, cause error) *Error {
+	return newError(KindNotFound, message, cause)
+}
+
+// NewUpstreamUnavailable creates a failure for storage that could not be
+// reached, or failed to respond
+func NewUpstreamUnavailable(message string, cause error) *Error {
+	return newError(KindUpstreamUnavailable, message, cause)
+}
+
+// NewUnsupportedIndex creates a failure for an object without a usable index
+func NewUnsupportedIndex(message string, cause error) *Error {
+	return newError(KindUnsupportedIndex, message, cause)
+}
+
+// NewToolFailure creates a failure for a tool processing an object
+func NewToolFailure(message string, cause error) *Error {
+	return newError(KindToolFailure, message, cause)
+}
+
+// NewInvalidInput creates a failure for a request the object cannot satisfy
+func NewInvalidInput(message string, cause error) *Error {
+	return newError(KindInvalidInput, message, cause)
+}
+
+// NewInternalError creates a failure of the server itself
+func NewInternalError(message string, cause error) *Error {
+	return newError(KindInternal, message, cause)
+}
+
+// NewHTTPStatusError classifies the non-successful response of the storage
+// serving an object: 404 and 410 as not found, any other status as upstream
+// unavailable
+//
+// Arguments
+//	location (string): url of the object
+//	status (int): status code of the response
+//	statusText (string): status line of the response
+// Returns
+//	(*Error): classified failure
+func NewHTTPStatusError(location string, status int, statusText string) *Error {
+	cause := errors.New("could not get " + location + ": " + statusText)
+	if status == http.StatusNotFound || status == http.StatusGone {
+		return NewNotFound("object not found", cause)
+	}
+	return NewUpstreamUnavailable("could not get object: "+statusText, cause)
+}
+
+// KindOf gets the kind of a failure, looking through wrapped errors
+//
+// Arguments
+//	err (error): failure
+// Returns
+//	(Kind): kind of the failure, 0 if it is not classified
+func KindOf(err error) Kind {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified.Kind
+	}
+	return 0
+}
+
+// WriteError writes a failure to the HTTP ResponseWriter as an htsget error,
+// with the error name and status code of its kind. only the message of the
+// failure is written to the client, while the full description, with its
+// cause, is recorded for the access log. unclassified failures are written
+// as an InternalServerError, with the default message
+//
+// Arguments
+//	writer (http.ResponseWriter): response writer
+//	err (error): failure
+func WriteError(writer http.ResponseWriter, err error) {
+	var classified *Error
+	var htsgetErr *htsgetError
+	if !errors.As(err, &classified) {
+		htsgetErr = newHtsgetError(codeInternalServerError, errorInternalServerError, dfltMsgInternalServerError)
+	} else {
+		info := kindInfoMap[classified.Kind]
+		htsgetErr = newHtsgetError(info.code, info.errorName, classified.Message)
+	}
+	htsgetErr.detail = err.Error()
+	writeHTTPError(writer, htsgetErr)
+}
@@ -0,0 +1,91 @@
+// Package htserror write HTTP errors with various codes, response bodies
+//
+// Module kinds_test tests kinds
+package htserror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var cause = errors.New("exit status 1")
+
+var writeErrorTC = []struct {
+	err        error
+	expKind    Kind
+	expCode    int
+	expError   string
+	expMessage string
+	expDetail  string
+}{
+	{NewNotFound("object not found", cause), KindNotFound, http.StatusNotFound, "NotFound", "object not found", "object not found: exit status 1"},
+	{NewUpstreamUnavailable("could not get object", nil), KindUpstreamUnavailable, http.StatusServiceUnavailable, "UpstreamUnavailable", "could not get object", "could not get object"},
+	{NewUnsupportedIndex("object has no BAI index", nil), KindUnsupportedIndex, http.StatusBadRequest, "UnsupportedFormat", "object has no BAI index", "object has no BAI index"},
+	{NewToolFailure("samtools failed", cause), KindToolFailure, http.StatusInternalServerError, "InternalServerError", "samtools failed", "samtools failed: exit status 1"},
+	{NewInvalidInput("sample NA1 is not in the object", nil), KindInvalidInput, http.StatusBadRequest, "InvalidInput", "sample NA1 is not in the object", "sample NA1 is not in the object"},
+	{NewInternalError("could not list ids", cause), KindInternal, http.StatusInternalServerError, "InternalServerError", "could not list ids", "could not list ids: exit status 1"},
+	{fmt.Errorf("resolving: %w", NewNotFound("no data source", nil)), KindNotFound, http.StatusNotFound, "NotFound", "no data source", "resolving: no data source"},
+	{NewHTTPStatusError("https://example.org/obj.bam", http.StatusGone, "410 Gone"), KindNotFound, http.StatusNotFound, "NotFound", "object not found", "object not found: could not get https://example.org/obj.bam: 410 Gone"},
+	{cause, 0, http.StatusInternalServerError, "InternalServerError", dfltMsgInternalServerError, "exit status 1"},
+}
+
+// detailRecorder records the htsget error written to it, as ErrorRecorder
+type detailRecorder struct {
+	*httptest.ResponseRecorder
+	message string
+	detail  string
+}
+
+func (recorder *detailRecorder) RecordHtsgetError(err string, message string, detail string) {
+	recorder.message = message
+	recorder.detail = detail
+}
+
+func TestWriteError(t *testing.T) {
+	// the client only gets the message of a failure, while the cause is
+	// recorded for the server
+	for _, tc := range writeErrorTC {
+		assert.Equal(t, tc.expKind, KindOf(tc.err), tc.err.Error())
+
+		writer := &detailRecorder{ResponseRecorder: httptest.NewRecorder()}
+		WriteError(writer, tc.err)
+		assert.Equal(t, tc.expCode, writer.Code, tc.err.Error())
+		htsgetErrObj := new(htsgetError)
+		json.Unmarshal(writer.Body.Bytes(), htsgetErrObj)
+		assert.Equal(t, tc.expError, htsgetErrObj.Htsget.Error)
+		assert.Equal(t, tc.expMessage, htsgetErrObj.Htsget.Message)
+		assert.NotContains(t, writer.Body.String(), cause.Error())
+		assert.Equal(t, tc.expMessage, writer.message)
+		assert.Equal(t, tc.expDetail, writer.detail)
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	err := NewToolFailure("samtools failed", cause)
+	assert.True(t, errors.Is(err, cause))
+	assert.Nil(t, NewNotFound("not found", nil).Unwrap())
+}
+
+var httpStatusErrorTC = []struct {
+	status  int
+	expKind Kind
+}{
+	{http.StatusNotFound, KindNotFound},
+	{http.StatusGone, KindNotFound},
+	{http.StatusForbidden, KindUpstreamUnavailable},
+	{http.StatusInternalServerError, KindUpstreamUnavailable},
+	{http.StatusServiceUnavailable, KindUpstreamUnavailable},
+}
+
+func TestNewHTTPStatusError(t *testing.T) {
+	for _, tc := range httpStatusErrorTC {
+		err := NewHTTPStatusError("https://bucket.s3.amazonaws.com/obj.bam", tc.status, http.StatusText(tc.status))
+		assert.Equal(t, tc.expKind, err.Kind, http.StatusText(tc.status))
+	}
+}
@@ -0,0 +1,100 @@
+// Package htsexec runs the external bioinformatics tools (samtools, bcftools)
+// the service depends on
+//
+// Module command wraps exec.Cmd so that every tool subprocess is
+// instrumented and tracked the same way
+package htsexec
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
+)
+
+// Cmd is an external tool subprocess. it behaves as an exec.Cmd, additionally
+// recording the duration of the subprocess once it has been waited on, and
+// tracing it as a span
+type Cmd struct {
+	*exec.Cmd
+	ctx    context.Context
+	tool   string
+	start  time.Time
+	span   *htstrace.Span
+	waited bool
+}
+
+// Command instantiates a Cmd to run the named tool with the given arguments.
+// the subprocess is killed if the context is done before it exits
+//
+// Arguments
+//	ctx (context.Context): request context, carrying the parent trace span
+//	tool (string): name of the tool executable (e.g. "samtools")
+//	args (...string): command-line arguments passed to the tool
+// Returns
+//	(*Cmd): the unstarted command
+func Command(ctx context.Context, tool string, args ...string) *Cmd {
+	cmd := new(Cmd)
+	cmd.Cmd = exec.CommandContext(ctx, tool, args...)
+	cmd.ctx = ctx
+	cmd.tool = tool
+	return cmd
+}
+
+// Start starts the subprocess, without waiting for it to complete
+func (cmd *Cmd) Start() error {
+	name := cmd.tool
+	if len(cmd.Args) > 1 {
+		name += " " + cmd.Args[1]
+	}
+	_, span := htstrace.Start(cmd.ctx, name, htstrace.SpanKindInternal)
+	span.SetAttribute("process.command_line", strings.Join(cmd.Args, " "))
+
+	start := time.Now()
+	err := cmd.Cmd.Start()
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		return err
+	}
+	cmd.start = start
+	cmd.span = span
+	register(cmd)
+	return nil
+}
+
+// Wait waits for the started subprocess to exit, and records its duration
+func (cmd *Cmd) Wait() error {
+	err := cmd.Cmd.Wait()
+	if !cmd.start.IsZero() && !cmd.waited {
+		cmd.waited = true
+		deregister(cmd)
+		htsmetrics.ObserveSubprocess(cmd.tool, cmd.start)
+		cmd.span.SetError(err)
+		cmd.span.End()
+	}
+	return err
+}
+
+// Run starts the subprocess and waits for it to complete
+func (cmd *Cmd) Run() error {
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	return cmd.Wait()
+}
+
+// Close kills the subprocess if it was started and has not yet been waited
+// on, then waits on it. it allows handlers returning early to release the
+// subprocess, and does nothing once the subprocess has been waited on
+func (cmd *Cmd) Close() {
+	if cmd.start.IsZero() || cmd.waited {
+		return
+	}
+	cmd.Process.Kill()
+	cmd.Wait()
+}
@@ -0,0 +1,55 @@
+// Package htsexec runs the external bioinformatics tools (samtools, bcftools)
+// the service depends on
+//
+// Module registry keeps track of running tool subprocesses, so that they can
+// be killed when the server shuts down
+package htsexec
+
+import (
+	"sync"
+)
+
+// running subprocesses that have been started, but not yet waited on
+var running = map[*Cmd]bool{}
+
+// runningMutex guards running
+var runningMutex sync.Mutex
+
+// register records that a subprocess has been started
+func register(cmd *Cmd) {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+	running[cmd] = true
+}
+
+// deregister records that a subprocess has been waited on
+func deregister(cmd *Cmd) {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+	delete(running, cmd)
+}
+
+// NumRunning gets the number of subprocesses that have been started, but not
+// yet waited on
+func NumRunning() int {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+	return len(running)
+}
+
+// KillAll kills all subprocesses that have been started, but not yet waited
+// on. the goroutines waiting on them return with an error
+//
+// Returns
+//	(int): number of subprocesses killed
+func KillAll() int {
+	runningMutex.Lock()
+	defer runningMutex.Unlock()
+	nKilled := 0
+	for cmd := range running {
+		if cmd.Process.Kill() == nil {
+			nKilled++
+		}
+	}
+	return nKilled
+}
@@ -0,0 +1,64 @@
+// Package htsexec runs the external bioinformatics tools (samtools, bcftools)
+// the service depends on
+//
+// Module registry_test tests module registry
+package htsexec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKillAll(t *testing.T) {
+	cmds := []*Cmd{
+		Command(context.Background(), "sleep", "10"),
+		Command(context.Background(), "sleep", "10"),
+	}
+	for _, cmd := range cmds {
+		assert.Nil(t, cmd.Start())
+	}
+	assert.Equal(t, 2, NumRunning())
+	assert.Equal(t, 2, KillAll())
+	for _, cmd := range cmds {
+		assert.NotNil(t, cmd.Wait())
+	}
+	assert.Equal(t, 0, NumRunning())
+}
+
+func TestClose(t *testing.T) {
+	// close kills a running subprocess
+	cmd := Command(context.Background(), "sleep", "10")
+	assert.Nil(t, cmd.Start())
+	assert.Equal(t, 1, NumRunning())
+	cmd.Close()
+	assert.Equal(t, 0, NumRunning())
+	assert.False(t, cmd.ProcessState.Success())
+
+	// close does nothing once the subprocess has been waited on
+	cmd = Command(context.Background(), "true")
+	assert.Nil(t, cmd.Run())
+	cmd.Close()
+	assert.True(t, cmd.ProcessState.Success())
+	assert.Equal(t, 0, NumRunning())
+
+	// close does nothing if the subprocess was never started
+	cmd = Command(context.Background(), "true")
+	cmd.Close()
+	assert.Nil(t, cmd.ProcessState)
+}
+
+func TestCommandContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := Command(ctx, "sleep", "10")
+	assert.Nil(t, cmd.Start())
+	cancel()
+	assert.NotNil(t, cmd.Wait())
+	assert.Equal(t, 0, NumRunning())
+
+	// a subprocess cannot be started once the context is done
+	cmd = Command(ctx, "true")
+	assert.NotNil(t, cmd.Start())
+	assert.Equal(t, 0, NumRunning())
+}
@@ -0,0 +1,89 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module bamheader.go separates the header of a BAM stream from its records,
+// as they are compressed, so that a body block can be served without the
+// header a tool encoded with it
+package htsformats
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// SkipBAMHeader reads the BGZF blocks holding the header from the start of a
+// BAM stream, leaving the reader at the first block of records. the length
+// of the header is read from the stream itself, so the header may differ
+// from the header of the object (e.g. with an @PG line added by samtools).
+// htslib flushes the header before writing records, so records never share a
+// block with it
+//
+// Arguments
+//	reader (*bufio.Reader): BAM stream, positioned at its start
+// Returns
+//	(error): if not nil, the stream is not BAM, is truncated, or its records share a block with the header
+func SkipBAMHeader(reader *bufio.Reader) error {
+	var header bytes.Buffer
+	for {
+		// gzip reads a BGZF block (gzip member) byte by byte from a
+		// bufio.Reader, without reading into the next block
+		block, err := gzip.NewReader(reader)
+		if err == io.EOF {
+			return errors.New("BAM stream ended within its header")
+		}
+		if err != nil {
+			return err
+		}
+		block.Multistream(false)
+		if _, err := io.Copy(&header, block); err != nil {
+			return err
+		}
+		if header.Len() >= len(bamMagic) && !bytes.HasPrefix(header.Bytes(), []byte(bamMagic)) {
+			return errors.New("stream is not BAM")
+		}
+		if size, ok := bamHeaderSize(header.Bytes()); ok {
+			if size != header.Len() {
+				return errors.New("BAM header shares a BGZF block with records")
+			}
+			return nil
+		}
+	}
+}
+
+// bamHeaderSize gets the uncompressed size of a BAM header: magic, text and
+// reference sequence dictionary. false if more of the header must be read to
+// know its size
+func bamHeaderSize(data []byte) (int, bool) {
+	int32At := func(offset int) (int, bool) {
+		if offset+4 > len(data) {
+			return 0, false
+		}
+		n := int(int32(binary.LittleEndian.Uint32(data[offset:])))
+		return n, n >= 0
+	}
+	textLen, ok := int32At(len(bamMagic))
+	if !ok {
+		return 0, false
+	}
+	size := len(bamMagic) + 4 + textLen
+	numRefs, ok := int32At(size)
+	if !ok {
+		return 0, false
+	}
+	size += 4
+	for i := 0; i < numRefs; i++ {
+		nameLen, ok := int32At(size)
+		if !ok {
+			return 0, false
+		}
+		// name, then reference sequence length
+		size += 4 + nameLen + 4
+	}
+	if size > len(data) {
+		return 0, false
+	}
+	return size, true
+}
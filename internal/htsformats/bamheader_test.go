@@ -0,0 +1,104 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module bamheader_test tests bamheader
+package htsformats
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/bgzf"
+	"github.com/biogo/hts/sam"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeTestBAM encodes a BAM stream holding a header with the given @PG
+// program ids, followed by a number of reads
+func encodeTestBAM(t *testing.T, programs []string, numReads int) []byte {
+	ref, _ := sam.NewReference("chr1", "", "", 1000000, nil, nil)
+	header, err := sam.NewHeader(nil, []*sam.Reference{ref})
+	assert.Nil(t, err)
+	for _, id := range programs {
+		assert.Nil(t, header.AddProgram(sam.NewProgram(id, "samtools", "samtools view -b -", "", "1.10")))
+	}
+	var output bytes.Buffer
+	writer, err := bam.NewWriter(&output, header, 1)
+	assert.Nil(t, err)
+	seq := []byte(strings.Repeat("A", 50))
+	qual := []byte(strings.Repeat("\x1e", 50))
+	cigar := []sam.CigarOp{sam.NewCigarOp(sam.CigarMatch, 50)}
+	for i := 0; i < numReads; i++ {
+		record, err := sam.NewRecord("read", ref, nil, i*100, -1, 0, 60, cigar, seq, qual, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Write(record))
+	}
+	assert.Nil(t, writer.Close())
+	return output.Bytes()
+}
+
+func TestSkipBAMHeader(t *testing.T) {
+	// the records of a stream whose header holds an added @PG line follow
+	// the header of the object once its own header is skipped
+	objectHeader := encodeTestBAM(t, nil, 0)
+	stream := encodeTestBAM(t, []string{"samtools", "samtools.1"}, 1000)
+	reader := bufio.NewReader(bytes.NewReader(stream))
+	assert.Nil(t, SkipBAMHeader(reader))
+	records, _ := ioutil.ReadAll(reader)
+
+	body := append(objectHeader[:len(objectHeader)-htsconstants.BamEOFLen], records...)
+	bamReader, err := bam.NewReader(bytes.NewReader(body), 1)
+	assert.Nil(t, err)
+	n := 0
+	for {
+		_, err := bamReader.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		n++
+	}
+	assert.Equal(t, 1000, n)
+}
+
+var skipBAMHeaderInvalidTC = []struct {
+	name   string
+	stream func(t *testing.T) []byte
+}{
+	{"empty", func(t *testing.T) []byte { return []byte{} }},
+	{"truncated", func(t *testing.T) []byte { return encodeTestBAM(t, nil, 0)[:20] }},
+	{"not BGZF", func(t *testing.T) []byte { return []byte("@HD\tVN:1.6\n") }},
+	{
+		"not BAM",
+		func(t *testing.T) []byte {
+			var output bytes.Buffer
+			writer := bgzf.NewWriter(&output, 1)
+			writer.Write([]byte("##fileformat=VCFv4.2\n"))
+			writer.Close()
+			return output.Bytes()
+		},
+	},
+	{
+		"records in the header block",
+		func(t *testing.T) []byte {
+			var output bytes.Buffer
+			writer := bgzf.NewWriter(&output, 1)
+			writer.Write([]byte("BAM\x01\x00\x00\x00\x00\x00\x00\x00\x00"))
+			writer.Write([]byte("record"))
+			writer.Close()
+			return output.Bytes()
+		},
+	},
+}
+
+func TestSkipBAMHeaderInvalid(t *testing.T) {
+	for _, tc := range skipBAMHeaderInvalidTC {
+		reader := bufio.NewReader(bytes.NewReader(tc.stream(t)))
+		assert.NotNil(t, SkipBAMHeader(reader), tc.name)
+	}
+}
@@ -6,9 +6,11 @@
 package htsformats
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
@@ -100,6 +102,47 @@ func (samRecord *SAMRecord) emitCustomTags(htsgetReq *htsrequest.HtsgetRequest)
 	return emittedTags
 }
 
+// Position gets the 1-based leftmost mapping position of the record (POS),
+// 0 if it is not set
+func (samRecord *SAMRecord) Position() int64 {
+	if len(samRecord.columns) <= htsconstants.BamFields["POS"] {
+		return 0
+	}
+	position, _ := strconv.ParseInt(samRecord.columns[htsconstants.BamFields["POS"]], 10, 64)
+	return position
+}
+
+// Region gets the region of the reference sequence the record is aligned to,
+// from POS and the reference bases consumed by its CIGAR. as in htslib, a
+// record without a CIGAR, or truncated before it, covers a single base
+func (samRecord *SAMRecord) Region() *htsregion.Region {
+	name := ""
+	if len(samRecord.columns) > htsconstants.BamFields["RNAME"] {
+		name = samRecord.columns[htsconstants.BamFields["RNAME"]]
+	}
+	start := samRecord.Position() - 1
+	cigar := ""
+	if len(samRecord.columns) > htsconstants.BamFields["CIGAR"] {
+		cigar = samRecord.columns[htsconstants.BamFields["CIGAR"]]
+	}
+	span, length := int64(0), int64(0)
+	for _, c := range cigar {
+		if c >= '0' && c <= '9' {
+			length = length*10 + int64(c-'0')
+			continue
+		}
+		// M, D, N, = and X consume the reference
+		if strings.ContainsRune("MDN=X", c) {
+			span += length
+		}
+		length = 0
+	}
+	if span == 0 {
+		span = 1
+	}
+	return htsregion.NewRegion(name, start, start+span)
+}
+
 // CustomEmit emits a new SAM record from an existing record, with only the
 // requested fields and tags included
 func (samRecord *SAMRecord) CustomEmit(htsgetReq *htsrequest.HtsgetRequest) string {
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
 	"github.com/stretchr/testify/assert"
 )
@@ -75,6 +76,30 @@ var samrecordCustomEmitTC = []struct {
 	},
 }
 
+var samrecordRegionTC = []struct {
+	cigar      string
+	start, end int64
+}{
+	{"100M", 24613322, 24613422},
+	// soft clips and insertions do not consume the reference, deletions and
+	// skipped regions do
+	{"5S20M3I10M2D30N40M", 24613322, 24613424},
+	{"10=1X9=", 24613322, 24613342},
+	// a record without a CIGAR covers a single base
+	{"*", 24613322, 24613323},
+}
+
+func TestSamRecordRegion(t *testing.T) {
+	for _, tc := range samrecordRegionTC {
+		line := "A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t24613323\t3\t" + tc.cigar + "\t=\t24613553\t330\t*\t*"
+		assert.Equal(t, htsregion.NewRegion("chr1", tc.start, tc.end), NewSAMRecord(line).Region(), tc.cigar)
+	}
+
+	// a record truncated before its CIGAR is still on its reference sequence
+	line := "A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t24613323"
+	assert.Equal(t, htsregion.NewRegion("chr1", 24613322, 24613323), NewSAMRecord(line).Region())
+}
+
 func TestSamRecordEmitCustomFields(t *testing.T) {
 	for _, tc := range samrecordEmitCustomFieldsTC {
 		samrecord := NewSAMRecord(tc.line)
@@ -0,0 +1,84 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module samstream.go masks the fields and tags of all records in a stream of
+// SAM lines, one line at a time, so that memory use does not depend on the
+// number of records
+package htsformats
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+)
+
+// samHeaderLinePrefix first character of SAM header lines
+const samHeaderLinePrefix = '@'
+
+// MaskSAMStream reads SAM lines, and writes them with only the requested
+// fields and tags of each record included. header lines are written
+// unchanged. if the request is for a block continuing a split
+// region, records starting before the block's start are dropped, as an
+// earlier block holds them. likewise, if an earlier block of a multi-region
+// request serves a region of the same reference sequence, records
+// overlapping that region are dropped
+//
+// Arguments
+//	htsgetReq (*htsrequest.HtsgetRequest): request holding fields, tags and notags
+//	reader (io.Reader): SAM lines, optionally preceded by the header
+//	writer (io.Writer): receives the masked SAM lines
+// Returns
+//	(error): if not nil, reading or writing the stream failed
+func MaskSAMStream(htsgetReq *htsrequest.HtsgetRequest, reader io.Reader, writer io.Writer) error {
+	lineReader := bufio.NewReader(reader)
+	lineWriter := bufio.NewWriter(writer)
+	minPosition := int64(0)
+	if htsgetReq.HtsgetBlockSplit() {
+		region, err := htsgetReq.Region()
+		if err != nil {
+			return err
+		}
+		minPosition = region.Start
+	}
+	var prevRegion *htsregion.Region
+	if htsgetReq.HtsgetPrevRegion() != "" {
+		region, err := htsregion.ParseRegion(htsgetReq.HtsgetPrevRegion())
+		if err != nil {
+			return err
+		}
+		prevRegion = region
+	}
+
+	for {
+		line, readErr := lineReader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 && line[0] != samHeaderLinePrefix {
+			record := NewSAMRecord(string(line))
+			// POS is 1-based, region starts are 0-based
+			if record.Position()-1 < minPosition {
+				line = nil
+			} else if prevRegion != nil && prevRegion.Overlaps(record.Region()) {
+				line = nil
+			} else {
+				line = []byte(record.CustomEmit(htsgetReq))
+			}
+		}
+		if len(line) > 0 {
+			if _, err := lineWriter.Write(line); err != nil {
+				return err
+			}
+			if err := lineWriter.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return lineWriter.Flush()
+}
@@ -0,0 +1,124 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module samstream_test tests samstream
+package htsformats
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/stretchr/testify/assert"
+)
+
+var samstreamRecord = "A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t24613323\t3\t100M\t=\t24613553\t330\tCAATAAGGAATG\tFFFFFFFFFFFF\tNH:i:2\tHI:i:1"
+
+var maskSAMStreamTC = []struct {
+	input, fields, tags, notags, exp string
+}{
+	// header lines pass through unchanged, records are masked
+	{
+		"@HD\tVN:1.6\tSO:coordinate\n@SQ\tSN:chr1\tLN:248956422\n" + samstreamRecord + "\n" + samstreamRecord + "\n",
+		"QNAME,FLAG,RNAME",
+		"ALL",
+		"HI",
+		"@HD\tVN:1.6\tSO:coordinate\n@SQ\tSN:chr1\tLN:248956422\n" +
+			"A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t0\t255\t*\t*\t0\t0\t*\t*\tNH:i:2\n" +
+			"A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t0\t255\t*\t*\t0\t0\t*\t*\tNH:i:2\n",
+	},
+	// last line without a trailing newline
+	{
+		samstreamRecord,
+		"ALL",
+		"HI",
+		"NONE",
+		"A00111:67:H3M5YDMXX:1:2407:21558:16094\t99\tchr1\t24613323\t3\t100M\t=\t24613553\t330\tCAATAAGGAATG\tFFFFFFFFFFFF\tHI:i:1\n",
+	},
+	// empty stream
+	{
+		"",
+		"QNAME",
+		"ALL",
+		"NONE",
+		"",
+	},
+}
+
+func TestMaskSAMStream(t *testing.T) {
+	for _, tc := range maskSAMStreamTC {
+		htsreq := htsrequest.NewHtsgetRequest()
+		htsreq.AddListParam("fields", strings.Split(tc.fields, ","))
+		htsreq.AddListParam("tags", strings.Split(tc.tags, ","))
+		htsreq.AddListParam("notags", strings.Split(tc.notags, ","))
+		var output bytes.Buffer
+		err := MaskSAMStream(htsreq, strings.NewReader(tc.input), &output)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.exp, output.String())
+	}
+}
+
+func TestMaskSAMStreamSplitBlock(t *testing.T) {
+	before := strings.Replace(samstreamRecord, "\t24613323\t", "\t24613000\t", 1)
+	input := "@HD\tVN:1.6\n" + before + "\n" + samstreamRecord + "\n"
+
+	// a block continuing a split region drops reads starting before it
+	htsreq := htsrequest.NewHtsgetRequest()
+	htsreq.AddScalarParam("start", "24613322")
+	htsreq.AddScalarParam("HtsgetBlockSplit", "true")
+	htsreq.AddListParam("fields", []string{"ALL"})
+	htsreq.AddListParam("tags", []string{"ALL"})
+	htsreq.AddListParam("notags", []string{"NONE"})
+	var output bytes.Buffer
+	err := MaskSAMStream(htsreq, strings.NewReader(input), &output)
+	assert.Nil(t, err)
+	assert.Equal(t, "@HD\tVN:1.6\n"+samstreamRecord+"\n", output.String())
+
+	// the first block of the region keeps them
+	htsreq.AddScalarParam("HtsgetBlockSplit", "")
+	output.Reset()
+	MaskSAMStream(htsreq, strings.NewReader(input), &output)
+	assert.Equal(t, input, output.String())
+}
+
+func TestMaskSAMStreamPrevRegion(t *testing.T) {
+	// a read from 24613322 to 24613422, and one after it
+	after := strings.Replace(samstreamRecord, "\t24613323\t", "\t24613500\t", 1)
+	input := "@HD\tVN:1.6\n" + samstreamRecord + "\n" + after + "\n"
+
+	// a block following a region of the same reference drops the reads
+	// overlapping that region, which its block holds
+	htsreq := htsrequest.NewHtsgetRequest()
+	htsreq.AddScalarParam("referenceName", "chr1")
+	htsreq.AddScalarParam("start", "24613410")
+	htsreq.AddScalarParam("HtsgetPrevRegion", "chr1:24613300-24613400")
+	htsreq.AddListParam("fields", []string{"ALL"})
+	htsreq.AddListParam("tags", []string{"ALL"})
+	htsreq.AddListParam("notags", []string{"NONE"})
+	var output bytes.Buffer
+	err := MaskSAMStream(htsreq, strings.NewReader(input), &output)
+	assert.Nil(t, err)
+	assert.Equal(t, "@HD\tVN:1.6\n"+after+"\n", output.String())
+
+	// reads ending before the region after them are kept
+	htsreq.AddScalarParam("HtsgetPrevRegion", "chr1:24613000-24613322")
+	output.Reset()
+	MaskSAMStream(htsreq, strings.NewReader(input), &output)
+	assert.Equal(t, input, output.String())
+}
+
+// failingWriter fails every write, as a closed pipe does
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestMaskSAMStreamWriteError(t *testing.T) {
+	htsreq := htsrequest.NewHtsgetRequest()
+	htsreq.AddListParam("fields", []string{"QNAME"})
+	input := strings.Repeat(samstreamRecord+"\n", 10000)
+	err := MaskSAMStream(htsreq, strings.NewReader(input), failingWriter{})
+	assert.EqualError(t, err, "broken pipe")
+}
@@ -0,0 +1,223 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module split.go splits a region of a BAM object into consecutive
+// sub-regions of about the same compressed size, estimated from the BAI
+// index, so that a large region can be served as several body blocks
+package htsformats
+
+import (
+	"context"
+	"errors"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/sam"
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
+)
+
+// splitTileWidth genomic width of a BAI linear index tile. sub-regions start
+// on tile boundaries, the finest resolution of the index
+const splitTileWidth = 1 << 14
+
+// splitTileBin number of the first bin of the smallest level of the BAI
+// binning scheme, whose bins are the tiles of the linear index
+const splitTileBin = 4681
+
+// tiles counts the linear index tiles a region overlaps, the bins of the
+// smallest level among its BAI bins
+//
+// Arguments
+//	region (*htsregion.Region): closed region
+// Returns
+//	(int64): number of tiles
+func tiles(region *htsregion.Region) int64 {
+	var n int64
+	for _, bin := range region.Bins() {
+		if bin >= splitTileBin {
+			n++
+		}
+	}
+	return n
+}
+
+// regionIndex estimates the compressed size of genomic intervals on a single
+// reference sequence from a BAM index
+//
+// Attributes
+//	index (*bam.Index): BAI index of the object
+//	reference (*sam.Reference): reference sequence, with its id in the index
+type regionIndex struct {
+	index     *bam.Index
+	reference *sam.Reference
+}
+
+// newRegionIndex looks up a reference sequence of a BAM object by name
+//
+// Arguments
+//	index (*bam.Index): BAI index of the object
+//	header (*htscache.Header): parsed header of the object
+//	name (string): reference sequence name
+// Returns
+//	(*regionIndex): size estimator for the reference sequence
+//	(int64): length of the reference sequence
+//	(error): if not nil, the reference sequence is not in the header
+func newRegionIndex(index *bam.Index, header *htscache.Header, name string) (*regionIndex, int64, error) {
+	references := make([]*sam.Reference, len(header.References))
+	var found *sam.Reference
+	var length int64
+	for i, ref := range header.References {
+		reference, err := sam.NewReference(ref.Name, "", "", int(ref.Length), nil, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		references[i] = reference
+		if ref.Name == name {
+			found = reference
+			length = ref.Length
+		}
+	}
+	if found == nil {
+		return nil, 0, errors.New("reference sequence " + name + " is not in the header")
+	}
+
+	// reference ids are assigned in header order, matching the index
+	if _, err := sam.NewHeader(nil, references); err != nil {
+		return nil, 0, err
+	}
+	return &regionIndex{index: index, reference: found}, length, nil
+}
+
+// size estimates the number of compressed bytes holding the reads
+// overlapping a genomic interval, from the file offsets of its index chunks
+//
+//	Type: regionIndex
+// Arguments
+//	beg (int64): interval start
+//	end (int64): interval end
+// Returns
+//	(int64): estimated compressed size in bytes
+func (ri *regionIndex) size(beg int64, end int64) int64 {
+	chunks, err := ri.index.Chunks(ri.reference, int(beg), int(end))
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, chunk := range chunks {
+		if chunk.End.File > chunk.Begin.File {
+			size += chunk.End.File - chunk.Begin.File
+		}
+	}
+	return size
+}
+
+// SplitRegion splits a region of a BAM object into consecutive sub-regions,
+// each holding about blockSize compressed bytes, as estimated from the BAI
+// index, and into at least the requested number of partitions. the
+// sub-regions tile the region exactly: each ends where the next starts, so
+// that a read is served in the sub-region its alignment starts in,
+// provided later sub-regions skip reads starting before them. a region whose
+// size does not exceed blockSize is not split, unless partitions is set
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the BAM object
+//	region (*htsregion.Region): requested region
+//	blockSize (int64): suggested compressed size of each sub-region
+//	partitions (int): minimum number of sub-regions, 0 to split by size only
+// Returns
+//	([]*htsregion.Region): consecutive sub-regions, in order
+//	(error): if not nil, the header or index of the object could not be read
+func SplitRegion(ctx context.Context, path string, region *htsregion.Region, blockSize int64, partitions int) ([]*htsregion.Region, error) {
+	header, err := htscache.ReadsHeader(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := htscache.BAMIndex(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	ri, length, err := newRegionIndex(index, header, region.Name)
+	if err != nil {
+		return nil, err
+	}
+	return ri.split(region, length, blockSize, partitions), nil
+}
+
+// split splits a region of the reference sequence into consecutive
+// sub-regions, each holding about blockSize compressed bytes, and at least
+// partitions sub-regions of about the same size
+//
+//	Type: regionIndex
+// Arguments
+//	region (*htsregion.Region): requested region
+//	length (int64): length of the reference sequence
+//	blockSize (int64): suggested compressed size of each sub-region
+//	partitions (int): minimum number of sub-regions
+// Returns
+//	([]*htsregion.Region): consecutive sub-regions, in order
+func (ri *regionIndex) split(region *htsregion.Region, length int64, blockSize int64, partitions int) []*htsregion.Region {
+	beg, end := region.Bounds(length)
+	// a region within a single tile is not split, as the index cannot
+	// separate its reads
+	bounded := htsregion.NewRegion(region.Name, beg, end)
+	if bounded.Bin() >= splitTileBin {
+		return []*htsregion.Region{region}
+	}
+	total := ri.size(beg, end)
+	numBlocks := int64(1)
+	if blockSize > 0 {
+		numBlocks = (total + blockSize - 1) / blockSize
+	}
+	if int64(partitions) > numBlocks {
+		numBlocks = int64(partitions)
+	}
+	// a region is not split finer than the tiles it overlaps
+	if n := tiles(bounded); numBlocks > n {
+		numBlocks = n
+	}
+	if numBlocks < 2 || total == 0 {
+		return []*htsregion.Region{region}
+	}
+
+	// find the tile boundary at which each block's share of the total size
+	// has been reached. the first boundary is after the start, so that each
+	// sub-region ends after it starts. boundaries that do not separate any
+	// bytes from the previous or next sub-region (e.g. within a single index
+	// chunk) are dropped
+	boundaries := []int64{}
+	lo := beg/splitTileWidth + 1
+	hi := (end - 1) / splitTileWidth
+	var reached int64
+	for k := int64(1); k < numBlocks && lo <= hi; k++ {
+		target := total * k / numBlocks
+		if target <= reached {
+			continue
+		}
+		tileLo, tileHi := lo, hi
+		for tileLo < tileHi {
+			mid := (tileLo + tileHi) / 2
+			if ri.size(beg, mid*splitTileWidth) >= target {
+				tileHi = mid
+			} else {
+				tileLo = mid + 1
+			}
+		}
+		size := ri.size(beg, tileLo*splitTileWidth)
+		if size <= reached || size >= total {
+			break
+		}
+		boundaries = append(boundaries, tileLo*splitTileWidth)
+		reached = size
+		lo = tileLo + 1
+	}
+
+	regions := []*htsregion.Region{}
+	previous := *region
+	for _, boundary := range boundaries {
+		subRegion := previous
+		subRegion.End, subRegion.OpenEnd = boundary, false
+		regions = append(regions, &subRegion)
+		previous.Start, previous.OpenStart = boundary, false
+	}
+	return append(regions, &previous)
+}
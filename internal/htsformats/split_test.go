@@ -0,0 +1,155 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module split_test tests split
+package htsformats
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/bgzf"
+	"github.com/biogo/hts/sam"
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
+	"github.com/stretchr/testify/assert"
+)
+
+// splitTestBAM path of the test BAM object, indexed alongside
+var splitTestBAM = filepath.Join("..", "..", "data", "test", "sources", "tabulamuris", "A1-B000168-3_57_F-1-1_R2.mus.Aligned.out.sorted.bam")
+
+// readTestHeader reads the reference dictionary of the test BAM object
+func readTestHeader(t *testing.T) *htscache.Header {
+	file, err := os.Open(splitTestBAM)
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := bam.NewReader(file, 1)
+	assert.Nil(t, err)
+	header := new(htscache.Header)
+	for _, ref := range reader.Header().Refs() {
+		header.References = append(header.References, &htscache.Reference{Name: ref.Name(), Length: int64(ref.Len())})
+	}
+	return header
+}
+
+// newSyntheticIndex indexes a read at the start of each of the first 1000
+// index tiles of a 20 Mb reference sequence, each taking up 100 compressed
+// bytes
+func newSyntheticIndex(t *testing.T) (*bam.Index, *htscache.Header) {
+	ref, _ := sam.NewReference("chr1", "", "", 20000000, nil, nil)
+	_, err := sam.NewHeader(nil, []*sam.Reference{ref})
+	assert.Nil(t, err)
+	index := new(bam.Index)
+	for i := 0; i < 1000; i++ {
+		cigar := []sam.CigarOp{sam.NewCigarOp(sam.CigarMatch, 100)}
+		seq := []byte(strings.Repeat("A", 100))
+		record, err := sam.NewRecord("read"+strconv.Itoa(i), ref, nil, i*splitTileWidth, -1, 0, 60, cigar, seq, nil, nil)
+		assert.Nil(t, err)
+		chunk := bgzf.Chunk{
+			Begin: bgzf.Offset{File: int64(i * 100)},
+			End:   bgzf.Offset{File: int64((i + 1) * 100)},
+		}
+		assert.Nil(t, index.Add(record, chunk))
+	}
+	header := new(htscache.Header)
+	header.References = []*htscache.Reference{{Name: "chr1", Length: 20000000}}
+	return index, header
+}
+
+// assertTiles checks that sub-regions tile a region, each ending where the
+// next starts
+func assertTiles(t *testing.T, region *htsregion.Region, regions []*htsregion.Region) {
+	first, last := regions[0], regions[len(regions)-1]
+	assert.Equal(t, region.Start, first.Start)
+	assert.Equal(t, region.OpenStart, first.OpenStart)
+	assert.Equal(t, region.End, last.End)
+	assert.Equal(t, region.OpenEnd, last.OpenEnd)
+	for i := 1; i < len(regions); i++ {
+		assert.Equal(t, region.Name, regions[i].Name)
+		assert.False(t, regions[i-1].OpenEnd)
+		assert.False(t, regions[i].OpenStart)
+		assert.Equal(t, regions[i-1].End, regions[i].Start)
+		assert.True(t, regions[i].Start%splitTileWidth == 0)
+		assert.True(t, regions[i-1].End > regions[i-1].Start)
+	}
+}
+
+var splitRegionTC = []struct {
+	region       *htsregion.Region
+	blockSize    int64
+	partitions   int
+	expNumBlocks int
+}{
+	{htsregion.NewReferenceRegion("chr1"), 200000, 0, 1},
+	{htsregion.NewReferenceRegion("chr1"), 0, 0, 1},
+	{htsregion.NewReferenceRegion("chr1"), 25000, 0, 4},
+	{htsregion.NewRegion("chr1", 4096000, 12288000), 10000, 0, 5},
+	{&htsregion.Region{Name: "chr1", Start: 8192000, OpenEnd: true}, 10000, 0, 5},
+	{htsregion.NewRegion("chr1", 100, 5000), 1, 0, 1},
+	{htsregion.NewReferenceRegion("chr1"), 0, 4, 4},
+	{htsregion.NewReferenceRegion("chr1"), 200000, 8, 8},
+	{htsregion.NewRegion("chr1", 4096000, 12288000), 10000, 2, 5},
+	{htsregion.NewRegion("chr1", 100, 5000), 0, 4, 1},
+	{&htsregion.Region{Name: "chr1", Start: 17000000, OpenEnd: true}, 0, 4, 1},
+}
+
+var tilesTC = []struct {
+	region   *htsregion.Region
+	expTiles int64
+}{
+	{htsregion.NewRegion("chr1", 0, 1), 1},
+	{htsregion.NewRegion("chr1", 0, splitTileWidth), 1},
+	{htsregion.NewRegion("chr1", splitTileWidth-1, splitTileWidth+1), 2},
+	{htsregion.NewRegion("chr1", 4096000, 12288000), 500},
+}
+
+func TestTiles(t *testing.T) {
+	for _, tc := range tilesTC {
+		assert.Equal(t, tc.expTiles, tiles(tc.region), tc.region.String())
+	}
+}
+
+func TestSplitRegion(t *testing.T) {
+	index, header := newSyntheticIndex(t)
+	ri, length, err := newRegionIndex(index, header, "chr1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(20000000), length)
+
+	for _, tc := range splitRegionTC {
+		regions := ri.split(tc.region, length, tc.blockSize, tc.partitions)
+		assert.Equal(t, tc.expNumBlocks, len(regions), tc.region.String())
+		assertTiles(t, tc.region, regions)
+
+		// sub-regions share the size of the region about equally
+		if tc.expNumBlocks > 1 {
+			total := ri.size(tc.region.Bounds(length))
+			expSize := float64(total) / float64(len(regions))
+			for _, subRegion := range regions {
+				size := ri.size(subRegion.Bounds(length))
+				assert.InDelta(t, expSize, size, expSize/5, subRegion.String())
+			}
+		}
+	}
+
+	_, _, err = newRegionIndex(index, header, "chrNotInHeader")
+	assert.NotNil(t, err)
+}
+
+func TestSplitRegionTestBAM(t *testing.T) {
+	header := readTestHeader(t)
+	index, err := htscache.BAMIndex(context.Background(), splitTestBAM)
+	assert.Nil(t, err)
+
+	// the reads of the small test object share a single index chunk, which
+	// cannot be split
+	for _, ref := range header.References {
+		ri, length, err := newRegionIndex(index, header, ref.Name)
+		assert.Nil(t, err)
+		region := htsregion.NewReferenceRegion(ref.Name)
+		assert.Equal(t, []*htsregion.Region{region}, ri.split(region, length, 1000, 4))
+	}
+}
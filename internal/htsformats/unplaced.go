@@ -0,0 +1,194 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module unplaced.go locates the unplaced unmapped reads of a coordinate
+// sorted BAM object from its BAI index, and reads their records from the tail
+// of the object, so that they are served without scanning the placed reads
+package htsformats
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/bgzf"
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// bamMagic magic string starting the uncompressed stream of a BAM object
+const bamMagic = "BAM\x01"
+
+// virtualOffset orders virtual file offsets: compressed offset of the BGZF
+// block, then offset within the uncompressed block
+func virtualOffset(offset bgzf.Offset) int64 {
+	return offset.File<<16 | int64(offset.Block)
+}
+
+// UnplacedUnmappedOffset gets the virtual file offset at which the unplaced
+// unmapped reads of a coordinate sorted BAM object start. reads without a
+// reference sequence are sorted after all placed reads, so they start where
+// the indexed reads of the last reference sequence end
+//
+// Arguments
+//	index (*bam.Index): BAI index of the object
+// Returns
+//	(bgzf.Offset): virtual offset following the last placed read
+//	(bool): false if no reads are placed, unplaced reads then follow the header
+func UnplacedUnmappedOffset(index *bam.Index) (bgzf.Offset, bool) {
+	var offset bgzf.Offset
+	placed := false
+	for id := 0; id < index.NumRefs(); id++ {
+		stats, ok := index.ReferenceStats(id)
+		if !ok {
+			continue
+		}
+		if !placed || virtualOffset(stats.Chunk.End) > virtualOffset(offset) {
+			offset = stats.Chunk.End
+			placed = true
+		}
+	}
+	return offset, placed
+}
+
+// unplacedRecords uncompressed records of the unplaced unmapped reads,
+// closing the object they are read from when closed
+type unplacedRecords struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (records *unplacedRecords) Close() error {
+	var err error
+	for _, closer := range records.closers {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// UnplacedUnmappedRecords opens the records of the unplaced unmapped reads of
+// a BAM object, as uncompressed BAM records without the header. the object is
+// read from the offset the BAI index gives for the first unplaced read, from
+// a local file, or with a range request on a url
+//
+// Arguments
+//	ctx (context.Context): request context
+//	path (string): local file path or url of the BAM object
+// Returns
+//	(io.ReadCloser): uncompressed records, up to the end of the object
+//	(error): if not nil, the index or object could not be read
+func UnplacedUnmappedRecords(ctx context.Context, path string) (io.ReadCloser, error) {
+	index, err := htscache.BAMIndex(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	offset, placed := UnplacedUnmappedOffset(index)
+	object, err := openObjectFrom(ctx, path, offset.File)
+	if err != nil {
+		return nil, err
+	}
+
+	// the index of an object without unplaced reads may point past its last
+	// block
+	reader, err := bgzf.NewReader(object, 0)
+	if err == io.EOF {
+		return &unplacedRecords{Reader: bytes.NewReader(nil), closers: []io.Closer{object}}, nil
+	}
+	if err != nil {
+		object.Close()
+		return nil, errors.New("could not read " + path + ": " + err.Error())
+	}
+	records := &unplacedRecords{Reader: reader, closers: []io.Closer{reader, object}}
+
+	if placed {
+		_, err = io.CopyN(ioutil.Discard, reader, int64(offset.Block))
+	} else {
+		err = skipBAMHeader(reader)
+	}
+	if err != nil {
+		records.Close()
+		return nil, errors.New("could not read " + path + ": " + err.Error())
+	}
+	return records, nil
+}
+
+// openObjectFrom opens a local file or url for reading from an offset
+func openObjectFrom(ctx context.Context, path string, start int64) (io.ReadCloser, error) {
+	if !htsutils.IsValidURL(path) {
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			return nil, htserror.NewNotFound("object not found", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(start, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return file, nil
+	}
+
+	res, err := htsutils.GetObjectFrom(ctx, path, start)
+	if err != nil {
+		return nil, htserror.NewUpstreamUnavailable("could not get object", err)
+	}
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		return res.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		res.Body.Close()
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	case http.StatusOK:
+		// the range is not supported, the bytes before it are skipped
+		if _, err := io.CopyN(ioutil.Discard, res.Body, start); err != nil && err != io.EOF {
+			res.Body.Close()
+			return nil, htserror.NewUpstreamUnavailable("could not get object", err)
+		}
+		return res.Body, nil
+	}
+	res.Body.Close()
+	return nil, htserror.NewHTTPStatusError(path, res.StatusCode, res.Status)
+}
+
+// skipBAMHeader reads past the header of an uncompressed BAM stream: the
+// magic string, the header text, and the reference dictionary
+func skipBAMHeader(reader io.Reader) error {
+	magic := make([]byte, len(bamMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return err
+	}
+	if string(magic) != bamMagic {
+		return errors.New("not a BAM object")
+	}
+
+	var textLen, numRefs int32
+	if err := binary.Read(reader, binary.LittleEndian, &textLen); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(ioutil.Discard, reader, int64(textLen)); err != nil {
+		return err
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &numRefs); err != nil {
+		return err
+	}
+	for i := int32(0); i < numRefs; i++ {
+		var nameLen int32
+		if err := binary.Read(reader, binary.LittleEndian, &nameLen); err != nil {
+			return err
+		}
+		// name, then the length of the reference sequence
+		if _, err := io.CopyN(ioutil.Discard, reader, int64(nameLen)+4); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module unplaced_test tests unplaced
+package htsformats
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/bgzf"
+	"github.com/biogo/hts/sam"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeUnplacedTestBAM writes an indexed BAM object holding a number of
+// placed reads, followed by a number of unplaced unmapped reads
+func writeUnplacedTestBAM(t *testing.T, dir string, numPlaced int, numUnplaced int) string {
+	path := filepath.Join(dir, "unplaced_"+strconv.Itoa(numPlaced)+".bam")
+	ref, _ := sam.NewReference("chr1", "", "", 1000000, nil, nil)
+	header, err := sam.NewHeader(nil, []*sam.Reference{ref})
+	assert.Nil(t, err)
+	header.SortOrder = sam.Coordinate
+
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+	writer, err := bam.NewWriter(file, header, 1)
+	assert.Nil(t, err)
+	seq := []byte(strings.Repeat("A", 50))
+	qual := []byte(strings.Repeat("\x1e", 50))
+	for i := 0; i < numPlaced; i++ {
+		cigar := []sam.CigarOp{sam.NewCigarOp(sam.CigarMatch, 50)}
+		record, err := sam.NewRecord("placed"+strconv.Itoa(i), ref, nil, i*1000, -1, 0, 60, cigar, seq, qual, nil)
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Write(record))
+	}
+	for i := 0; i < numUnplaced; i++ {
+		record, err := sam.NewRecord("unplaced"+strconv.Itoa(i), nil, nil, -1, -1, 0, 0, nil, seq, qual, nil)
+		assert.Nil(t, err)
+		record.Flags = sam.Unmapped
+		assert.Nil(t, writer.Write(record))
+	}
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, file.Close())
+
+	// index the reads at the chunks they were written to
+	file, err = os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := bam.NewReader(file, 1)
+	assert.Nil(t, err)
+	index := new(bam.Index)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		if record.Ref == nil {
+			// the reader decodes the unset position of unplaced reads
+			// as unsigned
+			record.Pos = -1
+		}
+		assert.Nil(t, index.Add(record, reader.LastChunk()))
+	}
+	indexFile, err := os.Create(path + ".bai")
+	assert.Nil(t, err)
+	assert.Nil(t, bam.WriteIndex(indexFile, index))
+	assert.Nil(t, indexFile.Close())
+	return path
+}
+
+// readRecordNames gets the read names of a stream of uncompressed BAM
+// records, checking that none of them is placed
+func readRecordNames(t *testing.T, reader io.Reader) []string {
+	names := []string{}
+	for {
+		var blockSize int32
+		err := binary.Read(reader, binary.LittleEndian, &blockSize)
+		if err == io.EOF {
+			return names
+		}
+		assert.Nil(t, err)
+		data := make([]byte, blockSize)
+		_, err = io.ReadFull(reader, data)
+		assert.Nil(t, err)
+		assert.Equal(t, int32(-1), int32(binary.LittleEndian.Uint32(data[0:4])))
+		nameLen := int(data[8])
+		names = append(names, string(data[32:32+nameLen-1]))
+	}
+}
+
+func TestUnplacedUnmappedOffset(t *testing.T) {
+	file, err := os.Open(splitTestBAM + ".bai")
+	assert.Nil(t, err)
+	defer file.Close()
+	index, err := bam.ReadIndex(file)
+	assert.Nil(t, err)
+
+	// the unplaced reads start where the reads of the last reference
+	// sequence end
+	offset, placed := UnplacedUnmappedOffset(index)
+	assert.True(t, placed)
+	for id := 0; id < index.NumRefs(); id++ {
+		if stats, ok := index.ReferenceStats(id); ok {
+			assert.True(t, virtualOffset(stats.Chunk.End) <= virtualOffset(offset))
+		}
+	}
+
+	_, placed = UnplacedUnmappedOffset(new(bam.Index))
+	assert.False(t, placed)
+	assert.Equal(t, int64(1<<16|5), virtualOffset(bgzf.Offset{File: 1, Block: 5}))
+}
+
+var unplacedUnmappedRecordsTC = []struct {
+	numPlaced   int
+	numUnplaced int
+}{
+	{5, 3},
+	{0, 3},
+	{5, 0},
+}
+
+func TestUnplacedUnmappedRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htsget-unplaced")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	for _, tc := range unplacedUnmappedRecordsTC {
+		path := writeUnplacedTestBAM(t, dir, tc.numPlaced, tc.numUnplaced)
+		expNames := []string{}
+		for i := 0; i < tc.numUnplaced; i++ {
+			expNames = append(expNames, "unplaced"+strconv.Itoa(i))
+		}
+
+		// local file, and url served with range requests
+		for _, objPath := range []string{path, server.URL + "/" + filepath.Base(path)} {
+			records, err := UnplacedUnmappedRecords(context.Background(), objPath)
+			assert.Nil(t, err, objPath)
+			if err != nil {
+				continue
+			}
+			assert.Equal(t, expNames, readRecordNames(t, records), objPath)
+			assert.Nil(t, records.Close())
+		}
+	}
+}
+
+func TestUnplacedUnmappedRecordsTestBAM(t *testing.T) {
+	// all reads of the test object are placed
+	records, err := UnplacedUnmappedRecords(context.Background(), splitTestBAM)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{}, readRecordNames(t, records))
+	assert.Nil(t, records.Close())
+
+	_, err = UnplacedUnmappedRecords(context.Background(), "../../data/test/sources/tabulamuris/NotFound.bam")
+	assert.NotNil(t, err)
+}
+
+// unplacedTestBAM test object holding unplaced unmapped reads: the object
+// and index written by samtools for splitTestBAM, with 10 unplaced unmapped
+// reads following its last block, where the index ends its placed reads
+var unplacedTestBAM = "../../data/test/sources/unplaced/A1-B000168-3_57_F-1-1_R2.unplaced.bam"
+
+func TestUnplacedUnmappedRecordsFixture(t *testing.T) {
+	file, err := os.Open(unplacedTestBAM + ".bai")
+	assert.Nil(t, err)
+	defer file.Close()
+	index, err := bam.ReadIndex(file)
+	assert.Nil(t, err)
+	numUnplaced, ok := index.Unmapped()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), numUnplaced)
+
+	// samtools ends the placed reads past the EOF block that followed them,
+	// at the start of the unplaced reads
+	offset, placed := UnplacedUnmappedOffset(index)
+	assert.True(t, placed)
+	info, err := os.Stat(splitTestBAM)
+	assert.Nil(t, err)
+	assert.Equal(t, bgzf.Offset{File: info.Size(), Block: 0}, offset)
+
+	expNames := []string{}
+	for i := 0; i < int(numUnplaced); i++ {
+		expNames = append(expNames, "unplaced"+strconv.Itoa(i))
+	}
+	records, err := UnplacedUnmappedRecords(context.Background(), unplacedTestBAM)
+	assert.Nil(t, err)
+	assert.Equal(t, expNames, readRecordNames(t, records))
+	assert.Nil(t, records.Close())
+}
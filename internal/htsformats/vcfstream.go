@@ -0,0 +1,373 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module vcfstream.go masks the INFO and FORMAT fields of all lines in a
+// stream of VCF lines, and updates the INFO fields of the samples subset by
+// bcftools, one line at a time, so that memory use does not depend on the
+// number of records
+package htsformats
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// vcf columns preceding the INFO, FORMAT and sample columns
+const (
+	vcfColumnInfo    = 7
+	vcfColumnFormat  = 8
+	vcfColumnSamples = 9
+)
+
+// INFO keys of the allele counts and frequencies over the samples of a record
+const (
+	vcfInfoAC = "AC"
+	vcfInfoAN = "AN"
+	vcfInfoAF = "AF"
+)
+
+// vcf field kinds, prefixing the INFO and FORMAT keys of tags/notags
+const (
+	vcfKindInfo   = "INFO"
+	vcfKindFormat = "FORMAT"
+)
+
+// vcfCohortInfoKeys INFO keys of values computed over all the samples of a
+// record, other than the allele counts and frequencies, as written by
+// bcftools +fill-tags. they are removed when the samples are subset
+var vcfCohortInfoKeys = map[string]bool{
+	"NS":        true,
+	"AC_Het":    true,
+	"AC_Hom":    true,
+	"AC_Hemi":   true,
+	"MAF":       true,
+	"HWE":       true,
+	"ExcHet":    true,
+	"F_MISSING": true,
+}
+
+// vcfMissing value of a missing VCF field
+const vcfMissing = "."
+
+// vcfDefinitionRegex matches an INFO or FORMAT definition header line,
+// capturing its kind and key
+var vcfDefinitionRegex = regexp.MustCompile("^##(INFO|FORMAT)=<ID=([^,>]+)")
+
+// vcfSampleRegex matches a sample metadata header line, capturing the sample
+var vcfSampleRegex = regexp.MustCompile("^##SAMPLE=<ID=([^,>]+)")
+
+// VCFMask includes only the requested INFO and FORMAT fields and samples of
+// VCF lines. tags and notags name the INFO and FORMAT keys to include and
+// exclude, either qualified by their kind (INFO/DP, FORMAT/GT), or unqualified
+// (DP), naming the key of both kinds. the sample columns are subset by
+// bcftools, which recomputes INFO/AC and INFO/AN over the samples kept; the
+// mask recomputes INFO/AF from them
+//
+// Attributes
+//	tags ([]string): requested keys, nil if all keys are requested
+//	notags ([]string): excluded keys
+//	samples (map[string]bool): names of the samples kept, nil if all samples are kept
+//	sampleNames ([]string): samples kept, in the order of their columns, nil if all samples are kept
+type VCFMask struct {
+	tags        []string
+	notags      []string
+	samples     map[string]bool
+	sampleNames []string
+}
+
+// NewVCFMask instantiates a mask for the tags, notags and samples of a
+// request, locating the requested samples in the header of the object. if the
+// client may only get some samples, the others are removed, and requesting
+// them fails as if they were not in the object, so that their presence is not
+// disclosed
+//
+// Arguments
+//	htsgetReq (*htsrequest.HtsgetRequest): request holding tags, notags and samples
+//	header (*htscache.Header): parsed header of the variants object
+//	allowedSamples ([]string): samples the client may get, nil if it may get all samples
+// Returns
+//	(*VCFMask): mask for the lines of the object
+//	(error): if not nil, a requested sample is not in the object, or not allowed (invalid input)
+func NewVCFMask(htsgetReq *htsrequest.HtsgetRequest, header *htscache.Header, allowedSamples []string) (*VCFMask, error) {
+	mask := new(VCFMask)
+	if !htsgetReq.TagsNotSpecified() {
+		mask.tags = htsgetReq.Tags()
+	}
+	if !htsgetReq.NoTagsNotSpecified() {
+		mask.notags = htsgetReq.NoTags()
+	}
+
+	available := make(map[string]bool)
+	for _, sample := range header.Samples {
+		if allowedSamples == nil || htsutils.IsItemInArray(sample, allowedSamples) {
+			available[sample] = true
+		}
+	}
+	if !htsgetReq.AllSamplesRequested() {
+		mask.samples = make(map[string]bool)
+		mask.sampleNames = []string{}
+		for _, sample := range htsgetReq.Samples() {
+			if !available[sample] {
+				return nil, htserror.NewInvalidInput("sample "+sample+" is not in the object", nil)
+			}
+			mask.samples[sample] = true
+			mask.sampleNames = append(mask.sampleNames, sample)
+		}
+	} else if allowedSamples != nil {
+		mask.samples = make(map[string]bool)
+		mask.sampleNames = []string{}
+		for _, sample := range header.Samples {
+			if available[sample] {
+				mask.samples[sample] = true
+				mask.sampleNames = append(mask.sampleNames, sample)
+			}
+		}
+	}
+	return mask, nil
+}
+
+// Samples gets the samples kept by the mask, for bcftools to subset the
+// sample columns to
+//
+//	Type: VCFMask
+// Returns
+//	([]string): samples kept, in the order of their columns, nil if all samples are kept
+func (mask *VCFMask) Samples() []string {
+	return mask.sampleNames
+}
+
+// vcfTagsMatch checks whether a list of tags names an INFO or FORMAT key
+func vcfTagsMatch(tags []string, kind string, key string) bool {
+	return htsutils.IsItemInArray(key, tags) || htsutils.IsItemInArray(kind+"/"+key, tags)
+}
+
+// included checks whether an INFO or FORMAT key is requested
+//
+//	Type: VCFMask
+// Arguments
+//	kind (string): INFO or FORMAT
+//	key (string): field key
+// Returns
+//	(bool): true if the field is included in the output
+func (mask *VCFMask) included(kind string, key string) bool {
+	if mask.tags != nil && !vcfTagsMatch(mask.tags, kind, key) {
+		return false
+	}
+	return !vcfTagsMatch(mask.notags, kind, key)
+}
+
+// MaskLine masks a single VCF line, whose sample columns bcftools subset.
+// INFO and FORMAT definitions of excluded keys, and metadata of removed
+// samples, are removed from the header. in records, INFO/AF is recomputed for
+// the samples kept, and excluded INFO and FORMAT fields are removed. a field
+// left empty is written as missing (".")
+//
+//	Type: VCFMask
+// Arguments
+//	line (string): VCF header line or record, without line terminator
+// Returns
+//	(string): masked line, empty if the line is removed
+func (mask *VCFMask) MaskLine(line string) string {
+	if strings.HasPrefix(line, "##") {
+		submatches := vcfDefinitionRegex.FindStringSubmatch(line)
+		if len(submatches) > 2 && !mask.included(submatches[1], submatches[2]) {
+			return ""
+		}
+		submatches = vcfSampleRegex.FindStringSubmatch(line)
+		if len(submatches) > 1 && mask.samples != nil && !mask.samples[submatches[1]] {
+			return ""
+		}
+		return line
+	}
+
+	if strings.HasPrefix(line, "#") {
+		return line
+	}
+	columns := strings.Split(line, "\t")
+	if len(columns) > vcfColumnInfo {
+		columns[vcfColumnInfo] = mask.maskInfo(mask.subsetInfo(columns[vcfColumnInfo]))
+	}
+	if len(columns) > vcfColumnFormat {
+		mask.maskFormat(columns)
+	}
+	return strings.Join(columns, "\t")
+}
+
+// subsetInfo updates the INFO column of a record for the samples kept.
+// bcftools recomputed the allele counts (AC, AN) for them, from which the
+// allele frequencies (AF) are recomputed, or removed if they cannot be. if no
+// samples are kept, bcftools dropped the genotypes without recomputing, and
+// the counts and frequencies are removed. other fields computed over all the
+// samples of the object, which bcftools does not recompute, are removed
+func (mask *VCFMask) subsetInfo(info string) string {
+	if mask.sampleNames == nil || info == vcfMissing {
+		return info
+	}
+	fields := strings.Split(info, ";")
+	values := make(map[string]string)
+	for _, field := range fields {
+		if i := strings.Index(field, "="); i >= 0 {
+			values[field[:i]] = field[i+1:]
+		}
+	}
+	af, afOK := alleleFrequencies(values[vcfInfoAC], values[vcfInfoAN])
+	kept := make([]string, 0, len(fields))
+	for _, field := range fields {
+		key := field
+		if i := strings.Index(field, "="); i >= 0 {
+			key = field[:i]
+		}
+		switch {
+		case vcfCohortInfoKeys[key]:
+		case key == vcfInfoAC || key == vcfInfoAN:
+			if len(mask.sampleNames) > 0 {
+				kept = append(kept, field)
+			}
+		case key == vcfInfoAF:
+			if len(mask.sampleNames) > 0 && afOK {
+				kept = append(kept, vcfInfoAF+"="+af)
+			}
+		default:
+			kept = append(kept, field)
+		}
+	}
+	if len(kept) == 0 {
+		return vcfMissing
+	}
+	return strings.Join(kept, ";")
+}
+
+// alleleFrequencies computes the INFO/AF value of a record, the frequency of
+// each alternate allele, from its INFO/AC and INFO/AN values
+//
+// Arguments
+//	ac (string): count of each alternate allele, comma separated
+//	an (string): total number of alleles
+// Returns
+//	(string): frequency of each alternate allele, comma separated, missing if there are no alleles
+//	(bool): false if the counts are missing or invalid
+func alleleFrequencies(ac string, an string) (string, bool) {
+	total, err := strconv.ParseInt(an, 10, 64)
+	if err != nil || total < 0 || ac == "" {
+		return "", false
+	}
+	frequencies := []string{}
+	for _, count := range strings.Split(ac, ",") {
+		n, err := strconv.ParseInt(count, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		if total == 0 {
+			frequencies = append(frequencies, vcfMissing)
+		} else {
+			frequencies = append(frequencies, strconv.FormatFloat(float64(n)/float64(total), 'g', 6, 64))
+		}
+	}
+	return strings.Join(frequencies, ","), true
+}
+
+// maskInfo removes the excluded fields of an INFO column (KEY=value;FLAG)
+func (mask *VCFMask) maskInfo(info string) string {
+	if info == vcfMissing {
+		return info
+	}
+	kept := []string{}
+	for _, field := range strings.Split(info, ";") {
+		key := field
+		if i := strings.Index(field, "="); i >= 0 {
+			key = field[:i]
+		}
+		if mask.included(vcfKindInfo, key) {
+			kept = append(kept, field)
+		}
+	}
+	if len(kept) == 0 {
+		return vcfMissing
+	}
+	return strings.Join(kept, ";")
+}
+
+// maskFormat removes the excluded keys of the FORMAT column, and their values
+// from each sample column, in place
+func (mask *VCFMask) maskFormat(columns []string) {
+	if columns[vcfColumnFormat] == vcfMissing {
+		return
+	}
+	keys := strings.Split(columns[vcfColumnFormat], ":")
+	keptIndices := []int{}
+	keptKeys := []string{}
+	for i, key := range keys {
+		if mask.included(vcfKindFormat, key) {
+			keptIndices = append(keptIndices, i)
+			keptKeys = append(keptKeys, key)
+		}
+	}
+	if len(keptIndices) == len(keys) {
+		return
+	}
+	if len(keptKeys) == 0 {
+		columns[vcfColumnFormat] = vcfMissing
+	} else {
+		columns[vcfColumnFormat] = strings.Join(keptKeys, ":")
+	}
+
+	for c := vcfColumnSamples; c < len(columns); c++ {
+		values := strings.Split(columns[c], ":")
+		kept := make([]string, 0, len(keptIndices))
+		for _, i := range keptIndices {
+			if i < len(values) {
+				kept = append(kept, values[i])
+			} else {
+				kept = append(kept, vcfMissing)
+			}
+		}
+		if len(kept) == 0 {
+			columns[c] = vcfMissing
+		} else {
+			columns[c] = strings.Join(kept, ":")
+		}
+	}
+}
+
+// MaskVCFStream reads VCF lines, and writes them masked, one line at a time
+//
+//	Type: VCFMask
+// Arguments
+//	reader (io.Reader): VCF lines, optionally preceded by the header
+//	writer (io.Writer): receives the masked VCF lines
+// Returns
+//	(error): if not nil, reading or writing the stream failed
+func (mask *VCFMask) MaskVCFStream(reader io.Reader, writer io.Writer) error {
+	lineReader := bufio.NewReader(reader)
+	lineWriter := bufio.NewWriter(writer)
+	for {
+		line, readErr := lineReader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) > 0 {
+			masked := mask.MaskLine(string(line))
+			if len(masked) > 0 {
+				if _, err := lineWriter.WriteString(masked); err != nil {
+					return err
+				}
+				if err := lineWriter.WriteByte('\n'); err != nil {
+					return err
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	return lineWriter.Flush()
+}
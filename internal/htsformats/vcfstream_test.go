@@ -0,0 +1,321 @@
+// Package htsformats manipulates bioinformatic data encountered by htsget
+//
+// Module vcfstream_test tests vcfstream
+package htsformats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/stretchr/testify/assert"
+)
+
+// vcfTestHeader header of the test VCF lines, declaring three samples
+var vcfTestHeader = &htscache.Header{Samples: []string{"S1", "S2", "S3"}}
+
+// vcfTestLines header lines and a record of a VCF object with three samples
+var vcfTestLines = []string{
+	"##fileformat=VCFv4.2",
+	"##INFO=<ID=DP,Number=1,Type=Integer,Description=\"Total depth\">",
+	"##INFO=<ID=AC,Number=A,Type=Integer,Description=\"Allele count\">",
+	"##INFO=<ID=AN,Number=1,Type=Integer,Description=\"Total number of alleles\">",
+	"##INFO=<ID=AF,Number=A,Type=Float,Description=\"Allele frequency\">",
+	"##INFO=<ID=DB,Number=0,Type=Flag,Description=\"dbSNP membership\">",
+	"##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">",
+	"##FORMAT=<ID=DP,Number=1,Type=Integer,Description=\"Read depth\">",
+	"##FORMAT=<ID=GQ,Number=1,Type=Integer,Description=\"Genotype quality\">",
+	"##SAMPLE=<ID=S1,Tissue=Liver>",
+	"##SAMPLE=<ID=S3,Tissue=Blood>",
+	"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS2\tS3",
+	"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=4;AN=6;AF=0.667;DB\tGT:DP:GQ\t0|1:1:48\t1|0:8:48\t1/1:5",
+}
+
+// vcfTestSubset gets the lines of the test object as bcftools writes them for
+// a subset of the samples: the header unchanged, but for the sample columns
+// of the #CHROM line, and a record with the sample columns subset, and
+// INFO/AC and INFO/AN recomputed
+func vcfTestSubset(chrom string, record string) []string {
+	return append(append([]string{}, vcfTestLines[:11]...), chrom, record)
+}
+
+// newVCFTestRequest creates a variants request for tags, notags and samples,
+// nil if not specified
+func newVCFTestRequest(tags []string, notags []string, samples []string) *htsrequest.HtsgetRequest {
+	htsgetReq := htsrequest.NewHtsgetRequest()
+	htsgetReq.AddListParam("tags", []string{"ALL"})
+	htsgetReq.AddListParam("notags", []string{"NONE"})
+	htsgetReq.AddListParam("samples", []string{"ALL"})
+	if tags != nil {
+		htsgetReq.AddListParam("tags", tags)
+	}
+	if notags != nil {
+		htsgetReq.AddListParam("notags", notags)
+	}
+	if samples != nil {
+		htsgetReq.AddListParam("samples", samples)
+	}
+	return htsgetReq
+}
+
+var vcfMaskLineTC = []struct {
+	name     string
+	tags     []string
+	notags   []string
+	samples  []string
+	input    []string
+	expLines []string
+}{
+	{
+		"all tags and samples",
+		nil, nil, nil,
+		vcfTestLines,
+		vcfTestLines,
+	},
+	{
+		"genotypes of a subset of samples",
+		[]string{"FORMAT/GT"}, nil, []string{"S3", "S1"},
+		vcfTestSubset(
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS3\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=3;AN=4;AF=0.667;DB\tGT:DP:GQ\t1/1:5\t0|1:1:48",
+		),
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">",
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS3\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\t.\tGT\t1/1\t0|1",
+		},
+	},
+	{
+		"allele frequencies of a subset of samples",
+		[]string{"INFO/AC", "INFO/AN", "INFO/AF"}, nil, []string{"S3", "S1"},
+		vcfTestSubset(
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS3\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=3;AN=4;AF=0.667;DB\tGT:DP:GQ\t1/1:5\t0|1:1:48",
+		),
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##INFO=<ID=AC,Number=A,Type=Integer,Description=\"Allele count\">",
+			"##INFO=<ID=AN,Number=1,Type=Integer,Description=\"Total number of alleles\">",
+			"##INFO=<ID=AF,Number=A,Type=Float,Description=\"Allele frequency\">",
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS3\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tAC=3;AN=4;AF=0.75\t.\t.\t.",
+		},
+	},
+	{
+		"unqualified key of both kinds",
+		[]string{"DP"}, nil, nil,
+		vcfTestLines,
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##INFO=<ID=DP,Number=1,Type=Integer,Description=\"Total depth\">",
+			"##FORMAT=<ID=DP,Number=1,Type=Integer,Description=\"Read depth\">",
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS2\tS3",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14\tDP\t1\t8\t5",
+		},
+	},
+	{
+		"excluded keys",
+		nil, []string{"INFO/DP", "DB", "GQ"}, []string{"S2"},
+		vcfTestSubset(
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=1;AN=2;AF=0.667;DB\tGT:DP:GQ\t1|0:8:48",
+		),
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##INFO=<ID=AC,Number=A,Type=Integer,Description=\"Allele count\">",
+			"##INFO=<ID=AN,Number=1,Type=Integer,Description=\"Total number of alleles\">",
+			"##INFO=<ID=AF,Number=A,Type=Float,Description=\"Allele frequency\">",
+			"##FORMAT=<ID=GT,Number=1,Type=String,Description=\"Genotype\">",
+			"##FORMAT=<ID=DP,Number=1,Type=Integer,Description=\"Read depth\">",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tAC=1;AN=2;AF=0.5\tGT:DP\t1|0:8",
+		},
+	},
+	{
+		"only INFO keys, sample values missing trailing keys",
+		[]string{"INFO/AF", "FORMAT/GQ"}, nil, nil,
+		vcfTestLines,
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##INFO=<ID=AF,Number=A,Type=Float,Description=\"Allele frequency\">",
+			"##FORMAT=<ID=GQ,Number=1,Type=Integer,Description=\"Genotype quality\">",
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1\tS2\tS3",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tAF=0.667\tGQ\t48\t48\t.",
+		},
+	},
+	{
+		"no FORMAT keys",
+		[]string{"INFO/AF"}, nil, []string{"S1"},
+		vcfTestSubset(
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=1;AN=2;AF=0.667;DB\tGT:DP:GQ\t0|1:1:48",
+		),
+		[]string{
+			"##fileformat=VCFv4.2",
+			"##INFO=<ID=AF,Number=A,Type=Float,Description=\"Allele frequency\">",
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tAF=0.5\t.\t.",
+		},
+	},
+}
+
+func TestVCFMaskLine(t *testing.T) {
+	for _, tc := range vcfMaskLineTC {
+		mask, err := NewVCFMask(newVCFTestRequest(tc.tags, tc.notags, tc.samples), vcfTestHeader, nil)
+		assert.Nil(t, err, tc.name)
+		lines := []string{}
+		for _, line := range tc.input {
+			if masked := mask.MaskLine(line); masked != "" {
+				lines = append(lines, masked)
+			}
+		}
+		assert.Equal(t, tc.expLines, lines, tc.name)
+	}
+}
+
+var vcfMaskAllowedSamplesTC = []struct {
+	name           string
+	samples        []string
+	allowedSamples []string
+	expSamples     []string
+	input          []string
+	expLines       []string
+}{
+	{
+		"all allowed samples",
+		nil, []string{"S3", "S2"}, []string{"S2", "S3"},
+		[]string{
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2\tS3",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=3;AN=4;AF=0.667;DB\tGT:DP:GQ\t1|0:8:48\t1/1:5",
+		},
+		[]string{
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2\tS3",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=3;AN=4;AF=0.75;DB\tGT:DP:GQ\t1|0:8:48\t1/1:5",
+		},
+	},
+	{
+		"requested allowed sample",
+		[]string{"S2"}, []string{"S2", "S3"}, []string{"S2"},
+		[]string{
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=1;AN=2;AF=0.667;DB\tGT:DP:GQ\t1|0:8:48",
+		},
+		[]string{
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS2",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=1;AN=2;AF=0.5;DB\tGT:DP:GQ\t1|0:8:48",
+		},
+	},
+	{
+		"no allowed samples",
+		nil, []string{}, []string{},
+		[]string{
+			"##SAMPLE=<ID=S1,Tissue=Liver>",
+			"##SAMPLE=<ID=S3,Tissue=Blood>",
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=4;AN=6;AF=0.667;DB",
+		},
+		[]string{
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;DB",
+		},
+	},
+	{
+		"counts over all samples",
+		nil, []string{"S1"}, []string{"S1"},
+		[]string{
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tNS=3;AC=1;AN=2;AF=0.667;AC_Het=2;MAF=0.333\tGT\t0|1",
+		},
+		[]string{
+			"#CHROM\tPOS\tID\tREF\tALT\tQUAL\tFILTER\tINFO\tFORMAT\tS1",
+			"20\t14370\trs6054257\tG\tA\t29\tPASS\tAC=1;AN=2;AF=0.5\tGT\t0|1",
+		},
+	},
+}
+
+func TestVCFMaskAllowedSamples(t *testing.T) {
+	for _, tc := range vcfMaskAllowedSamplesTC {
+		mask, err := NewVCFMask(newVCFTestRequest(nil, nil, tc.samples), vcfTestHeader, tc.allowedSamples)
+		assert.Nil(t, err, tc.name)
+		// bcftools subsets the sample columns to the samples of the mask
+		assert.Equal(t, tc.expSamples, mask.Samples(), tc.name)
+		lines := []string{}
+		for _, line := range tc.input {
+			if masked := mask.MaskLine(line); masked != "" {
+				lines = append(lines, masked)
+			}
+		}
+		assert.Equal(t, tc.expLines, lines, tc.name)
+	}
+}
+
+var alleleFrequenciesTC = []struct {
+	ac, an, exp string
+	expOK       bool
+}{
+	{"1", "2", "0.5", true},
+	{"1,2", "3", "0.333333,0.666667", true},
+	{"0", "0", ".", true},
+	{"", "2", "", false},
+	{"1", ".", "", false},
+	{"x", "2", "", false},
+}
+
+func TestAlleleFrequencies(t *testing.T) {
+	for _, tc := range alleleFrequenciesTC {
+		af, ok := alleleFrequencies(tc.ac, tc.an)
+		assert.Equal(t, tc.expOK, ok, tc.ac+"/"+tc.an)
+		assert.Equal(t, tc.exp, af, tc.ac+"/"+tc.an)
+	}
+}
+
+var newVCFMaskUnknownSampleTC = []struct {
+	samples        []string
+	allowedSamples []string
+	expMsg         string
+}{
+	{[]string{"S1", "S4"}, nil, "sample S4 is not in the object"},
+	// samples the client may not get are reported as absent
+	{[]string{"S1", "S2"}, []string{"S1"}, "sample S2 is not in the object"},
+	{[]string{"S1"}, []string{}, "sample S1 is not in the object"},
+}
+
+func TestNewVCFMaskUnknownSample(t *testing.T) {
+	for _, tc := range newVCFMaskUnknownSampleTC {
+		_, err := NewVCFMask(newVCFTestRequest(nil, nil, tc.samples), vcfTestHeader, tc.allowedSamples)
+		assert.NotNil(t, err, tc.expMsg)
+		assert.Equal(t, htserror.KindInvalidInput, htserror.KindOf(err), tc.expMsg)
+		assert.Equal(t, tc.expMsg, err.Error())
+	}
+}
+
+func TestMaskVCFStream(t *testing.T) {
+	mask, err := NewVCFMask(newVCFTestRequest([]string{"GT"}, nil, []string{"S2"}), vcfTestHeader, nil)
+	assert.Nil(t, err)
+
+	// body blocks stream records without the header
+	record := "20\t14370\trs6054257\tG\tA\t29\tPASS\tDP=14;AC=1;AN=2;AF=0.667;DB\tGT:DP:GQ\t1|0:8:48"
+	input := record + "\n" + record + "\n"
+	output := new(bytes.Buffer)
+	assert.Nil(t, mask.MaskVCFStream(strings.NewReader(input), output))
+	expLine := "20\t14370\trs6054257\tG\tA\t29\tPASS\t.\tGT\t1|0\n"
+	assert.Equal(t, expLine+expLine, output.String())
+}
@@ -0,0 +1,317 @@
+// Package htshealth checks whether the service and its dependencies are in a
+// state to serve htsget requests
+//
+// Module checks contains the individual health checks
+package htshealth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// checkTimeout maximum time spent on a single tool or data source check
+var checkTimeout = 2 * time.Second
+
+// tool an external tool the service depends on
+//
+// Attributes
+//	name (string): tool executable name
+//	minVersion ([]int): minimum acceptable version, as dot-separated components
+//	endpoint (htsconstants.APIEndpoint): ticket endpoint requiring the tool
+type tool struct {
+	name       string
+	minVersion []int
+	endpoint   htsconstants.APIEndpoint
+}
+
+// tools all external tools the service may depend on. versions are the
+// earliest the service has been tested against
+var tools = []*tool{
+	&tool{"samtools", []int{1, 9}, htsconstants.APIEndpointReadsTicket},
+	&tool{"bcftools", []int{1, 10}, htsconstants.APIEndpointVariantsTicket},
+}
+
+// namedDataSource a data source, labelled with the endpoint it serves
+type namedDataSource struct {
+	target     string
+	dataSource *htsconfig.DataSource
+}
+
+// dataSourceEndpoints ticket endpoints and their names in check targets
+var dataSourceEndpoints = []struct {
+	name     string
+	endpoint htsconstants.APIEndpoint
+}{
+	{"reads", htsconstants.APIEndpointReadsTicket},
+	{"variants", htsconstants.APIEndpointVariantsTicket},
+}
+
+// pathParameterRegex matches the {parameter} placeholders of a path template
+var pathParameterRegex = regexp.MustCompile(`\{(.+?)\}`)
+
+// tempdir gets the configured tempdir
+func tempdir() string {
+	return htsconfig.GetTempdir()
+}
+
+// requiredTools gets the tools needed by the enabled endpoints
+func requiredTools() []*tool {
+	required := []*tool{}
+	for _, t := range tools {
+		if htsconfig.IsEndpointEnabled(t.endpoint) {
+			required = append(required, t)
+		}
+	}
+	return required
+}
+
+// enabledDataSources gets the data sources of all enabled endpoints
+func enabledDataSources() []*namedDataSource {
+	sources := []*namedDataSource{}
+	for _, ep := range dataSourceEndpoints {
+		if !htsconfig.IsEndpointEnabled(ep.endpoint) {
+			continue
+		}
+		registry := htsconfig.GetDataSourceRegistry(ep.endpoint)
+		if registry == nil {
+			continue
+		}
+		for _, dataSource := range registry.Sources {
+			sources = append(sources, &namedDataSource{
+				target:     ep.name + " " + dataSource.Pattern,
+				dataSource: dataSource,
+			})
+		}
+	}
+	return sources
+}
+
+// checkConfig checks the configuration loaded without error
+//
+// Returns
+//	(error): the configuration load error, if any
+func checkConfig() error {
+	return htsconfig.GetConfigLoadError()
+}
+
+// checkTempdir checks that temporary files can be created, written and
+// removed in a directory
+//
+// Arguments
+//	dir (string): directory to check
+// Returns
+//	(error): if not nil, the directory is not writable
+func checkTempdir(dir string) error {
+	file, err := ioutil.TempFile(dir, ".htsget-healthcheck-")
+	if err != nil {
+		return err
+	}
+	_, writeErr := file.Write([]byte("ok"))
+	closeErr := file.Close()
+	removeErr := os.Remove(file.Name())
+	for _, err := range []error{writeErr, closeErr, removeErr} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTool checks that a tool is on the PATH, and reports a version no
+// earlier than its minimum version
+//
+// Arguments
+//	ctx (context.Context): bounds the time spent running the tool
+//	t (*tool): tool to check
+// Returns
+//	(string): the tool version line
+//	(error): if not nil, the tool is missing or its version is unacceptable
+func checkTool(ctx context.Context, t *tool) (string, error) {
+	path, err := exec.LookPath(t.name)
+	if err != nil {
+		return "", errors.New(t.name + " not found on PATH")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not get %s version: %v", t.name, err)
+	}
+
+	versionLine, _ := bufio.NewReader(bytes.NewReader(output)).ReadString('\n')
+	versionLine = strings.TrimSpace(versionLine)
+	fields := strings.Fields(versionLine)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("could not parse %s version from '%s'", t.name, versionLine)
+	}
+	version := parseVersion(fields[1])
+	if compareVersions(version, t.minVersion) < 0 {
+		return "", fmt.Errorf("%s is older than the minimum version %s", versionLine, formatVersion(t.minVersion))
+	}
+	return versionLine, nil
+}
+
+// parseVersion parses a dot-separated version string into its numeric
+// components, ignoring any non-numeric suffix (e.g. "1.9-166-g74718c2")
+//
+// Arguments
+//	version (string): version string
+// Returns
+//	([]int): numeric version components
+func parseVersion(version string) []int {
+	components := []int{}
+	for _, part := range strings.Split(version, ".") {
+		digits := 0
+		for digits < len(part) && part[digits] >= '0' && part[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 {
+			break
+		}
+		number, _ := strconv.Atoi(part[:digits])
+		components = append(components, number)
+		if digits < len(part) {
+			break
+		}
+	}
+	return components
+}
+
+// compareVersions compares two versions component by component, missing
+// components count as zero
+//
+// Returns
+//	(int): negative if a < b, zero if a == b, positive if a > b
+func compareVersions(a []int, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ai, bi int
+		if i < len(a) {
+			ai = a[i]
+		}
+		if i < len(b) {
+			bi = b[i]
+		}
+		if ai != bi {
+			return ai - bi
+		}
+	}
+	return 0
+}
+
+// formatVersion formats numeric version components as a dot-separated string
+func formatVersion(version []int) string {
+	parts := make([]string, len(version))
+	for i, component := range version {
+		parts[i] = strconv.Itoa(component)
+	}
+	return strings.Join(parts, ".")
+}
+
+// checkWarmup checks that the startup cache warm-up has completed. ids that
+// failed to warm do not fail the check, they are reported in the message
+//
+// Arguments
+//	done (bool): true if the warm-up has completed
+//	report (*htscache.WarmReport): outcome of the completed warm-up
+// Returns
+//	(string): number of ids warmed
+//	(error): if not nil, the warm-up is still in progress
+func checkWarmup(done bool, report *htscache.WarmReport) (string, error) {
+	if !done {
+		return "", errors.New("cache warm-up in progress")
+	}
+	nIDs := len(report.Results)
+	return fmt.Sprintf("warmed %d of %d ids", nIDs-report.NumFailed(), nIDs), nil
+}
+
+// checkDataSource checks that a data source can resolve ids to object
+// locations: its pattern compiles, every path template parameter is a named
+// group of the pattern, and the fixed part of the location exists (the
+// directory of a file path source, or the host of a url source)
+//
+// Arguments
+//	ctx (context.Context): bounds the time spent resolving url hosts
+//	dataSource (*htsconfig.DataSource): data source to check
+// Returns
+//	(error): if not nil, the data source cannot be resolved
+func checkDataSource(ctx context.Context, dataSource *htsconfig.DataSource) error {
+	pattern, err := regexp.Compile(dataSource.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+	groups := map[string]bool{}
+	for _, name := range pattern.SubexpNames() {
+		groups[name] = true
+	}
+	for _, match := range pathParameterRegex.FindAllStringSubmatch(dataSource.Path, -1) {
+		if !groups[match[1]] {
+			return fmt.Errorf("path parameter {%s} is not a named group of the pattern", match[1])
+		}
+	}
+
+	// placeholders are filled in so that templated hosts still parse as urls
+	filledPath := pathParameterRegex.ReplaceAllString(dataSource.Path, "x")
+	if htsutils.IsValidURL(filledPath) {
+		return checkURLHost(ctx, dataSource.Path, filledPath)
+	}
+	return checkPathDirectory(dataSource.Path)
+}
+
+// checkURLHost checks that the host of a url path template resolves
+func checkURLHost(ctx context.Context, path string, filledPath string) error {
+	authority := strings.SplitN(strings.SplitN(path, "://", 2)[1], "/", 2)[0]
+	if strings.Contains(authority, "{") {
+		// the host depends on the requested id, it cannot be checked ahead of time
+		return nil
+	}
+	u, err := url.Parse(filledPath)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	_, err = net.DefaultResolver.LookupHost(ctx, host)
+	return err
+}
+
+// checkPathDirectory checks that the directory holding the objects of a file
+// path template (the fixed part of the template, before any parameter)
+// exists
+func checkPathDirectory(path string) error {
+	fixed := path
+	if i := strings.Index(fixed, "{"); i >= 0 {
+		fixed = fixed[:i]
+	}
+	dir := filepath.Dir(fixed)
+	if strings.HasSuffix(fixed, string(filepath.Separator)) {
+		dir = filepath.Clean(fixed)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return errors.New(dir + " is not a directory")
+	}
+	return nil
+}
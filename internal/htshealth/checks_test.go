@@ -0,0 +1,149 @@
+// Package htshealth checks whether the service and its dependencies are in a
+// state to serve htsget requests
+//
+// Module checks_test tests module checks
+package htshealth
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/stretchr/testify/assert"
+)
+
+var parseVersionTC = []struct {
+	version string
+	exp     []int
+}{
+	{"1.9", []int{1, 9}},
+	{"1.10.2", []int{1, 10, 2}},
+	{"1.9-166-g74718c2", []int{1, 9}},
+	{"1.15.1+htslib-1.15.1", []int{1, 15, 1}},
+	{"unknown", []int{}},
+}
+
+var compareVersionsTC = []struct {
+	a, b []int
+	exp  int
+}{
+	{[]int{1, 9}, []int{1, 9}, 0},
+	{[]int{1, 10}, []int{1, 9}, 1},
+	{[]int{1, 8}, []int{1, 9}, -1},
+	{[]int{1, 10, 2}, []int{1, 10}, 1},
+	{[]int{1, 10}, []int{1, 10, 0}, 0},
+	{[]int{}, []int{1, 9}, -1},
+}
+
+var checkToolTC = []struct {
+	versionOutput string
+	expVersion    string
+	expError      bool
+}{
+	{"samtools 1.9\nUsing htslib 1.9\n", "samtools 1.9", false},
+	{"samtools 1.17\nUsing htslib 1.17\n", "samtools 1.17", false},
+	{"samtools 1.8\nUsing htslib 1.8\n", "", true},
+	{"garbage\n", "", true},
+}
+
+var checkDataSourceTC = []struct {
+	dataSource *htsconfig.DataSource
+	expError   bool
+}{
+	{&htsconfig.DataSource{Pattern: "^tabulamuris\\.(?P<accession>.*)$", Path: "../../data/test/sources/tabulamuris/{accession}.bam"}, false},
+	{&htsconfig.DataSource{Pattern: "^(?P<accession>.*)$", Path: "../../data/test/sources/{accession}"}, false},
+	{&htsconfig.DataSource{Pattern: "^tabulamuris\\.(?P<accession>.*)$", Path: "../../data/test/sources/nonexistent/{accession}.bam"}, true},
+	{&htsconfig.DataSource{Pattern: "^tabulamuris\\.(?P<accession>.*$", Path: "../../data/test/sources/tabulamuris/{accession}.bam"}, true},
+	{&htsconfig.DataSource{Pattern: "^tabulamuris\\.(?P<accession>.*)$", Path: "../../data/test/sources/tabulamuris/{sample}.bam"}, true},
+	{&htsconfig.DataSource{Pattern: "^(?P<bucket>[a-z]+)\\.(?P<key>.*)$", Path: "https://{bucket}.s3.amazonaws.com/{key}.bam"}, false},
+	{&htsconfig.DataSource{Pattern: "^(?P<key>.*)$", Path: "http://localhost/{key}.bam"}, false},
+}
+
+func TestParseVersion(t *testing.T) {
+	for _, tc := range parseVersionTC {
+		assert.Equal(t, tc.exp, parseVersion(tc.version))
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range compareVersionsTC {
+		result := compareVersions(tc.a, tc.b)
+		switch {
+		case tc.exp < 0:
+			assert.True(t, result < 0)
+		case tc.exp > 0:
+			assert.True(t, result > 0)
+		default:
+			assert.Equal(t, 0, result)
+		}
+	}
+}
+
+func TestCheckTempdir(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htshealth")
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, checkTempdir(dir))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 0, len(files))
+	assert.NotNil(t, checkTempdir(filepath.Join(dir, "nonexistent")))
+}
+
+func TestCheckTool(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htshealth")
+	defer os.RemoveAll(dir)
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", dir)
+
+	samtools := &tool{"samtools", []int{1, 9}, htsconstants.APIEndpointReadsTicket}
+	_, err := checkTool(context.Background(), samtools)
+	assert.EqualError(t, err, "samtools not found on PATH")
+
+	for _, tc := range checkToolTC {
+		script := "#!/bin/sh\nprintf '" + tc.versionOutput + "'\n"
+		ioutil.WriteFile(filepath.Join(dir, "samtools"), []byte(script), 0755)
+		version, err := checkTool(context.Background(), samtools)
+		assert.Equal(t, tc.expVersion, version)
+		assert.Equal(t, tc.expError, err != nil)
+	}
+}
+
+func TestCheckDataSource(t *testing.T) {
+	for _, tc := range checkDataSourceTC {
+		err := checkDataSource(context.Background(), tc.dataSource)
+		assert.Equal(t, tc.expError, err != nil, tc.dataSource.Path)
+	}
+}
+
+func TestCheckWarmup(t *testing.T) {
+	_, err := checkWarmup(false, nil)
+	assert.EqualError(t, err, "cache warm-up in progress")
+
+	report := &htscache.WarmReport{Results: []*htscache.WarmResult{
+		&htscache.WarmResult{},
+		&htscache.WarmResult{Err: errors.New("not found")},
+	}}
+	message, err := checkWarmup(true, report)
+	assert.Nil(t, err)
+	assert.Equal(t, "warmed 1 of 2 ids", message)
+}
+
+func TestReport(t *testing.T) {
+	report := newReport()
+	assert.True(t, report.OK())
+	report.add("tempdir", "/tmp/", "", nil)
+	assert.True(t, report.OK())
+	report.add("samtools", "", "", os.ErrNotExist)
+	assert.False(t, report.OK())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks[0].Status)
+	assert.Equal(t, StatusFail, report.Checks[1].Status)
+	assert.Equal(t, os.ErrNotExist.Error(), report.Checks[1].Message)
+}
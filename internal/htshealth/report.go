@@ -0,0 +1,123 @@
+// Package htshealth checks whether the service and its dependencies are in a
+// state to serve htsget requests
+//
+// Module report contains the JSON breakdown of check results returned by the
+// health and readiness endpoints
+package htshealth
+
+import (
+	"context"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+)
+
+// StatusOK indicates a check, or all checks of a report, passed
+const StatusOK = "ok"
+
+// StatusFail indicates a check, or at least one check of a report, failed
+const StatusFail = "fail"
+
+// Check is the result of a single health check
+//
+// Attributes
+//	Name (string): name of the check (e.g. "tempdir", "samtools")
+//	Target (string): the object checked, when a check is run for many (e.g. a data source pattern)
+//	Status (string): "ok" or "fail"
+//	Message (string): detail on the check result, such as the reason for failure
+type Check struct {
+	Name    string `json:"name"`
+	Target  string `json:"target,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the result of running a set of health checks
+//
+// Attributes
+//	Status (string): "ok" if all checks passed, otherwise "fail"
+//	Checks ([]*Check): results of individual checks, in the order they were run
+type Report struct {
+	Status string   `json:"status"`
+	Checks []*Check `json:"checks"`
+}
+
+// newReport instantiates an empty, passing report
+//
+// Returns
+//	(*Report): report without checks
+func newReport() *Report {
+	report := new(Report)
+	report.Status = StatusOK
+	report.Checks = []*Check{}
+	return report
+}
+
+// add records the outcome of a check in the report. a check with a non-nil
+// error fails, and fails the report
+//
+//	Type: Report
+// Arguments
+//	name (string): name of the check
+//	target (string): object checked, may be empty
+//	message (string): detail on a passing check, may be empty
+//	err (error): if not nil, the reason the check failed
+func (report *Report) add(name string, target string, message string, err error) {
+	check := new(Check)
+	check.Name = name
+	check.Target = target
+	check.Status = StatusOK
+	check.Message = message
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		report.Status = StatusFail
+	}
+	report.Checks = append(report.Checks, check)
+}
+
+// OK indicates whether all checks in the report passed
+//
+//	Type: Report
+// Returns
+//	(bool): true if all checks passed
+func (report *Report) OK() bool {
+	return report.Status == StatusOK
+}
+
+// Liveness runs the checks indicating the process itself is healthy: the
+// configuration loaded cleanly and the tempdir is writable
+//
+// Returns
+//	(*Report): liveness check results
+func Liveness() *Report {
+	report := newReport()
+	report.add("config", "", "", checkConfig())
+	report.add("tempdir", tempdir(), "", checkTempdir(tempdir()))
+	return report
+}
+
+// Readiness runs all liveness checks, and additionally checks that the
+// service dependencies are available: the required tools are on the PATH at
+// acceptable versions, and each data source of an enabled endpoint can be
+// resolved. if the cache is warmed at startup, the service is not ready until
+// the warm-up has completed
+//
+// Arguments
+//	ctx (context.Context): bounds the time spent running tools and resolving data sources
+// Returns
+//	(*Report): readiness check results
+func Readiness(ctx context.Context) *Report {
+	report := Liveness()
+	for _, tool := range requiredTools() {
+		version, err := checkTool(ctx, tool)
+		report.add(tool.name, "", version, err)
+	}
+	for _, source := range enabledDataSources() {
+		report.add("dataSource", source.target, "", checkDataSource(ctx, source.dataSource))
+	}
+	if started, done, warmReport := htscache.GetWarmupStatus(); started {
+		message, err := checkWarmup(done, warmReport)
+		report.add("warmup", "", message, err)
+	}
+	return report
+}
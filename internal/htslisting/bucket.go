@@ -0,0 +1,140 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module bucket lists the objects of an S3-compatible bucket with the
+// ListObjectsV2 API, both for virtual-hosted style urls
+// (https://bucket.s3.amazonaws.com/key) and path style urls
+// (https://host/bucket/key)
+package htslisting
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// bucketLocation locates the objects a url template can be filled into
+//
+// Attributes
+//	listURL (string): url of the bucket, to which list requests are made
+//	objectBase (string): url the key of an object is appended to
+//	keyPrefix (string): fixed part of the keys of the matching objects
+type bucketLocation struct {
+	listURL    string
+	objectBase string
+	keyPrefix  string
+}
+
+// listBucketResult is the body of a ListObjectsV2 response
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// isVirtualHosted checks whether a bucket host names the bucket as its first
+// label, e.g. bucket.s3.amazonaws.com or bucket.s3.us-east-1.amazonaws.com
+func isVirtualHosted(host string) bool {
+	return strings.Contains(host, ".s3.") || strings.Contains(host, ".s3-")
+}
+
+// newBucketLocation locates the bucket and key prefix of a url template. the
+// host, and the bucket of path style urls, cannot be templated
+//
+// Arguments
+//	urlTemplate (string): object url template
+// Returns
+//	(*bucketLocation): bucket and key prefix of the matching objects
+//	(error): if not nil, the template does not point to a single bucket
+func newBucketLocation(urlTemplate string) (*bucketLocation, error) {
+	u, err := url.Parse(fixedPrefix(urlTemplate))
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || !strings.HasPrefix(u.Path, "/") {
+		return nil, errors.New("url template " + urlTemplate + " does not point to a single bucket")
+	}
+	root := u.Scheme + "://" + u.Host
+	location := new(bucketLocation)
+	if isVirtualHosted(u.Host) {
+		location.listURL = root + "/"
+		location.objectBase = root + "/"
+		location.keyPrefix = u.Path[1:]
+		return location, nil
+	}
+
+	segments := strings.SplitN(u.Path[1:], "/", 2)
+	if len(segments) < 2 || segments[0] == "" {
+		return nil, errors.New("url template " + urlTemplate + " does not point to a single bucket")
+	}
+	location.listURL = root + "/" + segments[0]
+	location.objectBase = root + "/" + segments[0] + "/"
+	location.keyPrefix = segments[1]
+	return location, nil
+}
+
+// listBucket gets the urls of the objects in the bucket a url template
+// points to, whose keys start with the fixed part of the template
+//
+// Arguments
+//	ctx (context.Context): request context
+//	urlTemplate (string): object url template
+// Returns
+//	([]string): urls of the listed objects
+//	(error): if not nil, the bucket could not be listed
+func listBucket(ctx context.Context, urlTemplate string) ([]string, error) {
+	location, err := newBucketLocation(urlTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := []string{}
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", location.keyPrefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		result, err := listBucketPage(ctx, location.listURL+"?"+query.Encode())
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			urls = append(urls, location.objectBase+content.Key)
+		}
+		if !result.IsTruncated {
+			return urls, nil
+		}
+		if result.NextContinuationToken == "" {
+			return nil, errors.New("truncated bucket listing has no continuation token")
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// listBucketPage makes a single ListObjectsV2 request
+func listBucketPage(ctx context.Context, listURL string) (*listBucketResult, error) {
+	res, err := htsutils.GetObject(ctx, listURL)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("could not list bucket: " + res.Status)
+	}
+	result := new(listBucketResult)
+	err = xml.NewDecoder(res.Body).Decode(result)
+	if err != nil {
+		return nil, errors.New("could not parse bucket listing: " + err.Error())
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module listing lists the ids of an endpoint across its data sources, and
+// pages through them
+package htslisting
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// ListIDs gets the sorted, distinct ids served by the data sources of an
+// endpoint. data sources that cannot be enumerated are logged and skipped.
+// the ids are cached, so that paging through them does not enumerate the
+// data sources again
+//
+// Arguments
+//	ctx (context.Context): request context
+//	ep (htsconstants.APIEndpoint): listing endpoint
+// Returns
+//	([]string): sorted ids
+//	(error): if not nil, the ids could not be listed
+func ListIDs(ctx context.Context, ep htsconstants.APIEndpoint) ([]string, error) {
+	return htscache.ObjectIDs(ep.String(), func() ([]string, error) {
+		registry := htsconfig.GetDataSourceRegistry(ep)
+		seen := map[string]bool{}
+		ids := []string{}
+		for _, source := range registry.Sources {
+			sourceIDs, err := listSource(ctx, registry, source)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				htslog.Warn("could not list data source", htslog.Fields{
+					"endpoint": ep.String(),
+					"pattern":  source.Pattern,
+					"error":    err.Error(),
+				})
+				continue
+			}
+			for _, id := range sourceIDs {
+				if !seen[id] {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+		sort.Strings(ids)
+		return ids, nil
+	})
+}
+
+// Page gets a page of sorted ids starting with a prefix
+//
+// Arguments
+//	ids ([]string): sorted ids
+//	prefix (string): only ids starting with the prefix are paged through
+//	pageSize (int): maximum number of ids in the page
+//	pageToken (string): token returned with the previous page, empty for the first page
+// Returns
+//	([]string): ids of the page
+//	(string): token requesting the next page, empty if this is the last page
+//	(error): if not nil, the page token is invalid
+func Page(ids []string, prefix string, pageSize int, pageToken string) ([]string, string, error) {
+	start := sort.SearchStrings(ids, prefix)
+	if pageToken != "" {
+		last, err := htsutils.DecodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		if after := sort.SearchStrings(ids, last+"\x00"); after > start {
+			start = after
+		}
+	}
+
+	page := []string{}
+	for i := start; i < len(ids) && strings.HasPrefix(ids[i], prefix); i++ {
+		if len(page) == pageSize {
+			return page, htsutils.EncodePageToken(page[len(page)-1]), nil
+		}
+		page = append(page, ids[i])
+	}
+	return page, "", nil
+}
@@ -0,0 +1,248 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module listing_test tests listing, sources and bucket
+package htslisting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRegistry creates a registry of data sources, each given as a pattern,
+// path template and manifest
+func newRegistry(sources ...[3]string) *htsconfig.DataSourceRegistry {
+	registry := new(htsconfig.DataSourceRegistry)
+	for _, source := range sources {
+		registry.Sources = append(registry.Sources, &htsconfig.DataSource{
+			Pattern:  source[0],
+			Path:     source[1],
+			Manifest: source[2],
+		})
+	}
+	return registry
+}
+
+// touch creates empty files under a directory
+func touch(dir string, names ...string) {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte{}, 0644)
+	}
+}
+
+func TestListSourceLocal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htslisting-test-")
+	defer os.RemoveAll(dir)
+	touch(dir, "A1.mus.bam", "A1.mus.bam.bai", "A2.mus.bam", "B1.other.bam", "shadowed.mus.bam")
+
+	registry := newRegistry(
+		[3]string{"^mus\\.shadowed$", dir + "/elsewhere.bam", ""},
+		[3]string{"^mus\\.(?P<accession>.*)$", dir + "/{accession}.mus.bam", ""},
+	)
+	ids, err := listSource(context.Background(), registry, registry.Sources[1])
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"mus.A1", "mus.A2"}, ids)
+
+	// patterns that cannot be inverted into ids cannot be enumerated
+	registry = newRegistry([3]string{"^mus\\.(.*)$", dir + "/{accession}.mus.bam", ""})
+	_, err = listSource(context.Background(), registry, registry.Sources[0])
+	assert.NotNil(t, err)
+}
+
+func TestListSourceTestData(t *testing.T) {
+	registry := newRegistry([3]string{
+		"^tabulamuris\\.(?P<accession>.*)$",
+		"./../../data/test/sources/tabulamuris/{accession}.mus.Aligned.out.sorted.bam",
+		"",
+	})
+	ids, err := listSource(context.Background(), registry, registry.Sources[0])
+	assert.Nil(t, err)
+	assert.Contains(t, ids, "tabulamuris.A1-B000168-3_57_F-1-1_R2")
+}
+
+func TestListSourceManifest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htslisting-test-")
+	defer os.RemoveAll(dir)
+	manifest := filepath.Join(dir, "ids.txt")
+	ioutil.WriteFile(manifest, []byte("# cohort\nmus.A1\n\n  mus.A2  \nother.B1\n"), 0644)
+
+	registry := newRegistry([3]string{"^mus\\.(?P<accession>.*)$", dir + "/{accession}.mus.bam", manifest})
+	ids, err := listSource(context.Background(), registry, registry.Sources[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mus.A1", "mus.A2"}, ids)
+
+	registry.Sources[0].Manifest = filepath.Join(dir, "missing.txt")
+	_, err = listSource(context.Background(), registry, registry.Sources[0])
+	assert.NotNil(t, err)
+}
+
+// newBucketServer serves a path style bucket, listing the given keys two at
+// a time
+func newBucketServer(bucket string, keys []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		if request.URL.Path != "/"+bucket || query.Get("list-type") != "2" {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		matching := []string{}
+		for _, key := range keys {
+			if strings.HasPrefix(key, query.Get("prefix")) {
+				matching = append(matching, key)
+			}
+		}
+		start := 0
+		fmt.Sscanf(query.Get("continuation-token"), "%d", &start)
+		end := start + 2
+		truncated := end < len(matching)
+		if !truncated {
+			end = len(matching)
+		}
+		fmt.Fprint(writer, `<?xml version="1.0" encoding="UTF-8"?>`)
+		fmt.Fprint(writer, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">`)
+		for _, key := range matching[start:end] {
+			fmt.Fprintf(writer, "<Contents><Key>%s</Key><Size>0</Size></Contents>", key)
+		}
+		fmt.Fprintf(writer, "<IsTruncated>%t</IsTruncated>", truncated)
+		if truncated {
+			fmt.Fprintf(writer, "<NextContinuationToken>%d</NextContinuationToken>", end)
+		}
+		fmt.Fprint(writer, "</ListBucketResult>")
+	}))
+}
+
+func TestListSourceBucket(t *testing.T) {
+	server := newBucketServer("cohort", []string{
+		"reads/A1.bam", "reads/A1.bam.bai", "reads/A2.bam", "reads/nested/A3.bam", "variants/A1.vcf.gz",
+	})
+	defer server.Close()
+
+	registry := newRegistry([3]string{"^mus\\.(?P<accession>.*)$", server.URL + "/cohort/reads/{accession}.bam", ""})
+	ids, err := listSource(context.Background(), registry, registry.Sources[0])
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mus.A1", "mus.A2", "mus.nested/A3"}, ids)
+
+	registry = newRegistry([3]string{"^mus\\.(?P<accession>.*)$", server.URL + "/missing/reads/{accession}.bam", ""})
+	_, err = listSource(context.Background(), registry, registry.Sources[0])
+	assert.NotNil(t, err)
+}
+
+var newBucketLocationTC = []struct {
+	template    string
+	expLocation *bucketLocation
+}{
+	{
+		"https://bucket.s3.amazonaws.com/reads/{accession}.bam",
+		&bucketLocation{"https://bucket.s3.amazonaws.com/", "https://bucket.s3.amazonaws.com/", "reads/"},
+	},
+	{
+		"https://bucket.s3.us-east-1.amazonaws.com/{accession}.bam",
+		&bucketLocation{"https://bucket.s3.us-east-1.amazonaws.com/", "https://bucket.s3.us-east-1.amazonaws.com/", ""},
+	},
+	{
+		"http://localhost:9000/bucket/reads/{accession}.bam",
+		&bucketLocation{"http://localhost:9000/bucket", "http://localhost:9000/bucket/", "reads/"},
+	},
+	{"https://{bucket}.s3.amazonaws.com/{accession}.bam", nil},
+	{"http://localhost:9000/{bucket}/{accession}.bam", nil},
+}
+
+func TestNewBucketLocation(t *testing.T) {
+	for _, tc := range newBucketLocationTC {
+		location, err := newBucketLocation(tc.template)
+		if tc.expLocation == nil {
+			assert.NotNil(t, err, tc.template)
+		} else {
+			assert.Nil(t, err, tc.template)
+			assert.Equal(t, tc.expLocation, location)
+		}
+	}
+}
+
+var pageTC = []struct {
+	prefix   string
+	pageSize int
+	expPages [][]string
+}{
+	{"", 2, [][]string{{"mus.A1", "mus.A2"}, {"mus.B1", "rat.A1"}, {"rat.A2"}}},
+	{"", 5, [][]string{{"mus.A1", "mus.A2", "mus.B1", "rat.A1", "rat.A2"}}},
+	{"mus.", 2, [][]string{{"mus.A1", "mus.A2"}, {"mus.B1"}}},
+	{"rat.", 2, [][]string{{"rat.A1", "rat.A2"}}},
+	{"zebrafish.", 2, [][]string{{}}},
+}
+
+func TestPage(t *testing.T) {
+	ids := []string{"mus.A1", "mus.A2", "mus.B1", "rat.A1", "rat.A2"}
+	for _, tc := range pageTC {
+		pageToken := ""
+		for i, expPage := range tc.expPages {
+			page, nextPageToken, err := Page(ids, tc.prefix, tc.pageSize, pageToken)
+			assert.Nil(t, err)
+			assert.Equal(t, expPage, page)
+			if i == len(tc.expPages)-1 {
+				assert.Equal(t, "", nextPageToken)
+			} else {
+				assert.NotEqual(t, "", nextPageToken)
+			}
+			pageToken = nextPageToken
+		}
+	}
+
+	// a token issued for a removed id still continues after it
+	page, _, _ := Page(ids, "", 2, htsutils.EncodePageToken("mus.A3"))
+	assert.Equal(t, []string{"mus.B1", "rat.A1"}, page)
+	_, _, err := Page(ids, "", 2, "!")
+	assert.NotNil(t, err)
+}
+
+func TestListIDs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "htslisting-test-")
+	defer os.RemoveAll(dir)
+	touch(dir, "mus/A2.bam", "mus/A1.bam", "mus.A1.bam", "rat.A1.bam")
+
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"reads": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]string{
+						{"pattern": "^mus\\.(?P<accession>.*)$", "path": dir + "/mus/{accession}.bam"},
+						{"pattern": "^(?P<id>.*)$", "path": dir + "/{id}.bam"},
+						{"pattern": "^broken\\.(.*)$", "path": dir + "/broken/{accession}.bam"},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	defer func() {
+		htsconfig.SetConfigFile(nil)
+		htsconfig.LoadConfig()
+	}()
+
+	// mus.A1.bam is shadowed by the first data source, and the data source
+	// that cannot be enumerated is skipped
+	ids, err := ListIDs(context.Background(), htsconstants.APIEndpointReadsList)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mus.A1", "mus.A2", "rat.A1"}, ids)
+}
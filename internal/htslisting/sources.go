@@ -0,0 +1,156 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module sources enumerates the ids served by a single data source, from its
+// manifest file if it has one, otherwise by globbing its local path template
+// or listing the bucket its url template points to
+package htslisting
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// globEscaper escapes the characters of literal path text that have a
+// special meaning in glob patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// listSource gets the ids served by a data source. ids enumerated from object
+// locations are only kept if the registry resolves them back to the same
+// location, so that ids shadowed by an earlier data source are not listed
+//
+// Arguments
+//	ctx (context.Context): request context
+//	registry (*htsconfig.DataSourceRegistry): registry the data source belongs to
+//	source (*htsconfig.DataSource): data source to enumerate
+// Returns
+//	([]string): ids served by the data source, unsorted
+//	(error): if not nil, the data source could not be enumerated
+func listSource(ctx context.Context, registry *htsconfig.DataSourceRegistry, source *htsconfig.DataSource) ([]string, error) {
+	if source.Manifest != "" {
+		return listManifest(ctx, registry, source.Manifest)
+	}
+
+	idTemplate, err := invertPattern(source.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	pathTemplate := source.Path
+	var locations []string
+	if htsutils.IsValidURL(pathTemplate) {
+		locations, err = listBucket(ctx, pathTemplate)
+	} else {
+		pathTemplate = filepath.Clean(pathTemplate)
+		locations, err = globLocal(pathTemplate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pathRegex, err := templateRegex(pathTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, location := range locations {
+		values, ok := matchTemplate(pathRegex, location)
+		if !ok {
+			continue
+		}
+		id, err := fillTemplate(idTemplate, values)
+		if err != nil {
+			continue
+		}
+		path, err := registry.GetMatchingPath(id)
+		if err != nil || !sameLocation(path, location) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// sameLocation checks whether a resolved object path is the enumerated
+// location, local paths being compared in their cleaned form
+func sameLocation(path string, location string) bool {
+	if htsutils.IsValidURL(path) {
+		return path == location
+	}
+	return filepath.Clean(path) == location
+}
+
+// globLocal gets the local files a path template can be filled into
+//
+// Arguments
+//	pathTemplate (string): local path template
+// Returns
+//	([]string): paths of the matching files
+//	(error): if not nil, the template is not a valid glob pattern
+func globLocal(pathTemplate string) ([]string, error) {
+	var builder strings.Builder
+	last := 0
+	for _, loc := range templateParameterRegex.FindAllStringIndex(pathTemplate, -1) {
+		builder.WriteString(globEscaper.Replace(pathTemplate[last:loc[0]]))
+		builder.WriteString("*")
+		last = loc[1]
+	}
+	builder.WriteString(globEscaper.Replace(pathTemplate[last:]))
+	return filepath.Glob(builder.String())
+}
+
+// listManifest gets the ids listed in a manifest file, one per line. blank
+// lines and lines starting with # are ignored, as are ids no data source
+// resolves
+//
+// Arguments
+//	ctx (context.Context): request context
+//	registry (*htsconfig.DataSourceRegistry): registry resolving the listed ids
+//	manifest (string): local path or url of the manifest file
+// Returns
+//	([]string): listed ids
+//	(error): if not nil, the manifest could not be read
+func listManifest(ctx context.Context, registry *htsconfig.DataSourceRegistry, manifest string) ([]string, error) {
+	reader, err := openManifest(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	ids := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		id := strings.TrimSpace(scanner.Text())
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		if _, err := registry.GetMatchingPath(id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, scanner.Err()
+}
+
+// openManifest opens a local or remote manifest file for reading
+func openManifest(ctx context.Context, manifest string) (io.ReadCloser, error) {
+	if !htsutils.IsValidURL(manifest) {
+		return os.Open(manifest)
+	}
+	res, err := htsutils.GetObject(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, errors.New("could not get manifest " + manifest + ": " + res.Status)
+	}
+	return res.Body, nil
+}
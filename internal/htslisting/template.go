@@ -0,0 +1,162 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module template converts between ids, data source patterns and path
+// templates: a pattern is inverted into an id template, and object locations
+// are matched against a path template to recover the pattern's parameters
+package htslisting
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// templateParameterRegex matches the {parameter} placeholders of a template
+var templateParameterRegex = regexp.MustCompile(`\{(.+?)\}`)
+
+// invertPattern converts a data source pattern into an id template, with a
+// {name} placeholder in place of each named group, e.g.
+// "^tabulamuris\.(?P<accession>.*)$" becomes "tabulamuris.{accession}". only
+// patterns made of literal text and named groups can be inverted
+//
+// Arguments
+//	pattern (string): data source regex pattern
+// Returns
+//	(string): id template
+//	(error): if not nil, the pattern cannot be inverted
+func invertPattern(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	err = writeInvertedPattern(&builder, re.Simplify())
+	if err != nil {
+		return "", fmt.Errorf("pattern %s cannot be inverted into ids: %v", pattern, err)
+	}
+	return builder.String(), nil
+}
+
+// writeInvertedPattern writes the id template of a parsed pattern node
+func writeInvertedPattern(builder *strings.Builder, re *syntax.Regexp) error {
+	switch re.Op {
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := writeInvertedPattern(builder, sub); err != nil {
+				return err
+			}
+		}
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return errors.New("case-insensitive text is ambiguous")
+		}
+		builder.WriteString(string(re.Rune))
+	case syntax.OpCapture:
+		if re.Name == "" {
+			return errors.New("groups must be named")
+		}
+		builder.WriteString("{" + re.Name + "}")
+	case syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpEmptyMatch:
+	default:
+		return errors.New("only literal text and named groups are supported, found " + re.String())
+	}
+	return nil
+}
+
+// templateParameters gets the names of the placeholders of a template, in
+// order of first appearance
+func templateParameters(template string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range templateParameterRegex.FindAllStringSubmatch(template, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// fillTemplate replaces each placeholder of a template with its value
+//
+// Arguments
+//	template (string): template with {name} placeholders
+//	values (map[string]string): value of each placeholder
+// Returns
+//	(string): the filled template
+//	(error): if not nil, a placeholder has no value
+func fillTemplate(template string, values map[string]string) (string, error) {
+	var missing error
+	filled := templateParameterRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := values[name]
+		if !ok {
+			missing = errors.New("no value for template parameter {" + name + "}")
+		}
+		return value
+	})
+	return filled, missing
+}
+
+// templateRegex compiles a regex matching the strings a template can be
+// filled into, capturing the value of each placeholder. repeated
+// placeholders are captured at their first appearance only
+//
+// Arguments
+//	template (string): template with {name} placeholders
+// Returns
+//	(*regexp.Regexp): anchored regex with a named group per placeholder
+//	(error): if not nil, the template has invalid placeholder names
+func templateRegex(template string) (*regexp.Regexp, error) {
+	var builder strings.Builder
+	builder.WriteString("^")
+	seen := map[string]bool{}
+	last := 0
+	for _, loc := range templateParameterRegex.FindAllStringSubmatchIndex(template, -1) {
+		builder.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		name := template[loc[2]:loc[3]]
+		if seen[name] {
+			builder.WriteString("(.+?)")
+		} else {
+			seen[name] = true
+			builder.WriteString("(?P<" + name + ">.+?)")
+		}
+		last = loc[1]
+	}
+	builder.WriteString(regexp.QuoteMeta(template[last:]))
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
+
+// matchTemplate recovers the placeholder values a template was filled with
+//
+// Arguments
+//	re (*regexp.Regexp): regex compiled from the template by templateRegex
+//	filled (string): filled template
+// Returns
+//	(map[string]string): value of each placeholder
+//	(bool): false if the string does not match the template
+func matchTemplate(re *regexp.Regexp, filled string) (map[string]string, bool) {
+	submatches := re.FindStringSubmatch(filled)
+	if submatches == nil {
+		return nil, false
+	}
+	values := map[string]string{}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			values[name] = submatches[i]
+		}
+	}
+	return values, true
+}
+
+// fixedPrefix gets the part of a template before its first placeholder
+func fixedPrefix(template string) string {
+	if i := strings.Index(template, "{"); i >= 0 {
+		return template[:i]
+	}
+	return template
+}
@@ -0,0 +1,81 @@
+// Package htslisting enumerates the ids of the objects served by the data
+// sources of an endpoint, so that clients can discover what they can fetch
+//
+// Module template_test tests template
+package htslisting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var invertPatternTC = []struct {
+	pattern     string
+	expTemplate string
+	expError    bool
+}{
+	{"^tabulamuris\\.(?P<accession>.*)$", "tabulamuris.{accession}", false},
+	{"^(?P<cohort>[a-z]+)/(?P<sample>.+)\\.bam$", "{cohort}/{sample}.bam", false},
+	{"^object$", "object", false},
+	{"^tabulamuris\\.(.*)$", "", true},
+	{"^(tabula|muris)\\.(?P<accession>.*)$", "", true},
+	{"^a+(?P<accession>.*)$", "", true},
+	{"(?i)^tabulamuris\\.(?P<accession>.*)$", "", true},
+	{"^(?P<accession>.*", "", true},
+}
+
+func TestInvertPattern(t *testing.T) {
+	for _, tc := range invertPatternTC {
+		template, err := invertPattern(tc.pattern)
+		if tc.expError {
+			assert.NotNil(t, err, tc.pattern)
+		} else {
+			assert.Nil(t, err, tc.pattern)
+			assert.Equal(t, tc.expTemplate, template)
+		}
+	}
+}
+
+var templateTC = []struct {
+	template      string
+	values        map[string]string
+	expFilled     string
+	expParameters []string
+}{
+	{
+		"./data/{accession}.mus.bam",
+		map[string]string{"accession": "A1-B000168"},
+		"./data/A1-B000168.mus.bam",
+		[]string{"accession"},
+	},
+	{
+		"https://bucket.s3.amazonaws.com/{cohort}/{sample}/{sample}.bam",
+		map[string]string{"cohort": "c1", "sample": "s.2"},
+		"https://bucket.s3.amazonaws.com/c1/s.2/s.2.bam",
+		[]string{"cohort", "sample"},
+	},
+	{"object.bam", map[string]string{}, "object.bam", []string{}},
+}
+
+func TestTemplate(t *testing.T) {
+	for _, tc := range templateTC {
+		assert.Equal(t, tc.expParameters, templateParameters(tc.template))
+		filled, err := fillTemplate(tc.template, tc.values)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expFilled, filled)
+
+		// the values are recovered from the filled template
+		re, err := templateRegex(tc.template)
+		assert.Nil(t, err)
+		values, ok := matchTemplate(re, filled)
+		assert.True(t, ok)
+		assert.Equal(t, tc.values, values)
+		_, ok = matchTemplate(re, filled+".bai")
+		assert.False(t, ok)
+	}
+
+	_, err := fillTemplate("{cohort}/{sample}.bam", map[string]string{"cohort": "c1"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "https://bucket.s3.amazonaws.com/", fixedPrefix(templateTC[1].template))
+}
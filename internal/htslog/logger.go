@@ -0,0 +1,175 @@
+// Package htslog writes structured (JSON) application and access logs
+//
+// Module logger contains the leveled JSON logger, and the package-level
+// singleton used throughout the program
+package htslog
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level enum for log severity levels
+type Level int
+
+// enum values for Level
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelStringMap maps Level enum values to string representation
+var levelStringMap = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String gets the string representation of a Level enum instance
+func (l Level) String() string {
+	return levelStringMap[l]
+}
+
+// ParseLevel gets the Level enum value matching a (case insensitive) level
+// name. returns an error if the name does not match any level
+func ParseLevel(name string) (Level, error) {
+	for level, levelString := range levelStringMap {
+		if strings.ToLower(name) == levelString {
+			return level, nil
+		}
+	}
+	return LevelInfo, errors.New("invalid log level: " + name)
+}
+
+// Fields holds the structured key-value pairs attached to a single log entry
+type Fields map[string]interface{}
+
+// Logger writes leveled log entries as single-line JSON objects. entries
+// below the logger's level are discarded
+type Logger struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	level  Level
+}
+
+// NewLogger instantiates a Logger writing entries at or above level to writer
+func NewLogger(writer io.Writer, level Level) *Logger {
+	logger := new(Logger)
+	logger.writer = writer
+	logger.level = level
+	return logger
+}
+
+// Enabled checks if entries of the given level will be written by the logger
+func (logger *Logger) Enabled(level Level) bool {
+	return level >= logger.level
+}
+
+// Log writes a single entry with the given level, message and fields
+func (logger *Logger) Log(level Level, msg string, fields Fields) {
+	if !logger.Enabled(level) {
+		return
+	}
+
+	entry := make(map[string]interface{}, len(fields)+3)
+	for key, value := range fields {
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		entry[key] = value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.writer.Write(line)
+}
+
+// loggerSingleton (*Logger): the logger used by package-level functions.
+// writes to stderr until replaced via SetLogger or Configure
+var loggerSingleton = NewLogger(os.Stderr, LevelInfo)
+
+// loggerSingletonCloser (io.Closer): closes the underlying log file when the
+// singleton is replaced
+var loggerSingletonCloser io.Closer
+
+var loggerSingletonMutex sync.RWMutex
+
+// SetLogger replaces the logger used by package-level functions
+func SetLogger(logger *Logger) {
+	loggerSingletonMutex.Lock()
+	defer loggerSingletonMutex.Unlock()
+	if loggerSingletonCloser != nil {
+		loggerSingletonCloser.Close()
+		loggerSingletonCloser = nil
+	}
+	loggerSingleton = logger
+}
+
+// GetLogger gets the logger used by package-level functions
+func GetLogger() *Logger {
+	loggerSingletonMutex.RLock()
+	defer loggerSingletonMutex.RUnlock()
+	return loggerSingleton
+}
+
+// Configure sets up the package-level logger to write to a rotating log file
+//
+// Arguments
+//	path (string): path to the log file
+//	levelName (string): minimum level of entries to write
+//	maxSizeMB (int): size at which the log file is rotated, in megabytes
+//	maxBackups (int): number of rotated log files to keep
+// Returns
+//	(error): if not nil, the level was invalid or the log file could not be opened
+func Configure(path string, levelName string, maxSizeMB int, maxBackups int) error {
+	level, err := ParseLevel(levelName)
+	if err != nil {
+		return err
+	}
+	file, err := NewRotatingFile(path, int64(maxSizeMB)*1024*1024, maxBackups)
+	if err != nil {
+		return err
+	}
+	SetLogger(NewLogger(file, level))
+	loggerSingletonMutex.Lock()
+	loggerSingletonCloser = file
+	loggerSingletonMutex.Unlock()
+	return nil
+}
+
+// Debug writes a debug entry with the package-level logger
+func Debug(msg string, fields Fields) {
+	GetLogger().Log(LevelDebug, msg, fields)
+}
+
+// Info writes an info entry with the package-level logger
+func Info(msg string, fields Fields) {
+	GetLogger().Log(LevelInfo, msg, fields)
+}
+
+// Warn writes a warn entry with the package-level logger
+func Warn(msg string, fields Fields) {
+	GetLogger().Log(LevelWarn, msg, fields)
+}
+
+// Error writes an error entry with the package-level logger
+func Error(msg string, fields Fields) {
+	GetLogger().Log(LevelError, msg, fields)
+}
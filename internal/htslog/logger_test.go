@@ -0,0 +1,76 @@
+// Package htslog writes structured (JSON) application and access logs
+//
+// Module logger_test tests module logger
+package htslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var parseLevelTC = []struct {
+	name     string
+	expLevel Level
+	expErr   bool
+}{
+	{"debug", LevelDebug, false},
+	{"INFO", LevelInfo, false},
+	{"Warn", LevelWarn, false},
+	{"error", LevelError, false},
+	{"verbose", LevelInfo, true},
+}
+
+var loggerLogTC = []struct {
+	level    Level
+	logLevel Level
+	msg      string
+	fields   Fields
+	expLines int
+}{
+	{LevelInfo, LevelInfo, "request", Fields{"status": 200}, 1},
+	{LevelInfo, LevelDebug, "discarded", nil, 0},
+	{LevelWarn, LevelError, "failure", Fields{"error": errors.New("boom")}, 1},
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, tc := range parseLevelTC {
+		level, err := ParseLevel(tc.name)
+		assert.Equal(t, tc.expLevel, level)
+		assert.Equal(t, tc.expErr, err != nil)
+	}
+}
+
+func TestLoggerLog(t *testing.T) {
+	for _, tc := range loggerLogTC {
+		buffer := new(bytes.Buffer)
+		logger := NewLogger(buffer, tc.level)
+		logger.Log(tc.logLevel, tc.msg, tc.fields)
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		if tc.expLines == 0 {
+			assert.Equal(t, "", buffer.String())
+			continue
+		}
+		assert.Equal(t, tc.expLines, len(lines))
+
+		entry := make(map[string]interface{})
+		err := json.Unmarshal([]byte(lines[0]), &entry)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.msg, entry["msg"])
+		assert.Equal(t, tc.logLevel.String(), entry["level"])
+		assert.NotEmpty(t, entry["time"])
+		for key, value := range tc.fields {
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			expected, _ := json.Marshal(value)
+			actual, _ := json.Marshal(entry[key])
+			assert.Equal(t, string(expected), string(actual))
+		}
+	}
+}
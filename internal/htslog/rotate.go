@@ -0,0 +1,102 @@
+// Package htslog writes structured (JSON) application and access logs
+//
+// Module rotate contains a size-based rotating log file writer
+package htslog
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+// RotatingFile is an append-only file writer that rotates the file once it
+// exceeds a maximum size. rotated files are renamed with a numeric suffix
+// (path.1 being the most recent), and only maxBackups of them are kept
+type RotatingFile struct {
+	mutex      sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens (or creates) the log file at path for appending. a
+// maxBytes of zero or less disables rotation
+func NewRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	rotatingFile := new(RotatingFile)
+	rotatingFile.path = path
+	rotatingFile.maxBytes = maxBytes
+	rotatingFile.maxBackups = maxBackups
+	err := rotatingFile.open()
+	if err != nil {
+		return nil, err
+	}
+	return rotatingFile, nil
+}
+
+// open opens the log file for appending, recording its current size
+func (rotatingFile *RotatingFile) open() error {
+	file, err := os.OpenFile(rotatingFile.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rotatingFile.file = file
+	rotatingFile.size = fileInfo.Size()
+	return nil
+}
+
+// backupPath gets the path of the nth rotated log file
+func (rotatingFile *RotatingFile) backupPath(n int) string {
+	return rotatingFile.path + "." + strconv.Itoa(n)
+}
+
+// rotate closes the current file, shifts existing backups up by one (dropping
+// the oldest), moves the current file to the first backup, and reopens
+func (rotatingFile *RotatingFile) rotate() error {
+	err := rotatingFile.file.Close()
+	if err != nil {
+		return err
+	}
+
+	if rotatingFile.maxBackups > 0 {
+		os.Remove(rotatingFile.backupPath(rotatingFile.maxBackups))
+		for n := rotatingFile.maxBackups - 1; n >= 1; n-- {
+			os.Rename(rotatingFile.backupPath(n), rotatingFile.backupPath(n+1))
+		}
+		os.Rename(rotatingFile.path, rotatingFile.backupPath(1))
+	} else {
+		os.Remove(rotatingFile.path)
+	}
+	return rotatingFile.open()
+}
+
+// Write appends p to the log file, rotating first if the write would take the
+// file past its maximum size
+func (rotatingFile *RotatingFile) Write(p []byte) (int, error) {
+	rotatingFile.mutex.Lock()
+	defer rotatingFile.mutex.Unlock()
+
+	if rotatingFile.maxBytes > 0 && rotatingFile.size > 0 && rotatingFile.size+int64(len(p)) > rotatingFile.maxBytes {
+		err := rotatingFile.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rotatingFile.file.Write(p)
+	rotatingFile.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying log file
+func (rotatingFile *RotatingFile) Close() error {
+	rotatingFile.mutex.Lock()
+	defer rotatingFile.mutex.Unlock()
+	return rotatingFile.file.Close()
+}
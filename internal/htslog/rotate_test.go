@@ -0,0 +1,75 @@
+// Package htslog writes structured (JSON) application and access logs
+//
+// Module rotate_test tests module rotate
+package htslog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var rotatingFileTC = []struct {
+	maxBytes    int64
+	maxBackups  int
+	writes      []string
+	expCurrent  string
+	expBackups  []string
+	expNotExist []int
+}{
+	{
+		0,
+		2,
+		[]string{"aaaa\n", "bbbb\n"},
+		"aaaa\nbbbb\n",
+		[]string{},
+		[]int{1},
+	},
+	{
+		8,
+		2,
+		[]string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n"},
+		"dddd\n",
+		[]string{"cccc\n", "bbbb\n"},
+		[]int{3},
+	},
+	{
+		8,
+		0,
+		[]string{"aaaa\n", "bbbb\n"},
+		"bbbb\n",
+		[]string{},
+		[]int{1},
+	},
+}
+
+func TestRotatingFile(t *testing.T) {
+	for _, tc := range rotatingFileTC {
+		dir, err := ioutil.TempDir("", "htslog")
+		assert.Nil(t, err)
+		path := filepath.Join(dir, "test.log")
+
+		rotatingFile, err := NewRotatingFile(path, tc.maxBytes, tc.maxBackups)
+		assert.Nil(t, err)
+		for _, write := range tc.writes {
+			_, err := rotatingFile.Write([]byte(write))
+			assert.Nil(t, err)
+		}
+		rotatingFile.Close()
+
+		current, _ := ioutil.ReadFile(path)
+		assert.Equal(t, tc.expCurrent, string(current))
+		for i, expBackup := range tc.expBackups {
+			backup, _ := ioutil.ReadFile(rotatingFile.backupPath(i + 1))
+			assert.Equal(t, expBackup, string(backup))
+		}
+		for _, n := range tc.expNotExist {
+			_, err := os.Stat(rotatingFile.backupPath(n))
+			assert.True(t, os.IsNotExist(err))
+		}
+		os.RemoveAll(dir)
+	}
+}
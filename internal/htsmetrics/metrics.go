@@ -0,0 +1,140 @@
+// Package htsmetrics collects service metrics and exposes them in the
+// Prometheus text exposition format
+//
+// Module metrics defines the metrics collected by the service, and the HTTP
+// handler exposing them
+package htsmetrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// contentTypeTextExposition content type of the Prometheus text format
+const contentTypeTextExposition = "text/plain; version=0.0.4; charset=utf-8"
+
+// durationBuckets (seconds) for request and upstream latencies
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// subprocessDurationBuckets (seconds) for samtools/bcftools executions, which
+// may stream whole files
+var subprocessDurationBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900, 3600}
+
+// ticketBlockBuckets number of data blocks (urls) in a ticket
+var ticketBlockBuckets = []float64{1, 2, 4, 8, 16, 32, 64, 128}
+
+// DefaultRegistry holds all metrics collected by the service
+var DefaultRegistry = NewRegistry()
+
+// requestsTotal number of requests served, by endpoint
+var requestsTotal = DefaultRegistry.NewCounterVec(
+	"htsget_requests_total",
+	"Number of requests served, by endpoint.",
+	"endpoint",
+)
+
+// requestErrorsTotal number of requests answered with an htsget error, by
+// endpoint and error name
+var requestErrorsTotal = DefaultRegistry.NewCounterVec(
+	"htsget_request_errors_total",
+	"Number of requests answered with an htsget error, by endpoint and error name.",
+	"endpoint", "error",
+)
+
+// bytesStreamedTotal number of response body bytes written, by endpoint
+var bytesStreamedTotal = DefaultRegistry.NewCounterVec(
+	"htsget_bytes_streamed_total",
+	"Number of response body bytes written, by endpoint.",
+	"endpoint",
+)
+
+// requestDuration time taken to serve requests, by endpoint
+var requestDuration = DefaultRegistry.NewHistogramVec(
+	"htsget_request_duration_seconds",
+	"Time taken to serve requests, by endpoint.",
+	durationBuckets,
+	"endpoint",
+)
+
+// subprocessDuration time spent running external tools, by tool
+var subprocessDuration = DefaultRegistry.NewHistogramVec(
+	"htsget_subprocess_duration_seconds",
+	"Time spent running external tool subprocesses (samtools, bcftools), by tool.",
+	subprocessDurationBuckets,
+	"tool",
+)
+
+// ticketBlocks number of data blocks in returned tickets, by endpoint
+var ticketBlocks = DefaultRegistry.NewHistogramVec(
+	"htsget_ticket_blocks",
+	"Number of data blocks (urls) in returned tickets, by endpoint.",
+	ticketBlockBuckets,
+	"endpoint",
+)
+
+// upstreamHeadDuration latency of HEAD requests to remote data sources
+var upstreamHeadDuration = DefaultRegistry.NewHistogramVec(
+	"htsget_upstream_head_duration_seconds",
+	"Latency of HEAD requests made to remote (url) data sources.",
+	durationBuckets,
+)
+
+// cacheLookupsTotal number of object metadata cache lookups, by kind of
+// metadata and result
+var cacheLookupsTotal = DefaultRegistry.NewCounterVec(
+	"htsget_cache_lookups_total",
+	"Number of object metadata cache lookups, by kind of metadata and result (hit, miss).",
+	"kind", "result",
+)
+
+// ObserveRequest records a completed request against an endpoint
+//
+// Arguments
+//	endpoint (string): route pattern of the endpoint
+//	htsgetError (string): name of the htsget error written, empty if none
+//	bytesWritten (int64): number of response body bytes written
+//	start (time.Time): time the request was received
+func ObserveRequest(endpoint string, htsgetError string, bytesWritten int64, start time.Time) {
+	requestsTotal.Inc(endpoint)
+	if htsgetError != "" {
+		requestErrorsTotal.Inc(endpoint, htsgetError)
+	}
+	bytesStreamedTotal.Add(float64(bytesWritten), endpoint)
+	requestDuration.Observe(time.Since(start).Seconds(), endpoint)
+}
+
+// ObserveSubprocess records the duration of an external tool execution
+func ObserveSubprocess(tool string, start time.Time) {
+	subprocessDuration.Observe(time.Since(start).Seconds(), tool)
+}
+
+// ObserveTicketBlocks records the number of data blocks in a returned ticket
+func ObserveTicketBlocks(endpoint string, numBlocks int) {
+	ticketBlocks.Observe(float64(numBlocks), endpoint)
+}
+
+// ObserveUpstreamHead records the latency of a HEAD request to a remote
+// data source
+func ObserveUpstreamHead(start time.Time) {
+	upstreamHeadDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveCacheLookup records an object metadata cache lookup
+//
+// Arguments
+//	kind (string): kind of metadata looked up (e.g. "header")
+//	hit (bool): true if the metadata was found in the cache
+func ObserveCacheLookup(kind string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsTotal.Inc(kind, result)
+}
+
+// Handler serves all metrics in the default registry in the Prometheus text
+// exposition format
+func Handler(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", contentTypeTextExposition)
+	DefaultRegistry.WriteText(writer)
+}
@@ -0,0 +1,273 @@
+// Package htsmetrics collects service metrics and exposes them in the
+// Prometheus text exposition format
+//
+// Module registry contains labelled counter and histogram collectors, and
+// the registry that renders them
+package htsmetrics
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is a named metric family that can render itself in the
+// Prometheus text exposition format
+type collector interface {
+	name() string
+	writeText(writer io.Writer)
+}
+
+// Registry holds all registered collectors, rendering them in name order
+type Registry struct {
+	mutex      sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry instantiates an empty Registry
+func NewRegistry() *Registry {
+	return new(Registry)
+}
+
+// register adds a collector to the registry
+func (registry *Registry) register(c collector) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.collectors = append(registry.collectors, c)
+	sort.Slice(registry.collectors, func(i, j int) bool {
+		return registry.collectors[i].name() < registry.collectors[j].name()
+	})
+}
+
+// WriteText writes all registered metrics to the writer in the Prometheus
+// text exposition format
+func (registry *Registry) WriteText(writer io.Writer) {
+	registry.mutex.Lock()
+	collectors := append([]collector{}, registry.collectors...)
+	registry.mutex.Unlock()
+	for _, c := range collectors {
+		c.writeText(writer)
+	}
+}
+
+// series holds the label values identifying a single time series within a
+// metric family
+type series struct {
+	labelValues []string
+}
+
+// seriesKey joins label values into a single map key
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// escapeLabelValue escapes backslashes, double quotes and newlines in a label
+// value, as required by the exposition format
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	return strings.Replace(value, "\n", "\\n", -1)
+}
+
+// formatLabels renders label names and values as {name="value",...}. extra
+// name/value pairs (e.g. a histogram's "le") are appended after the series'
+// own labels
+func formatLabels(labelNames []string, labelValues []string, extra ...string) string {
+	pairs := []string{}
+	for i := 0; i < len(labelNames); i++ {
+		pairs = append(pairs, labelNames[i]+"=\""+escapeLabelValue(labelValues[i])+"\"")
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"=\""+escapeLabelValue(extra[i+1])+"\"")
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatFloat renders a sample value as expected by the exposition format
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric family
+func writeHeader(writer io.Writer, name string, help string, metricType string) {
+	io.WriteString(writer, "# HELP "+name+" "+help+"\n")
+	io.WriteString(writer, "# TYPE "+name+" "+metricType+"\n")
+}
+
+// sortedKeys gets the keys of a series map in sorted order, so that output
+// is deterministic
+func sortedKeys(keys map[string]*series) []string {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+/* **************************************************
+ * COUNTER
+ * ************************************************** */
+
+// CounterVec is a family of monotonically increasing counters, partitioned by
+// label values
+type CounterVec struct {
+	mutex      sync.Mutex
+	metricName string
+	help       string
+	labelNames []string
+	series     map[string]*series
+	values     map[string]float64
+}
+
+// NewCounterVec instantiates a CounterVec and adds it to the registry
+func (registry *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	counterVec := new(CounterVec)
+	counterVec.metricName = name
+	counterVec.help = help
+	counterVec.labelNames = labelNames
+	counterVec.series = make(map[string]*series)
+	counterVec.values = make(map[string]float64)
+	registry.register(counterVec)
+	return counterVec
+}
+
+func (counterVec *CounterVec) name() string {
+	return counterVec.metricName
+}
+
+// Add increments the counter identified by the label values by delta
+func (counterVec *CounterVec) Add(delta float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+	counterVec.mutex.Lock()
+	defer counterVec.mutex.Unlock()
+	if _, ok := counterVec.series[key]; !ok {
+		counterVec.series[key] = &series{labelValues: labelValues}
+	}
+	counterVec.values[key] += delta
+}
+
+// Inc increments the counter identified by the label values by one
+func (counterVec *CounterVec) Inc(labelValues ...string) {
+	counterVec.Add(1, labelValues...)
+}
+
+// Value gets the current value of the counter identified by the label values
+func (counterVec *CounterVec) Value(labelValues ...string) float64 {
+	counterVec.mutex.Lock()
+	defer counterVec.mutex.Unlock()
+	return counterVec.values[seriesKey(labelValues)]
+}
+
+func (counterVec *CounterVec) writeText(writer io.Writer) {
+	counterVec.mutex.Lock()
+	defer counterVec.mutex.Unlock()
+	writeHeader(writer, counterVec.metricName, counterVec.help, "counter")
+	for _, key := range sortedKeys(counterVec.series) {
+		labels := formatLabels(counterVec.labelNames, counterVec.series[key].labelValues)
+		io.WriteString(writer, counterVec.metricName+labels+" "+formatFloat(counterVec.values[key])+"\n")
+	}
+}
+
+/* **************************************************
+ * HISTOGRAM
+ * ************************************************** */
+
+// histogramValues holds the observations of a single histogram series
+type histogramValues struct {
+	bucketCounts []uint64
+	count        uint64
+	sum          float64
+}
+
+// HistogramVec is a family of histograms with shared bucket boundaries,
+// partitioned by label values
+type HistogramVec struct {
+	mutex      sync.Mutex
+	metricName string
+	help       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+	values     map[string]*histogramValues
+}
+
+// NewHistogramVec instantiates a HistogramVec with the given (ascending)
+// bucket upper bounds and adds it to the registry
+func (registry *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	histogramVec := new(HistogramVec)
+	histogramVec.metricName = name
+	histogramVec.help = help
+	histogramVec.labelNames = labelNames
+	histogramVec.buckets = buckets
+	histogramVec.series = make(map[string]*series)
+	histogramVec.values = make(map[string]*histogramValues)
+	registry.register(histogramVec)
+	return histogramVec
+}
+
+func (histogramVec *HistogramVec) name() string {
+	return histogramVec.metricName
+}
+
+// Observe records a single observation in the histogram identified by the
+// label values
+func (histogramVec *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+	histogramVec.mutex.Lock()
+	defer histogramVec.mutex.Unlock()
+	values, ok := histogramVec.values[key]
+	if !ok {
+		histogramVec.series[key] = &series{labelValues: labelValues}
+		values = &histogramValues{bucketCounts: make([]uint64, len(histogramVec.buckets))}
+		histogramVec.values[key] = values
+	}
+	for i, upperBound := range histogramVec.buckets {
+		if value <= upperBound {
+			values.bucketCounts[i]++
+		}
+	}
+	values.count++
+	values.sum += value
+}
+
+// Count gets the number of observations in the histogram identified by the
+// label values
+func (histogramVec *HistogramVec) Count(labelValues ...string) uint64 {
+	histogramVec.mutex.Lock()
+	defer histogramVec.mutex.Unlock()
+	values, ok := histogramVec.values[seriesKey(labelValues)]
+	if !ok {
+		return 0
+	}
+	return values.count
+}
+
+func (histogramVec *HistogramVec) writeText(writer io.Writer) {
+	histogramVec.mutex.Lock()
+	defer histogramVec.mutex.Unlock()
+	name := histogramVec.metricName
+	writeHeader(writer, name, histogramVec.help, "histogram")
+	for _, key := range sortedKeys(histogramVec.series) {
+		labelValues := histogramVec.series[key].labelValues
+		values := histogramVec.values[key]
+		for i, upperBound := range histogramVec.buckets {
+			labels := formatLabels(histogramVec.labelNames, labelValues, "le", formatFloat(upperBound))
+			io.WriteString(writer, name+"_bucket"+labels+" "+strconv.FormatUint(values.bucketCounts[i], 10)+"\n")
+		}
+		labels := formatLabels(histogramVec.labelNames, labelValues, "le", "+Inf")
+		io.WriteString(writer, name+"_bucket"+labels+" "+strconv.FormatUint(values.count, 10)+"\n")
+		labels = formatLabels(histogramVec.labelNames, labelValues)
+		io.WriteString(writer, name+"_sum"+labels+" "+formatFloat(values.sum)+"\n")
+		io.WriteString(writer, name+"_count"+labels+" "+strconv.FormatUint(values.count, 10)+"\n")
+	}
+}
@@ -0,0 +1,77 @@
+// Package htsmetrics collects service metrics and exposes them in the
+// Prometheus text exposition format
+//
+// Module registry_test tests module registry
+package htsmetrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var counterVecTC = []struct {
+	increments [][]string
+	exp        string
+}{
+	{
+		[][]string{},
+		"# HELP test_total help text\n" +
+			"# TYPE test_total counter\n",
+	},
+	{
+		[][]string{
+			{"/reads/{id}", "NotFound"},
+			{"/reads/{id}", "NotFound"},
+			{"/variants/{id}", "Invalid\"Input"},
+		},
+		"# HELP test_total help text\n" +
+			"# TYPE test_total counter\n" +
+			"test_total{endpoint=\"/reads/{id}\",error=\"NotFound\"} 2\n" +
+			"test_total{endpoint=\"/variants/{id}\",error=\"Invalid\\\"Input\"} 1\n",
+	},
+}
+
+var histogramVecTC = []struct {
+	observations []float64
+	exp          string
+}{
+	{
+		[]float64{0.5, 1, 3},
+		"# HELP test_seconds help text\n" +
+			"# TYPE test_seconds histogram\n" +
+			"test_seconds_bucket{tool=\"samtools\",le=\"1\"} 2\n" +
+			"test_seconds_bucket{tool=\"samtools\",le=\"2\"} 2\n" +
+			"test_seconds_bucket{tool=\"samtools\",le=\"+Inf\"} 3\n" +
+			"test_seconds_sum{tool=\"samtools\"} 4.5\n" +
+			"test_seconds_count{tool=\"samtools\"} 3\n",
+	},
+}
+
+func TestCounterVec(t *testing.T) {
+	for _, tc := range counterVecTC {
+		registry := NewRegistry()
+		counterVec := registry.NewCounterVec("test_total", "help text", "endpoint", "error")
+		for _, labelValues := range tc.increments {
+			counterVec.Inc(labelValues...)
+		}
+		buffer := new(bytes.Buffer)
+		registry.WriteText(buffer)
+		assert.Equal(t, tc.exp, buffer.String())
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	for _, tc := range histogramVecTC {
+		registry := NewRegistry()
+		histogramVec := registry.NewHistogramVec("test_seconds", "help text", []float64{1, 2}, "tool")
+		for _, observation := range tc.observations {
+			histogramVec.Observe(observation, "samtools")
+		}
+		buffer := new(bytes.Buffer)
+		registry.WriteText(buffer)
+		assert.Equal(t, tc.exp, buffer.String())
+		assert.Equal(t, uint64(len(tc.observations)), histogramVec.Count("samtools"))
+	}
+}
@@ -0,0 +1,348 @@
+// Package htsregion models the genomic regions requested from htsget
+// objects
+//
+// Module region.go contains genomic intervals, in the 0-based, half-open
+// coordinates of the htsget specification, their parsing from request
+// parameters, and their conversion to the region strings of htslib tools and
+// to the bins of BAI and tabix indexes
+package htsregion
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// binMaxPosition end of the coordinate space of the BAI and tabix binning
+// scheme (2^29)
+const binMaxPosition = 1 << 29
+
+// binLevels the levels of the BAI and tabix binning scheme, from the largest
+// bins to the smallest: the number of the first bin of each level, and the
+// width of its bins (2^shift)
+var binLevels = []struct {
+	offset int64
+	shift  uint
+}{
+	{1, 26}, {9, 23}, {73, 20}, {585, 17}, {4681, 14},
+}
+
+// Region a genomic interval on a reference sequence: Start is the 0-based
+// position of its first base, and End the position after its last base. a
+// region may be open at either end, extending to the start or the end of the
+// reference sequence
+//
+// Attributes
+//	Name (string): reference sequence name, * for the unplaced unmapped reads
+//	Start (int64): 0-based start, inclusive. 0 if the region is open at its start
+//	End (int64): 0-based end, exclusive. ignored if the region is open at its end
+//	OpenStart (bool): the region starts at the start of the reference sequence
+//	OpenEnd (bool): the region extends to the end of the reference sequence
+type Region struct {
+	Name      string
+	Start     int64
+	End       int64
+	OpenStart bool
+	OpenEnd   bool
+}
+
+// NewRegion instantiates a closed region
+//
+// Arguments
+//	name (string): reference sequence name
+//	start (int64): 0-based start, inclusive
+//	end (int64): 0-based end, exclusive
+// Returns
+//	(*Region): the region from start to end
+func NewRegion(name string, start int64, end int64) *Region {
+	return &Region{Name: name, Start: start, End: end}
+}
+
+// NewReferenceRegion instantiates a region spanning a whole reference
+// sequence
+//
+// Arguments
+//	name (string): reference sequence name
+// Returns
+//	(*Region): the region, open at both ends
+func NewReferenceRegion(name string) *Region {
+	return &Region{Name: name, OpenStart: true, OpenEnd: true}
+}
+
+// parseCoordinate parses the start or end parameter of a request as a
+// 0-based coordinate
+func parseCoordinate(param string, value string) (int64, error) {
+	coordinate, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New("'" + param + "' is not a valid integer")
+	}
+	if coordinate < 0 {
+		return 0, errors.New("'" + param + "' must be greater than or equal to zero")
+	}
+	return coordinate, nil
+}
+
+// NewRegionFromBounds instantiates a region from the referenceName, start and
+// end parameters of a request. the region is open at the start or end if the
+// parameter is not specified
+//
+// Arguments
+//	name (string): reference sequence name
+//	start (string): start parameter, empty if not specified
+//	end (string): end parameter, empty if not specified
+// Returns
+//	(*Region): the requested region
+//	(error): if not nil, start or end is not a coordinate, or end is not after start
+func NewRegionFromBounds(name string, start string, end string) (*Region, error) {
+	region := NewReferenceRegion(name)
+	var err error
+	if start != "" {
+		if region.Start, err = parseCoordinate("start", start); err != nil {
+			return nil, err
+		}
+		region.OpenStart = false
+	}
+	if end != "" {
+		if region.End, err = parseCoordinate("end", end); err != nil {
+			return nil, err
+		}
+		region.OpenEnd = false
+	}
+	if !region.OpenStart && !region.OpenEnd && region.Start >= region.End {
+		return nil, errors.New("'end' MUST be higher than 'start'")
+	}
+	return region, nil
+}
+
+// regionIntervalRegex matches the interval suffix of a region string, after
+// the last colon: a start, and an optional end
+var regionIntervalRegex = regexp.MustCompile("^(\\d+)-(\\d*)$")
+
+// ParseRegion parses a region string of the form name, name:start-end, or
+// name:start-, with start and end in the coordinates of the htsget start and
+// end parameters. a reference name may itself contain colons, the string is
+// only split at the last colon if an interval follows it
+//
+// Arguments
+//	regionString (string): region string
+// Returns
+//	(*Region): the parsed region
+//	(error): if not nil, the region string has no reference name, or an invalid interval
+func ParseRegion(regionString string) (*Region, error) {
+	region := NewReferenceRegion(regionString)
+	if i := strings.LastIndex(regionString, ":"); i >= 0 {
+		if submatches := regionIntervalRegex.FindStringSubmatch(regionString[i+1:]); submatches != nil {
+			region.Name = regionString[:i]
+			start, err := strconv.ParseInt(submatches[1], 10, 64)
+			if err != nil {
+				return nil, errors.New("region '" + regionString + "' has an invalid interval")
+			}
+			region.Start, region.OpenStart = start, false
+			if submatches[2] != "" {
+				end, err := strconv.ParseInt(submatches[2], 10, 64)
+				if err != nil {
+					return nil, errors.New("region '" + regionString + "' has an invalid interval")
+				}
+				if start >= end {
+					return nil, errors.New("region '" + regionString + "' does not end after its start")
+				}
+				region.End, region.OpenEnd = end, false
+			}
+		}
+	}
+	if region.Name == "" {
+		return nil, errors.New("region '" + regionString + "' has no reference name")
+	}
+	return region, nil
+}
+
+// ParseRegions parses a list of region strings, as ParseRegion does
+//
+// Arguments
+//	regionStrings ([]string): region strings
+// Returns
+//	([]*Region): parsed regions, in the given order
+//	(error): if not nil, a region string is malformed
+func ParseRegions(regionStrings []string) ([]*Region, error) {
+	regions := make([]*Region, 0, len(regionStrings))
+	for _, regionString := range regionStrings {
+		region, err := ParseRegion(regionString)
+		if err != nil {
+			return nil, err
+		}
+		regions = append(regions, region)
+	}
+	return regions, nil
+}
+
+// String gets a representation of a genomic region, as it is written in the
+// regions parameter: name, name:start-end, or name:start-
+func (r *Region) String() string {
+	if r.OpenStart && r.OpenEnd {
+		return r.Name
+	}
+	s := r.Name + ":" + strconv.FormatInt(r.Start, 10) + "-"
+	if !r.OpenEnd {
+		s += strconv.FormatInt(r.End, 10)
+	}
+	return s
+}
+
+// ExportHtslib exports the region as it is specified on the command-line of
+// htslib tools (samtools, bcftools), in 1-based, inclusive coordinates
+func (r *Region) ExportHtslib() string {
+	if r.OpenStart && r.OpenEnd {
+		return r.Name
+	}
+	s := r.Name + ":" + strconv.FormatInt(r.Start+1, 10) + "-"
+	if !r.OpenEnd {
+		s += strconv.FormatInt(r.End, 10)
+	}
+	return s
+}
+
+// Bounds gets the start and end of the region, closing an open end at the
+// length of the reference sequence
+//
+//	Type: Region
+// Arguments
+//	length (int64): length of the reference sequence
+// Returns
+//	(int64): 0-based start, inclusive
+//	(int64): 0-based end, exclusive
+func (r *Region) Bounds(length int64) (int64, int64) {
+	if r.OpenEnd {
+		return r.Start, length
+	}
+	return r.Start, r.End
+}
+
+// Overlaps checks whether two regions share at least one position. regions
+// on different reference sequences never overlap
+//
+//	Type: Region
+// Arguments
+//	other (*Region): region to compare with
+// Returns
+//	(bool): true if the regions overlap
+func (r *Region) Overlaps(other *Region) bool {
+	if r.Name != other.Name {
+		return false
+	}
+	return (r.OpenEnd || other.Start < r.End) && (other.OpenEnd || r.Start < other.End)
+}
+
+// Bin gets the smallest BAI/tabix bin containing the region, as computed by
+// the reg2bin function of the SAM specification
+//
+//	Type: Region
+// Returns
+//	(uint32): bin number
+func (r *Region) Bin() uint32 {
+	beg, end := r.Bounds(binMaxPosition)
+	if end > binMaxPosition {
+		end = binMaxPosition
+	}
+	if beg >= end {
+		return 0
+	}
+	end--
+	for i := len(binLevels) - 1; i >= 0; i-- {
+		level := binLevels[i]
+		if beg>>level.shift == end>>level.shift {
+			return uint32(level.offset + beg>>level.shift)
+		}
+	}
+	return 0
+}
+
+// Bins gets the BAI/tabix bins overlapping the region, as computed by the
+// reg2bins function of the SAM specification. reads or records overlapping
+// the region are found in these bins
+//
+//	Type: Region
+// Returns
+//	([]uint32): bin numbers, from the largest bin to the smallest
+func (r *Region) Bins() []uint32 {
+	beg, end := r.Bounds(binMaxPosition)
+	if end > binMaxPosition {
+		end = binMaxPosition
+	}
+	if beg >= end {
+		return []uint32{}
+	}
+	end--
+	bins := []uint32{0}
+	for _, level := range binLevels {
+		for k := level.offset + beg>>level.shift; k <= level.offset+end>>level.shift; k++ {
+			bins = append(bins, uint32(k))
+		}
+	}
+	return bins
+}
+
+// mergeable checks whether a region overlaps or abuts another region, which
+// does not start before it
+func (r *Region) mergeable(next *Region) bool {
+	return r.Name == next.Name && (r.OpenEnd || next.Start <= r.End)
+}
+
+// SortRegions sorts regions in the order of the reference sequences of the
+// object, then by start. unplaced unmapped reads (*) are sorted last
+//
+// Arguments
+//	regions ([]*Region): regions, sorted in place
+//	referenceNames ([]string): reference sequence names, in the order of the object header
+func SortRegions(regions []*Region, referenceNames []string) {
+	order := make(map[string]int)
+	for i, name := range referenceNames {
+		order[name] = i
+	}
+	rank := func(name string) int {
+		if i, ok := order[name]; ok {
+			return i
+		}
+		return len(referenceNames)
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		if regions[i].Name != regions[j].Name {
+			return rank(regions[i].Name) < rank(regions[j].Name)
+		}
+		return regions[i].Start < regions[j].Start
+	})
+}
+
+// MergeRegions sorts regions, as SortRegions does, and merges the regions of
+// a reference sequence that overlap or abut, so that no part of a reference
+// sequence is requested twice
+//
+// Arguments
+//	regions ([]*Region): regions, in any order
+//	referenceNames ([]string): reference sequence names, in the order of the object header
+// Returns
+//	([]*Region): sorted, non-overlapping regions
+func MergeRegions(regions []*Region, referenceNames []string) []*Region {
+	sorted := make([]*Region, len(regions))
+	copy(sorted, regions)
+	SortRegions(sorted, referenceNames)
+
+	merged := []*Region{}
+	for _, region := range sorted {
+		last := len(merged) - 1
+		if last >= 0 && merged[last].mergeable(region) {
+			previous := merged[last]
+			previous.OpenStart = previous.OpenStart || region.OpenStart
+			if region.OpenEnd {
+				previous.OpenEnd = true
+			} else if !previous.OpenEnd && region.End > previous.End {
+				previous.End = region.End
+			}
+			continue
+		}
+		copied := *region
+		merged = append(merged, &copied)
+	}
+	return merged
+}
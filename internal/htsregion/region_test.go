@@ -0,0 +1,211 @@
+// Package htsregion models the genomic regions requested from htsget
+// objects
+//
+// Module region_test tests region
+package htsregion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var regionExportTC = []struct {
+	region            *Region
+	expString, expHts string
+}{
+	{NewReferenceRegion("chr10"), "chr10", "chr10"},
+	{&Region{Name: "chr22", Start: 100, OpenEnd: true}, "chr22:100-", "chr22:101-"},
+	{&Region{Name: "chr5", End: 250000, OpenStart: true}, "chr5:0-250000", "chr5:1-250000"},
+	{NewRegion("chr1", 0, 100), "chr1:0-100", "chr1:1-100"},
+	{NewRegion("HLA-A*01:01", 9, 10), "HLA-A*01:01:9-10", "HLA-A*01:01:10-10"},
+}
+
+func TestString(t *testing.T) {
+	for _, tc := range regionExportTC {
+		assert.Equal(t, tc.expString, tc.region.String())
+	}
+}
+
+func TestExportHtslib(t *testing.T) {
+	for _, tc := range regionExportTC {
+		assert.Equal(t, tc.expHts, tc.region.ExportHtslib())
+	}
+}
+
+var newRegionFromBoundsTC = []struct {
+	referenceName, start, end string
+	exp                       *Region
+	expErr                    string
+}{
+	{"chr1", "", "", NewReferenceRegion("chr1"), ""},
+	{"chr1", "100", "", &Region{Name: "chr1", Start: 100, OpenEnd: true}, ""},
+	{"chr1", "", "200", &Region{Name: "chr1", End: 200, OpenStart: true}, ""},
+	{"chr1", "", "0", &Region{Name: "chr1", End: 0, OpenStart: true}, ""},
+	{"chr1", "100", "200", NewRegion("chr1", 100, 200), ""},
+	{"chr1", "-1", "", nil, "'start' must be greater than or equal to zero"},
+	{"chr1", "a", "", nil, "'start' is not a valid integer"},
+	{"chr1", "", "99999999999999999999", nil, "'end' is not a valid integer"},
+	{"chr1", "200", "200", nil, "'end' MUST be higher than 'start'"},
+}
+
+func TestNewRegionFromBounds(t *testing.T) {
+	for _, tc := range newRegionFromBoundsTC {
+		region, err := NewRegionFromBounds(tc.referenceName, tc.start, tc.end)
+		assert.Equal(t, tc.exp, region, tc.start+"-"+tc.end)
+		if tc.expErr == "" {
+			assert.Nil(t, err)
+		} else {
+			assert.EqualError(t, err, tc.expErr)
+		}
+	}
+}
+
+var parseRegionTC = []struct {
+	region    string
+	exp       *Region
+	expErrNil bool
+}{
+	{"chr1", NewReferenceRegion("chr1"), true},
+	{"chr1:100-200", NewRegion("chr1", 100, 200), true},
+	{"chr1:100-", &Region{Name: "chr1", Start: 100, OpenEnd: true}, true},
+	{"HLA-A*01:01:01:01", NewReferenceRegion("HLA-A*01:01:01:01"), true},
+	{"HLA-A*01:01:01:01:0-500", NewRegion("HLA-A*01:01:01:01", 0, 500), true},
+	{"chr1:200-100", nil, false},
+	{"chr1:100-100", nil, false},
+	{"chr1:0-99999999999999999999", nil, false},
+	{":100-200", nil, false},
+	{"", nil, false},
+}
+
+func TestParseRegion(t *testing.T) {
+	for _, tc := range parseRegionTC {
+		region, err := ParseRegion(tc.region)
+		assert.Equal(t, tc.exp, region, tc.region)
+		assert.Equal(t, tc.expErrNil, err == nil, tc.region)
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	regions, err := ParseRegions([]string{"chr2:5000-6000", "chr1", "chr1:100-"})
+	assert.Nil(t, err)
+	assert.Equal(t, []*Region{
+		NewRegion("chr2", 5000, 6000),
+		NewReferenceRegion("chr1"),
+		{Name: "chr1", Start: 100, OpenEnd: true},
+	}, regions)
+
+	_, err = ParseRegions([]string{"chr1:100-200", "chr1:300-200"})
+	assert.NotNil(t, err)
+}
+
+var regionBoundsTC = []struct {
+	region           *Region
+	expStart, expEnd int64
+}{
+	{NewReferenceRegion("chr1"), 0, 1000},
+	{&Region{Name: "chr1", Start: 100, OpenEnd: true}, 100, 1000},
+	{&Region{Name: "chr1", End: 200, OpenStart: true}, 0, 200},
+	{NewRegion("chr1", 100, 200), 100, 200},
+}
+
+func TestRegionBounds(t *testing.T) {
+	for _, tc := range regionBoundsTC {
+		start, end := tc.region.Bounds(1000)
+		assert.Equal(t, tc.expStart, start, tc.region.String())
+		assert.Equal(t, tc.expEnd, end, tc.region.String())
+	}
+}
+
+var regionBinTC = []struct {
+	region  *Region
+	expBin  uint32
+	expBins []uint32
+}{
+	{NewRegion("chr1", 0, 1), 4681, []uint32{0, 1, 9, 73, 585, 4681}},
+	{NewRegion("chr1", 100, 16384), 4681, []uint32{0, 1, 9, 73, 585, 4681}},
+	{NewRegion("chr1", 16384, 16385), 4682, []uint32{0, 1, 9, 73, 585, 4682}},
+	{NewRegion("chr1", 16383, 16385), 585, []uint32{0, 1, 9, 73, 585, 4681, 4682}},
+	{NewRegion("chr1", 131071, 131073), 73, []uint32{0, 1, 9, 73, 585, 586, 4688, 4689}},
+	{NewRegion("chr1", 1<<26, (1<<26)+1), 4681 + 4096, []uint32{0, 2, 17, 137, 1097, 8777}},
+	{&Region{Name: "chr1", Start: (1 << 29) - 1, OpenEnd: true}, 4681 + 32767, []uint32{0, 8, 72, 584, 4680, 37448}},
+	{NewReferenceRegion("chr1"), 0, nil},
+	{&Region{Name: "chr1", Start: 1 << 29, OpenEnd: true}, 0, []uint32{}},
+}
+
+func TestRegionBin(t *testing.T) {
+	for _, tc := range regionBinTC {
+		assert.Equal(t, tc.expBin, tc.region.Bin(), tc.region.String())
+		if tc.expBins != nil {
+			assert.Equal(t, tc.expBins, tc.region.Bins(), tc.region.String())
+		}
+	}
+	// a whole reference sequence overlaps every bin
+	assert.Equal(t, 37449, len(NewReferenceRegion("chr1").Bins()))
+}
+
+var regionOverlapsTC = []struct {
+	a, b *Region
+	exp  bool
+}{
+	{NewRegion("chr1", 100, 200), NewRegion("chr1", 150, 250), true},
+	{NewRegion("chr1", 100, 200), NewRegion("chr1", 199, 200), true},
+	{NewRegion("chr1", 100, 200), NewRegion("chr1", 200, 300), false},
+	{NewRegion("chr1", 100, 200), NewRegion("chr1", 0, 100), false},
+	{NewRegion("chr1", 100, 200), NewRegion("chr2", 100, 200), false},
+	{NewRegion("chr1", 100, 200), NewReferenceRegion("chr1"), true},
+	{&Region{Name: "chr1", Start: 500, OpenEnd: true}, NewRegion("chr1", 100, 200), false},
+	{&Region{Name: "chr1", Start: 500, OpenEnd: true}, &Region{Name: "chr1", Start: 900, OpenEnd: true}, true},
+}
+
+func TestRegionOverlaps(t *testing.T) {
+	for _, tc := range regionOverlapsTC {
+		assert.Equal(t, tc.exp, tc.a.Overlaps(tc.b), tc.a.String()+" "+tc.b.String())
+		assert.Equal(t, tc.exp, tc.b.Overlaps(tc.a), tc.b.String()+" "+tc.a.String())
+	}
+}
+
+var mergeRegionsTC = []struct {
+	name       string
+	regions    []string
+	expRegions []string
+}{
+	{
+		"sorted by reference sequence order, then start",
+		[]string{"chr2:10-20", "chr10:5-6", "chr1:500-600", "chr1:100-200"},
+		[]string{"chr1:100-200", "chr1:500-600", "chr2:10-20", "chr10:5-6"},
+	},
+	{
+		"overlapping and abutting regions",
+		[]string{"chr1:150-300", "chr1:100-200", "chr1:300-400", "chr1:120-130"},
+		[]string{"chr1:100-400"},
+	},
+	{
+		"open ended and whole regions",
+		[]string{"chr1:500-", "chr1:100-200", "chr1:600-700", "chr2:0-10", "chr2"},
+		[]string{"chr1:100-200", "chr1:500-", "chr2"},
+	},
+	{
+		"duplicates and unplaced unmapped reads",
+		[]string{"*", "chr2:5-10", "*", "chr2:5-10"},
+		[]string{"chr2:5-10", "*"},
+	},
+}
+
+func TestMergeRegions(t *testing.T) {
+	referenceNames := []string{"chr1", "chr2", "chr10"}
+	for _, tc := range mergeRegionsTC {
+		regions, err := ParseRegions(tc.regions)
+		assert.Nil(t, err, tc.name)
+		merged := []string{}
+		for _, region := range MergeRegions(regions, referenceNames) {
+			merged = append(merged, region.String())
+		}
+		assert.Equal(t, tc.expRegions, merged, tc.name)
+	}
+
+	// the regions passed are left unchanged
+	regions := []*Region{NewRegion("chr1", 100, 200), NewRegion("chr1", 150, 300)}
+	MergeRegions(regions, referenceNames)
+	assert.Equal(t, int64(200), regions[0].End)
+}
@@ -5,21 +5,36 @@
 // Module defaults.go contains default values for each parameter
 package htsrequest
 
+import (
+	"strconv"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+)
+
 // defaultScalarParameterValues (map[string]string): values for scalar params
 // if param is not specified in request
 var defaultScalarParameterValues = map[string]string{
-	"id":            "",
-	"format":        "BAM",
-	"class":         "",
-	"referenceName": "",
-	"start":         "-1",
-	"end":           "-1",
+	"id":                "",
+	"format":            "BAM",
+	"class":             "",
+	"referenceName":     "",
+	"start":             "",
+	"end":               "",
+	"prefix":            "",
+	"pageSize":          strconv.Itoa(htsconstants.DfltListPageSize),
+	"pageToken":         "",
+	"requiredFlags":     "0",
+	"excludedFlags":     "0",
+	"minMappingQuality": "0",
 }
 
 // defaultListParameterValues (map[string][]string): values for list params
 // if param is not specified in request
 var defaultListParameterValues = map[string][]string{
-	"fields": {"ALL"},
-	"tags":   {"ALL"},
-	"notags": {"NONE"},
+	"fields":     {"ALL"},
+	"tags":       {"ALL"},
+	"notags":     {"NONE"},
+	"samples":    {"ALL"},
+	"readGroups": {"ALL"},
+	"regions":    {"ALL"},
 }
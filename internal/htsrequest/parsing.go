@@ -38,39 +38,63 @@ const (
 // paramLocations (map[string]ParamLoc): indicates whether each htsget parameter
 // is found on the url path, query string, or header
 var paramLocations = map[string]ParamLoc{
-	"id":               ParamLocPath,
-	"format":           ParamLocQuery,
-	"class":            ParamLocQuery,
-	"referenceName":    ParamLocQuery,
-	"start":            ParamLocQuery,
-	"end":              ParamLocQuery,
-	"fields":           ParamLocQuery,
-	"tags":             ParamLocQuery,
-	"notags":           ParamLocQuery,
-	"HtsgetBlockClass": ParamLocHeader,
-	"HtsgetBlockId":    ParamLocHeader,
-	"HtsgetNumBlocks":  ParamLocHeader,
-	"HtsgetFilePath":   ParamLocHeader,
-	"Range":            ParamLocHeader,
+	"id":                ParamLocPath,
+	"format":            ParamLocQuery,
+	"class":             ParamLocQuery,
+	"referenceName":     ParamLocQuery,
+	"start":             ParamLocQuery,
+	"end":               ParamLocQuery,
+	"fields":            ParamLocQuery,
+	"tags":              ParamLocQuery,
+	"notags":            ParamLocQuery,
+	"samples":           ParamLocQuery,
+	"regions":           ParamLocQuery,
+	"requiredFlags":     ParamLocQuery,
+	"excludedFlags":     ParamLocQuery,
+	"minMappingQuality": ParamLocQuery,
+	"readGroups":        ParamLocQuery,
+	"HtsgetBlockClass":  ParamLocHeader,
+	"HtsgetBlockId":     ParamLocHeader,
+	"HtsgetNumBlocks":   ParamLocHeader,
+	"HtsgetBlockSplit":  ParamLocHeader,
+	"HtsgetPrevRegion":  ParamLocHeader,
+	"HtsgetFilePath":    ParamLocHeader,
+	"HtsgetObjectId":    ParamLocHeader,
+	"Range":             ParamLocHeader,
+	"prefix":            ParamLocQuery,
+	"pageSize":          ParamLocQuery,
+	"pageToken":         ParamLocQuery,
 }
 
 // paramTypes (map[string]ParamType): indicates whether each htsget parameter is
 // expected to contain a scalar or list value
 var paramTypes = map[string]ParamType{
-	"id":               ParamTypeScalar,
-	"format":           ParamTypeScalar,
-	"class":            ParamTypeScalar,
-	"referenceName":    ParamTypeScalar,
-	"start":            ParamTypeScalar,
-	"end":              ParamTypeScalar,
-	"fields":           ParamTypeList,
-	"tags":             ParamTypeList,
-	"notags":           ParamTypeList,
-	"HtsgetBlockClass": ParamTypeScalar,
-	"HtsgetBlockId":    ParamTypeScalar,
-	"HtsgetNumBlocks":  ParamTypeScalar,
-	"HtsgetFilePath":   ParamTypeScalar,
-	"Range":            ParamTypeScalar,
+	"id":                ParamTypeScalar,
+	"format":            ParamTypeScalar,
+	"class":             ParamTypeScalar,
+	"referenceName":     ParamTypeScalar,
+	"start":             ParamTypeScalar,
+	"end":               ParamTypeScalar,
+	"fields":            ParamTypeList,
+	"tags":              ParamTypeList,
+	"notags":            ParamTypeList,
+	"samples":           ParamTypeList,
+	"regions":           ParamTypeList,
+	"requiredFlags":     ParamTypeScalar,
+	"excludedFlags":     ParamTypeScalar,
+	"minMappingQuality": ParamTypeScalar,
+	"readGroups":        ParamTypeList,
+	"HtsgetBlockClass":  ParamTypeScalar,
+	"HtsgetBlockId":     ParamTypeScalar,
+	"HtsgetNumBlocks":   ParamTypeScalar,
+	"HtsgetBlockSplit":  ParamTypeScalar,
+	"HtsgetPrevRegion":  ParamTypeScalar,
+	"HtsgetFilePath":    ParamTypeScalar,
+	"HtsgetObjectId":    ParamTypeScalar,
+	"Range":             ParamTypeScalar,
+	"prefix":            ParamTypeScalar,
+	"pageSize":          ParamTypeScalar,
+	"pageToken":         ParamTypeScalar,
 }
 
 // parsePathParam parses a single url path parameter as a string
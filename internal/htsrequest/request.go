@@ -8,9 +8,13 @@
 package htsrequest
 
 import (
+	"context"
 	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
@@ -24,9 +28,11 @@ import (
 //	ScalarParams (map[string]string): map holding scalar parameter values
 //	ListParams (map[string][]string): map holding list parameter values
 type HtsgetRequest struct {
-	endpoint     htsconstants.APIEndpoint
-	ScalarParams map[string]string
-	ListParams   map[string][]string
+	endpoint      htsconstants.APIEndpoint
+	ctx           context.Context
+	clientSubject string
+	ScalarParams  map[string]string
+	ListParams    map[string][]string
 }
 
 // NewHtsgetRequest instantiates a new HtsgetRequest struct instance
@@ -48,6 +54,49 @@ func (htsgetReq *HtsgetRequest) GetEndpoint() htsconstants.APIEndpoint {
 	return htsgetReq.endpoint
 }
 
+// SetContext sets the context of the HTTP request the htsget request was
+// parsed from
+//
+// Type: HtsgetRequest
+// Arguments
+//	ctx (context.Context): request-scoped context
+func (htsgetReq *HtsgetRequest) SetContext(ctx context.Context) {
+	htsgetReq.ctx = ctx
+}
+
+// Context gets the context of the HTTP request the htsget request was parsed
+// from, carrying the current trace span
+//
+// Type: HtsgetRequest
+// Returns
+//	(context.Context): request-scoped context, background context if unset
+func (htsgetReq *HtsgetRequest) Context() context.Context {
+	if htsgetReq.ctx == nil {
+		return context.Background()
+	}
+	return htsgetReq.ctx
+}
+
+// SetClientSubject sets the subject of the verified client certificate the
+// request was made with over mutual TLS
+//
+// Type: HtsgetRequest
+// Arguments
+//	clientSubject (string): distinguished name of the client certificate subject
+func (htsgetReq *HtsgetRequest) SetClientSubject(clientSubject string) {
+	htsgetReq.clientSubject = clientSubject
+}
+
+// ClientSubject gets the subject of the verified client certificate the
+// request was made with, identifying the principal for authorization
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): client certificate subject distinguished name, empty if no verified client certificate was presented
+func (htsgetReq *HtsgetRequest) ClientSubject() string {
+	return htsgetReq.clientSubject
+}
+
 // AddScalarParam adds a key-value pair to HtsgetRequest scalar parameter map
 //
 // Type: HtsgetRequest
@@ -144,6 +193,17 @@ func (htsgetReq *HtsgetRequest) End() string {
 	return htsgetReq.get("end")
 }
 
+// Region gets the region requested by the 'referenceName', 'start' and 'end'
+// params, open at the start or end if they are not specified
+//
+// Type: HtsgetRequest
+// Returns
+//	(*htsregion.Region): the requested region
+//	(error): if not nil, 'start' or 'end' is not a valid coordinate
+func (htsgetReq *HtsgetRequest) Region() (*htsregion.Region, error) {
+	return htsregion.NewRegionFromBounds(htsgetReq.ReferenceName(), htsgetReq.Start(), htsgetReq.End())
+}
+
 // HtsgetBlockClass gets value of 'HtsgetBlockClass' header param
 //
 // Type: HtsgetRequest
@@ -171,14 +231,75 @@ func (htsgetReq *HtsgetRequest) HtsgetNumBlocks() string {
 	return htsgetReq.get("HtsgetNumBlocks")
 }
 
+// HtsgetBlockSplit indicates whether the block continues a region split
+// across several body blocks, in which case reads starting before the start
+// of the block's region are served by an earlier block
+//
+// Type: HtsgetRequest
+// Returns
+//	(bool): true if 'HtsgetBlockSplit' header param is "true"
+func (htsgetReq *HtsgetRequest) HtsgetBlockSplit() bool {
+	return htsgetReq.get("HtsgetBlockSplit") == "true"
+}
+
+// HtsgetPrevRegion gets the region served by an earlier body block on
+// the same reference sequence, in which case reads overlapping that region
+// are served by the earlier block
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): value of 'HtsgetPrevRegion' header param, empty if no earlier block
+func (htsgetReq *HtsgetRequest) HtsgetPrevRegion() string {
+	return htsgetReq.get("HtsgetPrevRegion")
+}
+
 func (htsgetReq *HtsgetRequest) HtsgetFilePath() string {
 	return htsgetReq.get("HtsgetFilePath")
 }
 
+// HtsgetObjectID gets the id of the object the file path of a file bytes
+// request was resolved from
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): value of 'HtsgetObjectId' header param
+func (htsgetReq *HtsgetRequest) HtsgetObjectID() string {
+	return htsgetReq.get("HtsgetObjectId")
+}
+
 func (htsgetReq *HtsgetRequest) Range() string {
 	return htsgetReq.get("Range")
 }
 
+// Prefix gets value of 'prefix' param, the prefix listed ids must start with
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): value of 'prefix'
+func (htsgetReq *HtsgetRequest) Prefix() string {
+	return htsgetReq.get("prefix")
+}
+
+// PageSize gets value of 'pageSize' param, the maximum number of ids listed
+//
+// Type: HtsgetRequest
+// Returns
+//	(int): value of 'pageSize'
+func (htsgetReq *HtsgetRequest) PageSize() int {
+	pageSize, _ := strconv.Atoi(htsgetReq.get("pageSize"))
+	return pageSize
+}
+
+// PageToken gets value of 'pageToken' param, the token returned by the
+// listing request for the previous page
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): value of 'pageToken', empty if the first page is requested
+func (htsgetReq *HtsgetRequest) PageToken() string {
+	return htsgetReq.get("pageToken")
+}
+
 // Fields gets value of 'fields' param
 //
 // Type: HtsgetRequest
@@ -206,6 +327,68 @@ func (htsgetReq *HtsgetRequest) NoTags() []string {
 	return htsgetReq.getList("notags")
 }
 
+// Samples gets value of 'samples' param, the samples to subset variants to
+//
+// Type: HtsgetRequest
+// Returns
+//	([]string): value of 'samples'
+func (htsgetReq *HtsgetRequest) Samples() []string {
+	return htsgetReq.getList("samples")
+}
+
+// RequiredFlags gets value of 'requiredFlags' param, the FLAG bits every
+// returned read must have set
+//
+// Type: HtsgetRequest
+// Returns
+//	(int): value of 'requiredFlags', 0 if not specified
+func (htsgetReq *HtsgetRequest) RequiredFlags() int {
+	flags, _ := parseFlags(htsgetReq.get("requiredFlags"))
+	return flags
+}
+
+// ExcludedFlags gets value of 'excludedFlags' param, the FLAG bits no
+// returned read may have set
+//
+// Type: HtsgetRequest
+// Returns
+//	(int): value of 'excludedFlags', 0 if not specified
+func (htsgetReq *HtsgetRequest) ExcludedFlags() int {
+	flags, _ := parseFlags(htsgetReq.get("excludedFlags"))
+	return flags
+}
+
+// MinMappingQuality gets value of 'minMappingQuality' param, the lowest
+// mapping quality (MAPQ) of returned reads
+//
+// Type: HtsgetRequest
+// Returns
+//	(int): value of 'minMappingQuality', 0 if not specified
+func (htsgetReq *HtsgetRequest) MinMappingQuality() int {
+	minMappingQuality, _ := strconv.Atoi(htsgetReq.get("minMappingQuality"))
+	return minMappingQuality
+}
+
+// ReadGroups gets value of 'readGroups' param, the ids of the read groups
+// returned reads belong to
+//
+// Type: HtsgetRequest
+// Returns
+//	([]string): value of 'readGroups'
+func (htsgetReq *HtsgetRequest) ReadGroups() []string {
+	return htsgetReq.getList("readGroups")
+}
+
+// Regions gets value of 'regions' param, the region strings of a multi-region
+// request
+//
+// Type: HtsgetRequest
+// Returns
+//	([]string): value of 'regions'
+func (htsgetReq *HtsgetRequest) Regions() []string {
+	return htsgetReq.getList("regions")
+}
+
 // isDefaultScalar checks if a scalar parameter value matches the default,
 // unspecfied value, thereby indicating that the parameter was not specified
 // in the HTTP request
@@ -273,14 +456,24 @@ func (htsgetReq *HtsgetRequest) EndRequested() bool {
 	return !htsgetReq.isDefaultScalar("end")
 }
 
+// RegionsRequested checks if the client requested a list of regions with the
+// regions parameter
+//
+// Type: HtsgetRequest
+// Returns
+//	(bool): true if the regions parameter was specified in request, false if not
+func (htsgetReq *HtsgetRequest) RegionsRequested() bool {
+	return len(htsgetReq.Regions()) > 0 && !htsgetReq.isDefaultList("regions")
+}
+
 // AllRegionsRequested checks if the client request is for all chromosomal
-// regions in the file (ie. referenceName not specified)
+// regions in the file (ie. neither referenceName nor regions specified)
 //
 // Type: HtsgetRequest
 // Returns
 //	(bool): true if all chromosomal regions requested
 func (htsgetReq *HtsgetRequest) AllRegionsRequested() bool {
-	return htsgetReq.isDefaultScalar("referenceName")
+	return htsgetReq.isDefaultScalar("referenceName") && !htsgetReq.RegionsRequested()
 }
 
 // AllFieldsRequested checks if all fields were requested by the client. all
@@ -323,6 +516,43 @@ func (htsgetReq *HtsgetRequest) AllTagsRequested() bool {
 	return htsgetReq.TagsNotSpecified() && htsgetReq.NoTagsNotSpecified()
 }
 
+// AllSamplesRequested checks if all samples were requested by the client, that
+// is, if the samples parameter was not specified. on reads requests, samples
+// filters reads by the sample (SM) of their read group
+//
+// Type: HtsgetRequest
+// Returns
+//	(bool): true if the samples parameter was not specified in request, false if not
+func (htsgetReq *HtsgetRequest) AllSamplesRequested() bool {
+	return len(htsgetReq.Samples()) == 0 || htsgetReq.isDefaultList("samples")
+}
+
+// AllReadGroupsRequested checks if reads of all read groups were requested by
+// the client, that is, if the readGroups parameter was not specified
+//
+// Type: HtsgetRequest
+// Returns
+//	(bool): true if the readGroups parameter was not specified in request, false if not
+func (htsgetReq *HtsgetRequest) AllReadGroupsRequested() bool {
+	return len(htsgetReq.ReadGroups()) == 0 || htsgetReq.isDefaultList("readGroups")
+}
+
+// AllReadsRequested checks if the client requested all reads of the
+// requested region, that is, if none of the read filtering parameters
+// (requiredFlags, excludedFlags, minMappingQuality, readGroups, samples) was
+// specified
+//
+// Type: HtsgetRequest
+// Returns
+//	(bool): true if reads are not filtered, false if not
+func (htsgetReq *HtsgetRequest) AllReadsRequested() bool {
+	return htsgetReq.RequiredFlags() == 0 &&
+		htsgetReq.ExcludedFlags() == 0 &&
+		htsgetReq.MinMappingQuality() == 0 &&
+		htsgetReq.AllReadGroupsRequested() &&
+		htsgetReq.AllSamplesRequested()
+}
+
 // ConstructDataEndpointURL for a given htsget request object, return the url
 // that will redirect the client to the correct data download endpoint with
 // all necessary parameters and headers provided
@@ -360,10 +590,47 @@ func (htsgetReq *HtsgetRequest) ConstructDataEndpointURL() (*url.URL, error) {
 		nt := strings.Join(htsgetReq.NoTags(), ",")
 		query.Set("notags", nt)
 	}
+	if !htsgetReq.AllSamplesRequested() {
+		query.Set("samples", strings.Join(htsgetReq.Samples(), ","))
+	}
+	if htsgetReq.RequiredFlags() != 0 {
+		query.Set("requiredFlags", strconv.Itoa(htsgetReq.RequiredFlags()))
+	}
+	if htsgetReq.ExcludedFlags() != 0 {
+		query.Set("excludedFlags", strconv.Itoa(htsgetReq.ExcludedFlags()))
+	}
+	if htsgetReq.MinMappingQuality() != 0 {
+		query.Set("minMappingQuality", strconv.Itoa(htsgetReq.MinMappingQuality()))
+	}
+	if !htsgetReq.AllReadGroupsRequested() {
+		query.Set("readGroups", strings.Join(htsgetReq.ReadGroups(), ","))
+	}
 	dataEndpoint.RawQuery = query.Encode()
 	return dataEndpoint, nil
 }
 
+// GetObjectPath resolves the requested id to the path or url of the object,
+// using the endpoint's data source registry
+//
+// Type: HtsgetRequest
+// Returns
+//	(string): location of the requested object
+//	(error): if not nil, the id could not be resolved to a location
+func (htsgetReq *HtsgetRequest) GetObjectPath() (string, error) {
+	return resolveObjectPath(htsgetReq.Context(), htsgetReq.GetEndpoint(), htsgetReq.ID())
+}
+
+// resolveObjectPath resolves an id to the path or url of the object, as a
+// traced operation
+func resolveObjectPath(ctx context.Context, endpoint htsconstants.APIEndpoint, id string) (string, error) {
+	_, span := htstrace.Start(ctx, "resolve data source", htstrace.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute("htsget.id", id)
+	path, err := htsconfig.GetObjectPath(endpoint, id)
+	span.SetError(err)
+	return path, err
+}
+
 func (htsgetReq *HtsgetRequest) GetDataSourceRegistry() *htsconfig.DataSourceRegistry {
 	return htsconfig.GetDataSourceRegistry(htsgetReq.GetEndpoint())
 }
@@ -117,6 +117,30 @@ var requestNoTagsTC = []struct {
 	{[]string{"NM", "NZ", "MD", "QL"}},
 }
 
+var requestAllSamplesRequestedTC = []struct {
+	samples []string
+	exp     bool
+}{
+	{nil, true},
+	{[]string{"ALL"}, true},
+	{[]string{"HG00096"}, false},
+	{[]string{"HG00096", "HG00097"}, false},
+}
+
+var requestAllReadsRequestedTC = []struct {
+	scalarParams map[string]string
+	listParams   map[string][]string
+	exp          bool
+}{
+	{nil, nil, true},
+	{defaultScalarParameterValues, defaultListParameterValues, true},
+	{map[string]string{"requiredFlags": "0x2"}, nil, false},
+	{map[string]string{"excludedFlags": "1024"}, nil, false},
+	{map[string]string{"minMappingQuality": "20"}, nil, false},
+	{nil, map[string][]string{"readGroups": {"RG1"}}, false},
+	{nil, map[string][]string{"samples": {"NA12878"}}, false},
+}
+
 var requestHeaderOnlyRequestedTC = []struct {
 	class string
 	exp   bool
@@ -149,7 +173,7 @@ var requestStartRequestedTC = []struct {
 	start string
 	exp   bool
 }{
-	{"-1", false},
+	{"", false},
 	{"100", true},
 	{"20000000", true},
 }
@@ -158,18 +182,21 @@ var requestEndRequestedTC = []struct {
 	end string
 	exp bool
 }{
-	{"-1", false},
+	{"", false},
 	{"100", true},
 	{"20000000", true},
 }
 
 var requestAllRegionsRequestedTC = []struct {
 	referenceName string
+	regions       []string
 	exp           bool
 }{
-	{"", true},
-	{"chr1", false},
-	{"chr22", false},
+	{"", nil, true},
+	{"chr1", nil, false},
+	{"chr22", nil, false},
+	{"", []string{"ALL"}, true},
+	{"", []string{"chr1:100-200", "chr2"}, false},
 }
 
 var requestAllFieldsRequestedTC = []struct {
@@ -194,7 +221,7 @@ var requestAllTagsRequestedTC = []struct {
 var requestConstructDataEndpointURLTC = []struct {
 	endpoint                             htsconstants.APIEndpoint
 	id, class, referenceName, start, end string
-	fields, tags, notags                 []string
+	fields, tags, notags, samples        []string
 	exp                                  string
 }{
 	{
@@ -207,6 +234,7 @@ var requestConstructDataEndpointURLTC = []struct {
 		defaultListParameterValues["fields"],
 		defaultListParameterValues["tags"],
 		defaultListParameterValues["notags"],
+		nil,
 		"http://localhost:3000/reads/data/object0052?end=420000&referenceName=chr1&start=65000",
 	},
 	{
@@ -219,6 +247,7 @@ var requestConstructDataEndpointURLTC = []struct {
 		[]string{"SEQ", "QUAL"},
 		[]string{"NM", "HI"},
 		defaultListParameterValues["notags"],
+		nil,
 		"http://localhost:3000/reads/data/tabulamuris.00001?end=45000000&fields=SEQ%2CQUAL&referenceName=chr22&start=11000000&tags=NM%2CHI",
 	},
 	{
@@ -231,6 +260,7 @@ var requestConstructDataEndpointURLTC = []struct {
 		defaultListParameterValues["fields"],
 		defaultListParameterValues["tags"],
 		defaultListParameterValues["notags"],
+		nil,
 		"http://localhost:3000/reads/data/tabulamuris.00001?class=header",
 	},
 	{
@@ -243,8 +273,22 @@ var requestConstructDataEndpointURLTC = []struct {
 		defaultListParameterValues["fields"],
 		defaultListParameterValues["tags"],
 		[]string{"NM", "HI"},
+		nil,
 		"http://localhost:3000/reads/data/tabulamuris.00001?notags=NM%2CHI",
 	},
+	{
+		htsconstants.APIEndpointVariantsTicket,
+		"1000genomes.00001",
+		defaultScalarParameterValues["class"],
+		"20",
+		defaultScalarParameterValues["start"],
+		defaultScalarParameterValues["end"],
+		defaultListParameterValues["fields"],
+		[]string{"FORMAT/GT"},
+		defaultListParameterValues["notags"],
+		[]string{"HG00096", "HG00097"},
+		"http://localhost:3000/variants/data/1000genomes.00001?referenceName=20&samples=HG00096%2CHG00097&tags=FORMAT%2FGT",
+	},
 }
 
 var requestDataSourceRegistryTC = []struct {
@@ -384,6 +428,41 @@ func TestRequestNoTags(t *testing.T) {
 	}
 }
 
+func TestRequestAllSamplesRequested(t *testing.T) {
+	for _, tc := range requestAllSamplesRequestedTC {
+		r := NewHtsgetRequest()
+		if tc.samples != nil {
+			r.AddListParam("samples", tc.samples)
+			assert.Equal(t, tc.samples, r.Samples())
+		}
+		assert.Equal(t, tc.exp, r.AllSamplesRequested())
+	}
+}
+
+func TestRequestAllReadsRequested(t *testing.T) {
+	for _, tc := range requestAllReadsRequestedTC {
+		r := NewHtsgetRequest()
+		for key, value := range tc.scalarParams {
+			r.AddScalarParam(key, value)
+		}
+		for key, value := range tc.listParams {
+			r.AddListParam(key, value)
+		}
+		assert.Equal(t, tc.exp, r.AllReadsRequested())
+	}
+
+	r := NewHtsgetRequest()
+	r.AddScalarParam("requiredFlags", "0x42")
+	r.AddScalarParam("excludedFlags", "1024")
+	r.AddScalarParam("minMappingQuality", "30")
+	r.AddListParam("readGroups", []string{"RG1", "RG2"})
+	assert.Equal(t, 0x42, r.RequiredFlags())
+	assert.Equal(t, 1024, r.ExcludedFlags())
+	assert.Equal(t, 30, r.MinMappingQuality())
+	assert.Equal(t, []string{"RG1", "RG2"}, r.ReadGroups())
+	assert.False(t, r.AllReadGroupsRequested())
+}
+
 func TestRequestHeaderOnlyRequested(t *testing.T) {
 	for _, tc := range requestHeaderOnlyRequestedTC {
 		r := NewHtsgetRequest()
@@ -428,6 +507,10 @@ func TestRequestAllRegionsRequested(t *testing.T) {
 	for _, tc := range requestAllRegionsRequestedTC {
 		r := NewHtsgetRequest()
 		r.AddScalarParam("referenceName", tc.referenceName)
+		if tc.regions != nil {
+			r.AddListParam("regions", tc.regions)
+			assert.Equal(t, tc.regions, r.Regions())
+		}
 		assert.Equal(t, tc.exp, r.AllRegionsRequested())
 	}
 }
@@ -462,11 +545,33 @@ func TestRequestConstructDataEndpointURL(t *testing.T) {
 		request.AddListParam("fields", tc.fields)
 		request.AddListParam("tags", tc.tags)
 		request.AddListParam("notags", tc.notags)
+		if tc.samples != nil {
+			request.AddListParam("samples", tc.samples)
+		}
 		ep, _ := request.ConstructDataEndpointURL()
 		assert.Equal(t, tc.exp, ep.String())
 	}
 }
 
+func TestRequestConstructDataEndpointURLReadFilters(t *testing.T) {
+	request := NewHtsgetRequest()
+	request.SetEndpoint(htsconstants.APIEndpointReadsTicket)
+	for key, value := range defaultScalarParameterValues {
+		request.AddScalarParam(key, value)
+	}
+	for key, value := range defaultListParameterValues {
+		request.AddListParam(key, value)
+	}
+	request.AddScalarParam("id", "tabulamuris.00001")
+	request.AddScalarParam("requiredFlags", "0x2")
+	request.AddScalarParam("excludedFlags", "1024")
+	request.AddScalarParam("minMappingQuality", "30")
+	request.AddListParam("readGroups", []string{"RG1", "RG2"})
+	request.AddListParam("samples", []string{"NA12878"})
+	ep, _ := request.ConstructDataEndpointURL()
+	assert.Equal(t, "http://localhost:3000/reads/data/tabulamuris.00001?excludedFlags=1024&minMappingQuality=30&readGroups=RG1%2CRG2&requiredFlags=2&samples=NA12878", ep.String())
+}
+
 func TestRequestGetDataSourceRegistry(t *testing.T) {
 	for _, tc := range requestDataSourceRegistryTC {
 		r := NewHtsgetRequest()
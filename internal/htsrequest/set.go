@@ -24,9 +24,15 @@ var orderedParametersByMethodAndEndpoint = map[htsconstants.HTTPMethod]map[htsco
 			"referenceName",
 			"start",
 			"end",
+			"regions",
 			"fields",
 			"tags",
 			"notags",
+			"requiredFlags",
+			"excludedFlags",
+			"minMappingQuality",
+			"readGroups",
+			"samples",
 		},
 		htsconstants.APIEndpointReadsData: []string{
 			"id",
@@ -37,9 +43,16 @@ var orderedParametersByMethodAndEndpoint = map[htsconstants.HTTPMethod]map[htsco
 			"fields",
 			"tags",
 			"notags",
+			"requiredFlags",
+			"excludedFlags",
+			"minMappingQuality",
+			"readGroups",
+			"samples",
 			"HtsgetBlockClass",
 			"HtsgetBlockId",
 			"HtsgetNumBlocks",
+			"HtsgetBlockSplit",
+			"HtsgetPrevRegion",
 		},
 		htsconstants.APIEndpointReadsServiceInfo: []string{},
 		htsconstants.APIEndpointVariantsTicket: []string{
@@ -49,9 +62,11 @@ var orderedParametersByMethodAndEndpoint = map[htsconstants.HTTPMethod]map[htsco
 			"referenceName",
 			"start",
 			"end",
+			"regions",
 			"fields",
 			"tags",
 			"notags",
+			"samples",
 		},
 		htsconstants.APIEndpointVariantsData: []string{
 			"id",
@@ -63,12 +78,30 @@ var orderedParametersByMethodAndEndpoint = map[htsconstants.HTTPMethod]map[htsco
 			"fields",
 			"tags",
 			"notags",
+			"samples",
 			"HtsgetBlockClass",
 			"HtsgetBlockId",
 			"HtsgetNumBlocks",
 		},
+		htsconstants.APIEndpointReadsMetadata: []string{
+			"id",
+		},
+		htsconstants.APIEndpointVariantsMetadata: []string{
+			"id",
+		},
+		htsconstants.APIEndpointReadsList: []string{
+			"prefix",
+			"pageSize",
+			"pageToken",
+		},
+		htsconstants.APIEndpointVariantsList: []string{
+			"prefix",
+			"pageSize",
+			"pageToken",
+		},
 		htsconstants.APIEndpointFileBytes: []string{
 			"HtsgetFilePath",
+			"HtsgetObjectId",
 			"Range",
 		},
 	},
@@ -158,6 +191,8 @@ func SetAllParameters(method htsconstants.HTTPMethod, endpoint htsconstants.APIE
 	orderedParams := orderedParametersByMethodAndEndpoint[method][endpoint]
 	htsgetReq := NewHtsgetRequest()
 	htsgetReq.SetEndpoint(endpoint)
+	htsgetReq.SetContext(request.Context())
+	htsgetReq.SetClientSubject(getClientSubject(request))
 	params := request.URL.Query()
 	for i := 0; i < len(orderedParams); i++ {
 		paramKey := orderedParams[i]
@@ -171,3 +206,18 @@ func SetAllParameters(method htsconstants.HTTPMethod, endpoint htsconstants.APIE
 	}
 	return htsgetReq, nil
 }
+
+// getClientSubject gets the subject of the client certificate presented over
+// mutual TLS. only certificates verified against the client CA bundle are
+// considered
+//
+// Arguments
+//	request (*http.Request): HTTP request
+// Returns
+//	(string): client certificate subject distinguished name, empty if none was verified
+func getClientSubject(request *http.Request) string {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return request.TLS.VerifiedChains[0][0].Subject.String()
+}
@@ -13,25 +13,37 @@ import (
 // transformationScalarByParam (map[string]func(string) string): map of
 // functions. the correct transformation function for each scalar parameter
 var transformationScalarByParam = map[string]func(string) string{
-	"id":               noTransform,
-	"format":           strings.ToUpper,
-	"class":            strings.ToLower,
-	"referenceName":    noTransform,
-	"start":            noTransform,
-	"end":              noTransform,
-	"HtsgetBlockClass": strings.ToLower,
-	"HtsgetBlockId":    noTransform,
-	"HtsgetNumBlocks":  noTransform,
-	"HtsgetFilePath":   noTransform,
-	"Range":            noTransform,
+	"id":                noTransform,
+	"format":            strings.ToUpper,
+	"class":             strings.ToLower,
+	"referenceName":     noTransform,
+	"start":             noTransform,
+	"end":               noTransform,
+	"HtsgetBlockClass":  strings.ToLower,
+	"HtsgetBlockId":     noTransform,
+	"HtsgetNumBlocks":   noTransform,
+	"HtsgetBlockSplit":  strings.ToLower,
+	"HtsgetPrevRegion":  noTransform,
+	"HtsgetFilePath":    noTransform,
+	"HtsgetObjectId":    noTransform,
+	"Range":             noTransform,
+	"prefix":            noTransform,
+	"pageSize":          noTransform,
+	"pageToken":         noTransform,
+	"requiredFlags":     noTransform,
+	"excludedFlags":     noTransform,
+	"minMappingQuality": noTransform,
 }
 
 // transformationScalarByParam (map[string]func(string) []string): map of
 // functions. the correct transformation function for each list parameter
 var transformationListByParam = map[string]func(string) []string{
-	"fields": splitAndUppercase,
-	"tags":   splitOnComma,
-	"notags": splitOnComma,
+	"fields":     splitAndUppercase,
+	"tags":       splitOnComma,
+	"notags":     splitOnComma,
+	"samples":    splitOnComma,
+	"readGroups": splitOnComma,
+	"regions":    splitOnComma,
 }
 
 // noTransform performs no transformation on a request parameter
@@ -7,17 +7,15 @@
 package htsrequest
 
 import (
-	"bufio"
 	"net/http"
 	"os"
-	"os/exec"
-	"regexp"
 	"strconv"
 	"strings"
 
-	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
 	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
 
@@ -26,71 +24,63 @@ import (
 // returns a boolean indicating whether the parameter passed validation, and a
 // string indicating why validation failed (if it failed)
 var validationByParam = map[string]func(string, *HtsgetRequest) (bool, string){
-	"id":               validateID,
-	"format":           validateFormat,
-	"class":            validateClass,
-	"referenceName":    validateReferenceName,
-	"start":            validateStart,
-	"end":              validateEnd,
-	"fields":           validateFields,
-	"tags":             validateTags,
-	"notags":           validateNoTags,
-	"HtsgetBlockClass": validateClass,
-	"HtsgetBlockId":    noValidation,
-	"HtsgetNumBlocks":  noValidation,
-	"HtsgetFilePath":   noValidation,
-	"Range":            noValidation,
+	"id":                validateID,
+	"format":            validateFormat,
+	"class":             validateClass,
+	"referenceName":     validateReferenceName,
+	"start":             validateStart,
+	"end":               validateEnd,
+	"fields":            validateFields,
+	"tags":              validateTags,
+	"notags":            validateNoTags,
+	"samples":           validateSamples,
+	"regions":           validateRegions,
+	"requiredFlags":     validateRequiredFlags,
+	"excludedFlags":     validateExcludedFlags,
+	"minMappingQuality": validateMinMappingQuality,
+	"readGroups":        validateReadGroups,
+	"HtsgetBlockClass":  validateClass,
+	"HtsgetBlockId":     noValidation,
+	"HtsgetNumBlocks":   noValidation,
+	"HtsgetBlockSplit":  noValidation,
+	"HtsgetPrevRegion":  validatePrevRegion,
+	"HtsgetFilePath":    noValidation,
+	"HtsgetObjectId":    noValidation,
+	"Range":             noValidation,
+	"prefix":            noValidation,
+	"pageSize":          validatePageSize,
+	"pageToken":         validatePageToken,
 }
 
 // errorsByParam (map[string]func(http.ResponseWriter, *string)): the correct
 // error to raise for each request parameter validation
 var errorsByParam = map[string]func(http.ResponseWriter, *string){
-	"id":               htserror.NotFound,
-	"format":           htserror.UnsupportedFormat,
-	"class":            htserror.InvalidInput,
-	"referenceName":    htserror.InvalidRange,
-	"start":            htserror.InvalidRange,
-	"end":              htserror.InvalidRange,
-	"fields":           htserror.InvalidInput,
-	"tags":             htserror.InvalidInput,
-	"notags":           htserror.InvalidInput,
-	"HtsgetBlockClass": htserror.InvalidInput,
-	"HtsgetBlockId":    htserror.InternalServerError,
-	"HtsgetNumBlocks":  htserror.InternalServerError,
-	"HtsgetFilePath":   htserror.InternalServerError,
-	"Range":            htserror.InternalServerError,
-}
-
-// isInteger determines if a string can be parsed as an integer
-//
-// Arguments
-//	value (string): string to check
-// Returns
-//	(bool): true if the string can be converted to an integer, false if not
-func isInteger(value string) bool {
-	_, err := strconv.Atoi(value)
-	if err != nil {
-		return false
-	}
-	return true
-}
-
-// isGreaterThanEqualToZero determines if a string can be parsed as an integer,
-// and is greater than or equal to zero
-//
-// Arguments
-//	value (string): string to check
-// Returns
-// (bool): true if string is a valid integer greater than or equal to zero
-func isGreaterThanEqualToZero(value string) bool {
-	if !isInteger(value) {
-		return false
-	}
-	num, _ := strconv.Atoi(value)
-	if num < 0 {
-		return false
-	}
-	return true
+	"id":                htserror.NotFound,
+	"format":            htserror.UnsupportedFormat,
+	"class":             htserror.InvalidInput,
+	"referenceName":     htserror.InvalidRange,
+	"start":             htserror.InvalidRange,
+	"end":               htserror.InvalidRange,
+	"fields":            htserror.InvalidInput,
+	"tags":              htserror.InvalidInput,
+	"notags":            htserror.InvalidInput,
+	"samples":           htserror.InvalidInput,
+	"regions":           htserror.InvalidRange,
+	"requiredFlags":     htserror.InvalidInput,
+	"excludedFlags":     htserror.InvalidInput,
+	"minMappingQuality": htserror.InvalidInput,
+	"readGroups":        htserror.InvalidInput,
+	"HtsgetBlockClass":  htserror.InvalidInput,
+	"HtsgetBlockId":     htserror.InternalServerError,
+	"HtsgetNumBlocks":   htserror.InternalServerError,
+	"HtsgetBlockSplit":  htserror.InternalServerError,
+	"HtsgetPrevRegion":  htserror.InvalidInput,
+	"HtsgetFilePath":    htserror.InternalServerError,
+	"HtsgetObjectId":    htserror.InternalServerError,
+	"Range":             htserror.InternalServerError,
+	"prefix":            htserror.InvalidInput,
+	"pageSize":          htserror.InvalidInput,
+	"pageToken":         htserror.InvalidInput,
 }
 
 // noValidation is an empty validation function for request parameters that do
@@ -116,7 +106,7 @@ func noValidation(value string, htsgetReq *HtsgetRequest) (bool, string) {
 //	(bool): true if a resource matching id could be found from the data source
 //	(string): diagnostic message if error encountered
 func validateID(id string, htsgetReq *HtsgetRequest) (bool, string) {
-	objPath, err := htsconfig.GetObjectPath(htsgetReq.GetEndpoint(), id)
+	objPath, err := resolveObjectPath(htsgetReq.Context(), htsgetReq.GetEndpoint(), id)
 	if err != nil {
 		return false, "The requested resource could not be associated with a registered data source"
 	}
@@ -124,11 +114,10 @@ func validateID(id string, htsgetReq *HtsgetRequest) (bool, string) {
 	// attempt to locate the object by http request (if url) or on local file
 	// path
 	if htsutils.IsValidURL(objPath) {
-		res, err := http.Head(objPath)
+		res, err := htsutils.HeadObject(htsgetReq.Context(), objPath)
 		if err != nil {
 			return false, "The requested resource was not found"
 		}
-		res.Body.Close()
 		if res.Status == "404 Not Found" {
 			return false, "The requested resource was not found"
 		}
@@ -179,72 +168,44 @@ func validateClass(class string, htsgetReq *HtsgetRequest) (bool, string) {
 	}
 }
 
-func getReferenceNamesInReadsObject(htsgetReq *HtsgetRequest) ([]string, error) {
-
-	var referenceNames []string
-	fileURL, err := htsconfig.GetObjectPath(htsgetReq.GetEndpoint(), htsgetReq.ID())
+func getReadsObjectHeader(htsgetReq *HtsgetRequest) (*htscache.Header, error) {
+	fileURL, err := htsgetReq.GetObjectPath()
 	if err != nil {
 		return nil, err
 	}
-	cmd := exec.Command("samtools", "view", "-H", fileURL)
-	pipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, err
-	}
-
-	cmd.Start()
-	reader := bufio.NewReader(pipe)
-	l, _, err := reader.ReadLine()
-
-	for ; err == nil; l, _, err = reader.ReadLine() {
-		pattern := regexp.MustCompile("^@SQ\tSN:(.+?)\t.+?$")
-		submatches := pattern.FindStringSubmatch(string(l))
-		if len(submatches) > 1 {
-			referenceNames = append(referenceNames, submatches[1])
-		}
-	}
-	cmd.Wait()
-	return referenceNames, nil
+	return htscache.ReadsHeader(htsgetReq.Context(), fileURL)
 }
 
-func getReferenceNamesInVariantsObject(htsgetReq *HtsgetRequest) ([]string, error) {
-	var referenceNames []string
-	fileURL, err := htsconfig.GetObjectPath(htsgetReq.GetEndpoint(), htsgetReq.ID())
-	if err != nil {
-		return nil, err
-	}
-	cmd := exec.Command("bcftools", "view", "-h", fileURL)
-	pipe, err := cmd.StdoutPipe()
+func getVariantsObjectHeader(htsgetReq *HtsgetRequest) (*htscache.Header, error) {
+	fileURL, err := htsgetReq.GetObjectPath()
 	if err != nil {
 		return nil, err
 	}
+	return htscache.VariantsHeader(htsgetReq.Context(), fileURL)
+}
 
-	cmd.Start()
-	reader := bufio.NewReader(pipe)
-	l, _, err := reader.ReadLine()
-
-	for ; err == nil; l, _, err = reader.ReadLine() {
-		pattern := regexp.MustCompile("^##contig=<.*?ID=(.+?)[,>]")
-		submatches := pattern.FindStringSubmatch(string(l))
-		if len(submatches) > 1 {
-			referenceNames = append(referenceNames, submatches[1])
-		}
+// getObjectHeader
+// for a given endpoint (BAM request / VCF request), return the header of the
+// requested object
+func getObjectHeader(htsgetReq *HtsgetRequest) (*htscache.Header, error) {
+	functions := map[htsconstants.APIEndpoint]func(htsgetReq *HtsgetRequest) (*htscache.Header, error){
+		htsconstants.APIEndpointReadsTicket:    getReadsObjectHeader,
+		htsconstants.APIEndpointReadsData:      getReadsObjectHeader,
+		htsconstants.APIEndpointVariantsTicket: getVariantsObjectHeader,
+		htsconstants.APIEndpointVariantsData:   getVariantsObjectHeader,
 	}
-	cmd.Wait()
-	return referenceNames, nil
+	return functions[htsgetReq.endpoint](htsgetReq)
 }
 
 // getAllowedReferenceNames
 // for a given endpoint (BAM request / VCF request), return the allowable values
 // for the 'referenceName' parameter for the requested object
 func getReferenceNames(htsgetReq *HtsgetRequest) ([]string, error) {
-	functions := map[htsconstants.APIEndpoint]func(htsgetReq *HtsgetRequest) ([]string, error){
-		htsconstants.APIEndpointReadsTicket:    getReferenceNamesInReadsObject,
-		htsconstants.APIEndpointReadsData:      getReferenceNamesInReadsObject,
-		htsconstants.APIEndpointVariantsTicket: getReferenceNamesInVariantsObject,
-		htsconstants.APIEndpointVariantsData:   getReferenceNamesInVariantsObject,
+	header, err := getObjectHeader(htsgetReq)
+	if err != nil {
+		return nil, err
 	}
-	return functions[htsgetReq.endpoint](htsgetReq)
+	return header.ReferenceNames, nil
 }
 
 // validateReferenceName validates the 'referenceName' query string
@@ -282,8 +243,8 @@ func validateReferenceName(referenceName string, htsgetReq *HtsgetRequest) (bool
 }
 
 // validateStart validates the 'start' query string parameter. checks that it is
-// a valid, non-zero integer, and that it is being used correctly in conjunction
-// with 'referenceName'
+// a valid coordinate of the requested region, and that it is being used
+// correctly in conjunction with 'referenceName'
 //
 // Arguments
 //	start (string): start parameter value
@@ -304,27 +265,60 @@ func validateStart(start string, htsgetReq *HtsgetRequest) (bool, string) {
 	}
 
 	// start requires referenceName to be specified as well
-	if htsgetReq.AllRegionsRequested() {
+	if !htsgetReq.ReferenceNameRequested() {
 		return false, "'start' cannot be set without 'referenceName'"
 	}
 
-	// start must be an integer
-	if !isInteger(start) {
-		return false, "'start' is not a valid integer"
+	// start must be a coordinate, an integer >= 0, within the reference
+	region, err := htsregion.NewRegionFromBounds(htsgetReq.ReferenceName(), start, "")
+	if err != nil {
+		return false, err.Error()
 	}
+	return validateWithinReference("start", region, htsgetReq)
+}
 
-	// start must be >= 0
-	if !isGreaterThanEqualToZero(start) {
-		return false, "'start' must be greater than or equal to zero"
+// validatePageSize validates the 'pageSize' query string parameter of a
+// listing request. checks that it is an integer between 1 and the maximum
+// page size
+//
+// Arguments
+//	pageSize (string): pageSize parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if pageSize is correctly specified
+//	(string): diagnostic message if error encountered
+func validatePageSize(pageSize string, htsgetReq *HtsgetRequest) (bool, string) {
+	n, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return false, "'pageSize' is not a valid integer"
 	}
+	if n < 1 || n > htsconstants.MaxListPageSize {
+		return false, "'pageSize' must be between 1 and " + strconv.Itoa(htsconstants.MaxListPageSize)
+	}
+	return true, ""
+}
 
+// validatePageToken validates the 'pageToken' query string parameter of a
+// listing request. checks that it is a token issued by a previous listing
+// request
+//
+// Arguments
+//	pageToken (string): pageToken parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if pageToken is a valid token
+//	(string): diagnostic message if error encountered
+func validatePageToken(pageToken string, htsgetReq *HtsgetRequest) (bool, string) {
+	_, err := htsutils.DecodePageToken(pageToken)
+	if err != nil {
+		return false, "invalid 'pageToken': " + pageToken
+	}
 	return true, ""
 }
 
 // validateEnd validates the 'end' query string parameter. checks that it is a
-// valid, non-zero integer, that it's being used correctly in conjunction with
-// 'referenceName', and that the end coordinate is greater than the start
-// coordinate
+// valid coordinate of the requested region, that it's being used correctly in
+// conjunction with 'referenceName', and that the region ends after its start
 //
 // Arguments
 //	end (string): end parameter value
@@ -339,42 +333,148 @@ func validateEnd(end string, htsgetReq *HtsgetRequest) (bool, string) {
 		return false, "'end' incompatible with header-only request"
 	}
 
-	start := htsgetReq.Start()
-
 	// end requires referenceName to specify a true chromosome
 	if htsgetReq.UnplacedUnmappedReadsRequested() {
 		return false, "'end' cannot be requested with unplaced, unmapped reads"
 	}
 
 	// end requires referenceName to be specified as well
-	if htsgetReq.AllRegionsRequested() {
+	if !htsgetReq.ReferenceNameRequested() {
 		return false, "'end' cannot be set without 'referenceName'"
 	}
 
-	// end must be an integer
-	if !isInteger(end) {
-		return false, "'end' is not a valid integer"
+	// end must be a coordinate, after start if it is specified, within the
+	// reference
+	region, err := htsregion.NewRegionFromBounds(htsgetReq.ReferenceName(), htsgetReq.Start(), end)
+	if err != nil {
+		return false, err.Error()
 	}
+	return validateWithinReference("end", region, htsgetReq)
+}
 
-	// end must be >= 0
-	if !isGreaterThanEqualToZero(end) {
-		return false, "'end' must be greater than or equal to zero"
+// validateWithinReference checks that the region of the 'referenceName',
+// 'start' and 'end' parameters is within the length of its reference
+// sequence, as regionWithinReference does for each of 'regions'
+//
+// Arguments
+//	param (string): name of the parameter bounding the region
+//	region (*htsregion.Region): requested region
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if the region is within its reference sequence
+//	(string): diagnostic message if error encountered
+func validateWithinReference(param string, region *htsregion.Region, htsgetReq *HtsgetRequest) (bool, string) {
+	// the header is loaded to validate 'referenceName' first, which fails if
+	// the header cannot be read
+	header, err := getObjectHeader(htsgetReq)
+	if err != nil {
+		return true, ""
+	}
+	if !regionWithinReference(region, header) {
+		return false, "'" + param + "' is past the end of " + region.Name
 	}
+	return true, ""
+}
 
-	// if start is specified, end must be greater than start
-	if start != "-1" {
-		startNum, startErr := strconv.Atoi(start)
-		endNum, endErr := strconv.Atoi(end)
-		if startErr != nil || endErr != nil {
-			return false, "error converting 'start' and/or 'end' to integers"
+// validateRegions validates the 'regions' query string parameter, a comma
+// separated list of regions (name, name:start-end, or name:start-). checks
+// that it is not combined with 'referenceName', that every region is well
+// formed, and that every reference name is in the sequence dictionary of the
+// object
+//
+// Arguments
+//	regions (string): unsplit regions parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if all regions are valid
+//	(string): diagnostic message if error encountered
+func validateRegions(regions string, htsgetReq *HtsgetRequest) (bool, string) {
+
+	// incompatible with header only request
+	if htsgetReq.HeaderOnlyRequested() {
+		return false, "'regions' incompatible with header-only request"
+	}
+	if htsgetReq.ReferenceNameRequested() {
+		return false, "'regions' cannot be requested with 'referenceName'"
+	}
+
+	var header *htscache.Header
+	for _, regionString := range splitOnComma(regions) {
+		region, err := htsregion.ParseRegion(regionString)
+		if err != nil {
+			return false, "invalid 'regions': " + err.Error()
 		}
-		if startNum >= endNum {
-			return false, "'end' MUST be higher than 'start'"
+		name := region.Name
+		// unplaced, unmapped reads have no coordinates, and variants have no
+		// unplaced records
+		if name == "*" {
+			endpoint := htsgetReq.GetEndpoint()
+			if endpoint != htsconstants.APIEndpointReadsTicket && endpoint != htsconstants.APIEndpointReadsData {
+				return false, "invalid 'regions': unplaced, unmapped reads (*) can only be requested from reads"
+			}
+			if !region.OpenStart || !region.OpenEnd {
+				return false, "invalid 'regions': unplaced, unmapped reads cannot have an interval"
+			}
+			continue
+		}
+		if header == nil {
+			header, err = getObjectHeader(htsgetReq)
+			if err != nil {
+				return false, err.Error()
+			}
+		}
+		if !htsutils.IsItemInArray(name, header.ReferenceNames) {
+			return false, "invalid 'regions': " + name + " is not a reference sequence of the object"
+		}
+		if !regionWithinReference(region, header) {
+			return false, "invalid 'regions': region '" + regionString + "' extends past the end of " + name
 		}
 	}
 	return true, ""
 }
 
+// regionWithinReference checks that a region starts, and ends, within the
+// length of its reference sequence, as declared in the header of the object.
+// regions of reference sequences without a declared length are not checked
+//
+// Arguments
+//	region (*htsregion.Region): region on a reference sequence of the object
+//	header (*htscache.Header): header of the object
+// Returns
+//	(bool): true if the region is within its reference sequence
+func regionWithinReference(region *htsregion.Region, header *htscache.Header) bool {
+	for _, reference := range header.References {
+		if reference.Name != region.Name || reference.Length == 0 {
+			continue
+		}
+		if region.Start >= reference.Length || (!region.OpenEnd && region.End > reference.Length) {
+			return false
+		}
+	}
+	return true
+}
+
+// validatePrevRegion validates the 'HtsgetPrevRegion' header param, the region
+// served by an earlier body block of a multi-region request. checks that it
+// is well formed, and on the reference sequence of the block
+//
+// Arguments
+//	prevRegion (string): HtsgetPrevRegion header value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if the region is valid
+//	(string): diagnostic message if error encountered
+func validatePrevRegion(prevRegion string, htsgetReq *HtsgetRequest) (bool, string) {
+	region, err := htsregion.ParseRegion(prevRegion)
+	if err != nil {
+		return false, "invalid 'HtsgetPrevRegion': " + err.Error()
+	}
+	if region.Name != htsgetReq.ReferenceName() {
+		return false, "'HtsgetPrevRegion' must be on the requested reference sequence"
+	}
+	return true, ""
+}
+
 // validateFields validates the 'fields' query string parameter. checks that
 // every requested field is an acceptable value (an expected BAM/CRAM field)
 //
@@ -445,3 +545,155 @@ func validateNoTags(notags string, htsgetReq *HtsgetRequest) (bool, string) {
 	}
 	return true, ""
 }
+
+// validateSamples validates the 'samples' query string parameter. checks that
+// every requested sample is named, and named only once. on reads requests,
+// also checks that every sample is the SM of a read group of the object, and
+// that requested read groups include one of the samples. the samples of
+// variants are checked against the header when the samples are subset
+//
+// Arguments
+//	samples (string): unsplit samples parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if all requested samples are named once, and in the object
+//	(string): diagnostic message if error encountered
+func validateSamples(samples string, htsgetReq *HtsgetRequest) (bool, string) {
+	if ok, message := validateNames("samples", "sample", samples); !ok {
+		return false, message
+	}
+	endpoint := htsgetReq.GetEndpoint()
+	if endpoint != htsconstants.APIEndpointReadsTicket && endpoint != htsconstants.APIEndpointReadsData {
+		return true, ""
+	}
+
+	header, err := getObjectHeader(htsgetReq)
+	if err != nil {
+		return false, err.Error()
+	}
+	samplesList := splitOnComma(samples)
+	for _, sample := range samplesList {
+		if len(header.SampleReadGroups([]string{sample})) == 0 {
+			return false, "sample " + sample + " is not in the object"
+		}
+	}
+	if !htsgetReq.AllReadGroupsRequested() {
+		for _, readGroup := range header.SampleReadGroups(samplesList) {
+			if htsutils.IsItemInArray(readGroup, htsgetReq.ReadGroups()) {
+				return true, ""
+			}
+		}
+		return false, "no read group in 'readGroups' is of a requested sample"
+	}
+	return true, ""
+}
+
+// validateReadGroups validates the 'readGroups' query string parameter.
+// checks that every requested read group id is named, and named only once
+//
+// Arguments
+//	readGroups (string): unsplit readGroups parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if all requested read groups are named once
+//	(string): diagnostic message if error encountered
+func validateReadGroups(readGroups string, htsgetReq *HtsgetRequest) (bool, string) {
+	return validateNames("readGroups", "read group id", readGroups)
+}
+
+// validateNames checks that a comma separated list names every item, and
+// names it only once
+//
+// Arguments
+//	param (string): parameter name
+//	item (string): description of a list item
+//	names (string): unsplit parameter value
+// Returns
+//	(bool): true if all items are named once
+//	(string): diagnostic message if error encountered
+func validateNames(param string, item string, names string) (bool, string) {
+	requested := make(map[string]bool)
+	for _, name := range splitOnComma(names) {
+		if name == "" {
+			return false, "'" + param + "' cannot contain an empty " + item
+		}
+		if requested[name] {
+			return false, "'" + name + "' requested more than once in '" + param + "'"
+		}
+		requested[name] = true
+	}
+	return true, ""
+}
+
+// validateRequiredFlags validates the 'requiredFlags' query string parameter,
+// the FLAG bits every returned read must have set. checks that it is a FLAG
+// value, in decimal or hexadecimal (0x) notation
+//
+// Arguments
+//	requiredFlags (string): requiredFlags parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if requiredFlags is a FLAG value
+//	(string): diagnostic message if error encountered
+func validateRequiredFlags(requiredFlags string, htsgetReq *HtsgetRequest) (bool, string) {
+	if _, ok := parseFlags(requiredFlags); !ok {
+		return false, "'requiredFlags' is not a valid FLAG value"
+	}
+	return true, ""
+}
+
+// validateExcludedFlags validates the 'excludedFlags' query string parameter,
+// the FLAG bits no returned read may have set. checks that it is a FLAG
+// value, and that no bit is both required and excluded
+//
+// Arguments
+//	excludedFlags (string): excludedFlags parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if excludedFlags is a FLAG value, sharing no bit with requiredFlags
+//	(string): diagnostic message if error encountered
+func validateExcludedFlags(excludedFlags string, htsgetReq *HtsgetRequest) (bool, string) {
+	flags, ok := parseFlags(excludedFlags)
+	if !ok {
+		return false, "'excludedFlags' is not a valid FLAG value"
+	}
+	if flags&htsgetReq.RequiredFlags() != 0 {
+		return false, "FLAG bits cannot be in both 'requiredFlags' and 'excludedFlags'"
+	}
+	return true, ""
+}
+
+// parseFlags parses a FLAG value, in decimal or hexadecimal (0x) notation
+//
+// Arguments
+//	flags (string): FLAG value
+// Returns
+//	(int): FLAG bits
+//	(bool): true if the value is a 16-bit unsigned integer
+func parseFlags(flags string) (int, bool) {
+	n, err := strconv.ParseUint(flags, 0, 16)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// validateMinMappingQuality validates the 'minMappingQuality' query string
+// parameter. checks that it is an integer between 0 and 255
+//
+// Arguments
+//	minMappingQuality (string): minMappingQuality parameter value
+//	htsgetReq (*HtsgetRequest): htsget request object
+// Returns
+//	(bool): true if minMappingQuality is a MAPQ value
+//	(string): diagnostic message if error encountered
+func validateMinMappingQuality(minMappingQuality string, htsgetReq *HtsgetRequest) (bool, string) {
+	n, err := strconv.Atoi(minMappingQuality)
+	if err != nil {
+		return false, "'minMappingQuality' is not a valid integer"
+	}
+	if n < 0 || n > 255 {
+		return false, "'minMappingQuality' must be between 0 and 255"
+	}
+	return true, ""
+}
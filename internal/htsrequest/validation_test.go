@@ -1,9 +1,13 @@
 package htsrequest
 
 import (
+	"context"
+	"os/exec"
 	"testing"
 
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -100,6 +104,16 @@ var validateEndTC = []struct {
 	{"", "chr1", "100", "500", true},
 }
 
+var validateBoundsTC = []struct {
+	start, end string
+	exp        bool
+}{
+	{"195471970", "", true},
+	{"195471971", "", false},
+	{"0", "195471971", true},
+	{"0", "195471972", false},
+}
+
 var validateFieldsTC = []struct {
 	class, fields string
 	exp           bool
@@ -130,6 +144,130 @@ var validateNoTagsTC = []struct {
 	{"", []string{"NM", "MD"}, "MD", false},
 }
 
+var validateSamplesTC = []struct {
+	samples string
+	exp     bool
+}{
+	{"HG00096", true},
+	{"HG00096,HG00097", true},
+	{"", false},
+	{"HG00096,,HG00097", false},
+	{"HG00096,HG00096", false},
+}
+
+var validateReadGroupsTC = []struct {
+	readGroups string
+	exp        bool
+}{
+	{"RG1", true},
+	{"RG1,RG2", true},
+	{"", false},
+	{"RG1,RG1", false},
+}
+
+var validateRequiredFlagsTC = []struct {
+	requiredFlags string
+	exp           bool
+}{
+	{"0", true},
+	{"3", true},
+	{"0x42", true},
+	{"65535", true},
+	{"65536", false},
+	{"-1", false},
+	{"paired", false},
+}
+
+var validateExcludedFlagsTC = []struct {
+	requiredFlags, excludedFlags string
+	exp                          bool
+}{
+	{"", "0x904", true},
+	{"1", "4", true},
+	{"0x3", "2", false},
+	{"", "0x10000", false},
+	{"", "unmapped", false},
+}
+
+var validateMinMappingQualityTC = []struct {
+	minMappingQuality string
+	exp               bool
+}{
+	{"0", true},
+	{"30", true},
+	{"255", true},
+	{"256", false},
+	{"-1", false},
+	{"high", false},
+}
+
+var validateRegionsTC = []struct {
+	class, referenceName, regions string
+	exp                           bool
+}{
+	{"", "", "*", true},
+	{"", "", "*,*", true},
+	{"header", "", "*", false},
+	{"", "chr1", "*", false},
+	{"", "", "*:100-200", false},
+	{"", "", "chr1:200-100", false},
+	{"", "", "*,:1-2", false},
+	{"", "", "chr1:99999999999999999999-", false},
+	{"", "", "chr1:0-99999999999999999999", false},
+}
+
+func TestValidateRegionsUnplacedVariants(t *testing.T) {
+	// variants have no unplaced, unmapped records
+	htsgetReq := NewHtsgetRequest()
+	htsgetReq.SetEndpoint(htsconstants.APIEndpointVariantsTicket)
+	result, _ := validateRegions("*", htsgetReq)
+	assert.False(t, result)
+}
+
+var regionWithinReferenceTC = []struct {
+	region string
+	exp    bool
+}{
+	{"chr1", true},
+	{"chr1:0-1000", true},
+	{"chr1:999-", true},
+	{"chr1:1000-", false},
+	{"chr1:500-1001", false},
+	// no declared length
+	{"chr2:5000-6000", true},
+}
+
+var validatePrevRegionTC = []struct {
+	referenceName, prevRegion string
+	exp                       bool
+}{
+	{"chr1", "chr1:100-200", true},
+	{"chr1", "chr1", true},
+	{"chr1", "chr2:100-200", false},
+	{"chr1", "chr1:200-100", false},
+	{"chr1", ":100-200", false},
+}
+
+var validatePageSizeTC = []struct {
+	pageSize string
+	exp      bool
+}{
+	{"1", true},
+	{"1000", true},
+	{"0", false},
+	{"1001", false},
+	{"ten", false},
+}
+
+var validatePageTokenTC = []struct {
+	pageToken string
+	exp       bool
+}{
+	{"dGFidWxhbXVyaXMuQTE", true},
+	{"", false},
+	{"not a token!", false},
+}
+
 func TestValidateID(t *testing.T) {
 	for _, tc := range validateIDTC {
 		r := NewHtsgetRequest()
@@ -188,6 +326,26 @@ func TestValidateEnd(t *testing.T) {
 	}
 }
 
+func TestValidateBounds(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools is not installed")
+	}
+	// start and end are within the length of chr1 in the header
+	for _, tc := range validateBoundsTC {
+		r := NewHtsgetRequest()
+		r.SetEndpoint(htsconstants.APIEndpointReadsTicket)
+		r.SetContext(context.Background())
+		r.AddScalarParam("id", "tabulamuris.A1-B000168-3_57_F-1-1_R2")
+		r.AddScalarParam("referenceName", "chr1")
+		result, _ := validateStart(tc.start, r)
+		if result && tc.end != "" {
+			r.AddScalarParam("start", tc.start)
+			result, _ = validateEnd(tc.end, r)
+		}
+		assert.Equal(t, tc.exp, result, tc.start+"-"+tc.end)
+	}
+}
+
 func TestValidateFields(t *testing.T) {
 	for _, tc := range validateFieldsTC {
 		r := NewHtsgetRequest()
@@ -215,3 +373,88 @@ func TestValidateNoTags(t *testing.T) {
 		assert.Equal(t, tc.exp, result)
 	}
 }
+
+func TestValidateSamples(t *testing.T) {
+	// samples of reads requests are also checked against the header
+	for _, tc := range validateSamplesTC {
+		htsgetReq := NewHtsgetRequest()
+		htsgetReq.SetEndpoint(htsconstants.APIEndpointVariantsTicket)
+		result, _ := validateSamples(tc.samples, htsgetReq)
+		assert.Equal(t, tc.exp, result, tc.samples)
+	}
+}
+
+func TestValidateReadGroups(t *testing.T) {
+	for _, tc := range validateReadGroupsTC {
+		result, _ := validateReadGroups(tc.readGroups, NewHtsgetRequest())
+		assert.Equal(t, tc.exp, result, tc.readGroups)
+	}
+}
+
+func TestValidateRequiredFlags(t *testing.T) {
+	for _, tc := range validateRequiredFlagsTC {
+		result, _ := validateRequiredFlags(tc.requiredFlags, NewHtsgetRequest())
+		assert.Equal(t, tc.exp, result, tc.requiredFlags)
+	}
+}
+
+func TestValidateExcludedFlags(t *testing.T) {
+	for _, tc := range validateExcludedFlagsTC {
+		htsgetReq := NewHtsgetRequest()
+		htsgetReq.AddScalarParam("requiredFlags", tc.requiredFlags)
+		result, _ := validateExcludedFlags(tc.excludedFlags, htsgetReq)
+		assert.Equal(t, tc.exp, result, tc.excludedFlags)
+	}
+}
+
+func TestValidateMinMappingQuality(t *testing.T) {
+	for _, tc := range validateMinMappingQualityTC {
+		result, _ := validateMinMappingQuality(tc.minMappingQuality, NewHtsgetRequest())
+		assert.Equal(t, tc.exp, result, tc.minMappingQuality)
+	}
+}
+
+func TestValidateRegions(t *testing.T) {
+	for _, tc := range validateRegionsTC {
+		htsgetReq := NewHtsgetRequest()
+		htsgetReq.AddScalarParam("class", tc.class)
+		htsgetReq.AddScalarParam("referenceName", tc.referenceName)
+		result, _ := validateRegions(tc.regions, htsgetReq)
+		assert.Equal(t, tc.exp, result, tc.regions)
+	}
+}
+
+func TestRegionWithinReference(t *testing.T) {
+	header := &htscache.Header{
+		ReferenceNames: []string{"chr1", "chr2"},
+		References:     []*htscache.Reference{{Name: "chr1", Length: 1000}, {Name: "chr2"}},
+	}
+	for _, tc := range regionWithinReferenceTC {
+		region, err := htsregion.ParseRegion(tc.region)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.exp, regionWithinReference(region, header), tc.region)
+	}
+}
+
+func TestValidatePrevRegion(t *testing.T) {
+	for _, tc := range validatePrevRegionTC {
+		htsgetReq := NewHtsgetRequest()
+		htsgetReq.AddScalarParam("referenceName", tc.referenceName)
+		result, _ := validatePrevRegion(tc.prevRegion, htsgetReq)
+		assert.Equal(t, tc.exp, result, tc.prevRegion)
+	}
+}
+
+func TestValidatePageSize(t *testing.T) {
+	for _, tc := range validatePageSizeTC {
+		result, _ := validatePageSize(tc.pageSize, NewHtsgetRequest())
+		assert.Equal(t, tc.exp, result)
+	}
+}
+
+func TestValidatePageToken(t *testing.T) {
+	for _, tc := range validatePageTokenTC {
+		result, _ := validatePageToken(tc.pageToken, NewHtsgetRequest())
+		assert.Equal(t, tc.exp, result)
+	}
+}
@@ -0,0 +1,139 @@
+package htsserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// requestIDHeader response header echoing the id assigned to the request
+const requestIDHeader = "X-Request-Id"
+
+type ctxKeyAccessRecord int
+
+// accessRecordKey is the request context key holding the *accessRecord
+const accessRecordKey ctxKeyAccessRecord = 0
+
+// accessRecord holds htsget-specific attributes of a request, populated by the
+// request handler once parameters have been parsed, and written to the access
+// log once the response is complete
+type accessRecord struct {
+	id        string
+	region    string
+	format    string
+	class     string
+	blockID   string
+	numBlocks string
+	client    string
+}
+
+// getAccessRecord gets the access record attached to the request context, or
+// nil if the request was not routed through the access log middleware
+func getAccessRecord(ctx context.Context) *accessRecord {
+	record, _ := ctx.Value(accessRecordKey).(*accessRecord)
+	return record
+}
+
+// recordHtsgetRequest copies the audited htsget parameters onto the access
+// record attached to the request context. the id is taken from the url path
+// if it was rejected during validation
+func recordHtsgetRequest(request *http.Request, htsgetReq *htsrequest.HtsgetRequest) {
+	record := getAccessRecord(request.Context())
+	if record == nil || htsgetReq == nil {
+		return
+	}
+	record.id = htsgetReq.ID()
+	if record.id == "" {
+		record.id = chi.URLParam(request, "id")
+	}
+	record.format = htsgetReq.Format()
+	record.class = htsgetReq.Class()
+	if region, err := htsgetReq.Region(); err == nil && htsgetReq.ReferenceNameRequested() {
+		record.region = region.String()
+	}
+	if htsgetReq.HtsgetBlockClass() != "" {
+		record.class = htsgetReq.HtsgetBlockClass()
+	}
+	record.blockID = htsgetReq.HtsgetBlockID()
+	record.numBlocks = htsgetReq.HtsgetNumBlocks()
+	record.client = htsgetReq.ClientSubject()
+}
+
+// accessLog is middleware that writes a structured access log entry for every
+// request once the response is complete. panics raised by handlers are
+// recovered, logged as errors, and answered with an InternalServerError.
+// responses aborted on purpose (http.ErrAbortHandler) are logged, and still
+// aborted
+func accessLog(next http.Handler) http.Handler {
+	fn := func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		respWriter := newResponseWriter(writer)
+		record := new(accessRecord)
+		requestID := middleware.GetReqID(request.Context())
+		respWriter.Header().Set(requestIDHeader, requestID)
+		request = request.WithContext(context.WithValue(request.Context(), accessRecordKey, record))
+
+		defer func() {
+			rec := recover()
+			if rec != nil && rec != http.ErrAbortHandler {
+				htslog.Error("panic serving request", htslog.Fields{
+					"request_id": requestID,
+					"panic":      fmt.Sprint(rec),
+					"stack":      string(debug.Stack()),
+				})
+				if !respWriter.wroteHeader {
+					htserror.InternalServerError(respWriter, nil)
+				}
+			}
+
+			fields := htslog.Fields{
+				"request_id":  requestID,
+				"method":      request.Method,
+				"path":        request.URL.Path,
+				"endpoint":    chi.RouteContext(request.Context()).RoutePattern(),
+				"remote_addr": request.RemoteAddr,
+				"status":      respWriter.status,
+				"bytes":       respWriter.bytesWritten,
+				"duration_ms": float64(time.Since(start).Nanoseconds()) / 1e6,
+			}
+			optionalFields := map[string]string{
+				"htsget_id":            record.id,
+				"region":               record.region,
+				"format":               record.format,
+				"class":                record.class,
+				"block_id":             record.blockID,
+				"num_blocks":           record.numBlocks,
+				"client_subject":       record.client,
+				"htsget_error":         respWriter.htsgetError,
+				"htsget_error_message": respWriter.htsgetErrorMessage,
+			}
+			for key, value := range optionalFields {
+				if value != "" {
+					fields[key] = value
+				}
+			}
+
+			level := htslog.LevelInfo
+			if respWriter.status >= http.StatusInternalServerError || respWriter.failedLate {
+				level = htslog.LevelError
+			} else if respWriter.status >= http.StatusBadRequest {
+				level = htslog.LevelWarn
+			}
+			htslog.GetLogger().Log(level, "request completed", fields)
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+		}()
+
+		next.ServeHTTP(respWriter, request)
+	}
+	return http.HandlerFunc(fn)
+}
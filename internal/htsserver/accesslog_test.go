@@ -0,0 +1,66 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+	"github.com/stretchr/testify/assert"
+)
+
+var accessLogTC = []struct {
+	endpoint    string
+	expStatus   float64
+	expLevel    string
+	expEndpoint string
+	expFields   map[string]string
+}{
+	{
+		"/reads/service-info",
+		200,
+		"info",
+		"/reads/service-info",
+		map[string]string{},
+	},
+	{
+		"/reads/NonExistentId",
+		404,
+		"warn",
+		"/reads/{id}",
+		map[string]string{
+			"htsget_id":    "NonExistentId",
+			"htsget_error": "NotFound",
+		},
+	},
+}
+
+func TestAccessLog(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	htslog.SetLogger(htslog.NewLogger(buffer, htslog.LevelInfo))
+	defer htslog.SetLogger(htslog.NewLogger(new(bytes.Buffer), htslog.LevelError))
+
+	router, _ := SetRouter()
+	for _, tc := range accessLogTC {
+		buffer.Reset()
+		writer := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, tc.endpoint, nil)
+		router.ServeHTTP(writer, request)
+
+		entry := make(map[string]interface{})
+		err := json.Unmarshal(buffer.Bytes(), &entry)
+		assert.Nil(t, err)
+		assert.Equal(t, "request completed", entry["msg"])
+		assert.Equal(t, tc.expLevel, entry["level"])
+		assert.Equal(t, tc.expStatus, entry["status"])
+		assert.Equal(t, tc.expEndpoint, entry["endpoint"])
+		assert.Equal(t, float64(writer.Body.Len()), entry["bytes"])
+		assert.NotEmpty(t, entry["request_id"])
+		assert.Equal(t, entry["request_id"], writer.Header().Get(requestIDHeader))
+		for key, value := range tc.expFields {
+			assert.Equal(t, value, entry[key])
+		}
+	}
+}
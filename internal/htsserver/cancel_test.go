@@ -0,0 +1,75 @@
+package htsserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenWriter is a ResponseWriter for a client that has gone away
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (writer *brokenWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write: broken pipe")
+}
+
+func TestWriteErrorCancelsRequest(t *testing.T) {
+	var cancelledBefore, cancelledAfter bool
+	var startErr error
+	reqHandler := newRequestHandler(
+		htsconstants.GetMethod,
+		htsconstants.APIEndpointReadsServiceInfo,
+		func(handler *requestHandler) {
+			cancelledBefore = handler.cancelled()
+			handler.Writer.Write([]byte("data"))
+			cancelledAfter = handler.cancelled()
+			startErr = htsexec.Command(handler.HtsReq.Context(), "true").Start()
+		},
+	)
+	request := httptest.NewRequest(http.MethodGet, "/reads/service-info", nil)
+	reqHandler.handleRequest(&brokenWriter{httptest.NewRecorder()}, request)
+
+	assert.False(t, cancelledBefore)
+	assert.True(t, cancelledAfter)
+	assert.NotNil(t, startErr)
+}
+
+func TestClientDisconnectKillsSubprocess(t *testing.T) {
+	started := make(chan bool)
+	waitErrs := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		newRequestHandler(
+			htsconstants.GetMethod,
+			htsconstants.APIEndpointReadsServiceInfo,
+			func(handler *requestHandler) {
+				cmd := htsexec.Command(handler.HtsReq.Context(), "sleep", "30")
+				cmd.Start()
+				started <- true
+				waitErrs <- cmd.Wait()
+			},
+		).handleRequest(writer, request)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/reads/service-info", nil)
+	go http.DefaultClient.Do(request.WithContext(ctx))
+	<-started
+	cancel()
+
+	select {
+	case err := <-waitErrs:
+		assert.NotNil(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("subprocess was not killed after the client disconnected")
+	}
+}
@@ -0,0 +1,87 @@
+package htsserver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHTTPRequestParallelFields fires concurrent field-filtered requests on
+// the same object. each request masks records in its own streaming pipeline,
+// so all responses must match the expected file, and no temp files are left
+func TestHTTPRequestParallelFields(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools not found on PATH")
+	}
+
+	// set the configuration for E2E tests
+	wd, _ := os.Getwd()
+	parentDir := filepath.Dir(filepath.Dir(wd))
+	configFilePath := filepath.Join(parentDir, "data", "config", "integration-tests.config.json")
+	configJSONBytes, _ := ioutil.ReadFile(configFilePath)
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSONBytes, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	defer func() {
+		htsconfig.SetConfigFile(nil)
+		htsconfig.LoadConfig()
+	}()
+
+	// setup test server on port 3000, the host in the E2E configuration
+	router, _ := SetRouter()
+	listener, err := net.Listen("tcp", "localhost:3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(router)
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	tc := httpRequestMultiTC[1]
+	expectedMD5 := calculateMD5(filepath.Join(parentDir, "data", "test", "expected", tc.expFilename))
+
+	nRequests := 8
+	actualMD5s := make([]string, nRequests)
+	var wg sync.WaitGroup
+	for r := 0; r < nRequests; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			var output bytes.Buffer
+			writer := bufio.NewWriter(&output)
+			ticket := getHtsgetTicket(server, tc.endpoint, tc.queryParams)
+			for i, ticketURL := range ticket.HTSget.URLS {
+				downloadFilepart(server, i, ticketURL, writer)
+			}
+			body := output.Bytes()
+			if len(body) > 2097152 {
+				body = body[:2097152]
+			}
+			actualMD5s[r] = fmt.Sprintf("%x", md5.Sum(body))
+		}(r)
+	}
+	wg.Wait()
+
+	for _, actualMD5 := range actualMD5s {
+		assert.Equal(t, expectedMD5, actualMD5)
+	}
+
+	// masking streams through samtools, leaving no temporary files behind
+	assert.Equal(t, 0, htsconfig.RemoveAllTempfiles())
+}
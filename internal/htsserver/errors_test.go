@@ -0,0 +1,104 @@
+package htsserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRequestHandler creates a handler for an already validated request,
+// so that failures past validation can be exercised
+func newTestRequestHandler(endpoint htsconstants.APIEndpoint, scalarParams map[string]string) (*requestHandler, *httptest.ResponseRecorder) {
+	htsReq := htsrequest.NewHtsgetRequest()
+	htsReq.SetEndpoint(endpoint)
+	htsReq.SetContext(context.Background())
+	for key, value := range scalarParams {
+		htsReq.AddScalarParam(key, value)
+	}
+	htsReq.AddListParam("fields", []string{"ALL"})
+	htsReq.AddListParam("tags", []string{"ALL"})
+	htsReq.AddListParam("notags", []string{"NONE"})
+
+	recorder := httptest.NewRecorder()
+	handler := newRequestHandler(htsconstants.GetMethod, endpoint, nil)
+	handler.Writer = newResponseWriter(recorder)
+	handler.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.HtsReq = htsReq
+	return handler, recorder
+}
+
+var handlerErrorTC = []struct {
+	name         string
+	endpoint     htsconstants.APIEndpoint
+	handlerFunc  func(handler *requestHandler)
+	scalarParams map[string]string
+	expCode      int
+	expError     string
+}{
+	{
+		"reads data, unmatched id",
+		htsconstants.APIEndpointReadsData,
+		getReadsDataHandler,
+		map[string]string{"id": "NoDataSource.00001"},
+		http.StatusNotFound,
+		"NotFound",
+	},
+	{
+		"variants data, unmatched id",
+		htsconstants.APIEndpointVariantsData,
+		getVariantsDataHandler,
+		map[string]string{"id": "NoDataSource.00001"},
+		http.StatusNotFound,
+		"NotFound",
+	},
+	{
+		"reads ticket, unmatched id",
+		htsconstants.APIEndpointReadsTicket,
+		ticketRequestHandler,
+		map[string]string{"id": "NoDataSource.00001"},
+		http.StatusNotFound,
+		"NotFound",
+	},
+	{
+		"reads ticket, missing object",
+		htsconstants.APIEndpointReadsTicket,
+		ticketRequestHandler,
+		map[string]string{"id": "tabulamuris.missing", "format": "BAM", "referenceName": "", "class": ""},
+		http.StatusNotFound,
+		"NotFound",
+	},
+	{
+		"reads metadata, unmatched id",
+		htsconstants.APIEndpointReadsMetadata,
+		readsMetadataRequestHandler,
+		map[string]string{"id": "NoDataSource.00001"},
+		http.StatusNotFound,
+		"NotFound",
+	},
+	{
+		"reads header block, tool failure",
+		htsconstants.APIEndpointReadsData,
+		getReadsDataHandler,
+		map[string]string{"id": "tabulamuris.missing", "HtsgetBlockClass": "header", "HtsgetBlockId": "1", "HtsgetNumBlocks": "2"},
+		http.StatusInternalServerError,
+		"InternalServerError",
+	},
+}
+
+func TestHandlerErrors(t *testing.T) {
+	setListingConfig(false)
+	for _, tc := range handlerErrorTC {
+		handler, recorder := newTestRequestHandler(tc.endpoint, tc.scalarParams)
+		tc.handlerFunc(handler)
+		assert.Equal(t, tc.expCode, recorder.Code, tc.name)
+		errBody := make(map[string]map[string]string)
+		json.Unmarshal(recorder.Body.Bytes(), &errBody)
+		assert.Equal(t, tc.expError, errBody["htsget"]["error"], tc.name)
+	}
+}
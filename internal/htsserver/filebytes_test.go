@@ -0,0 +1,199 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/stretchr/testify/assert"
+)
+
+// fileBytesTestBAM path of the test BAM object served by /file-bytes
+var fileBytesTestBAM = "../../data/test/sources/tabulamuris/A1-B000168-3_57_F-1-1_R2.mus.Aligned.out.sorted.bam"
+
+// fileBytesTestID id of the test BAM object
+var fileBytesTestID = "tabulamuris.A1-B000168-3_57_F-1-1_R2"
+
+// requestFileBytes requests /file-bytes for the file of an object, with the
+// given headers
+func requestFileBytes(t *testing.T, serverURL string, id string, filePath string, headers map[string]string) (*http.Response, []byte) {
+	request, _ := http.NewRequest("GET", serverURL+"/file-bytes", nil)
+	request.Header.Set("HtsgetObjectId", id)
+	request.Header.Set("HtsgetFilePath", filePath)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	return resp, body
+}
+
+func TestFileBytesRanges(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	data, err := ioutil.ReadFile(fileBytesTestBAM)
+	assert.Nil(t, err)
+	size := len(data)
+	sizeStr := strconv.Itoa(size)
+
+	fileBytesTC := []struct {
+		rangeHeader     string
+		expStatus       int
+		expContentRange string
+		expBody         []byte
+	}{
+		{"", http.StatusOK, "", data},
+		{"bytes=0-99", http.StatusPartialContent, "bytes 0-99/" + sizeStr, data[:100]},
+		{"bytes=100-", http.StatusPartialContent, "bytes 100-" + strconv.Itoa(size-1) + "/" + sizeStr, data[100:]},
+		{"bytes=-28", http.StatusPartialContent, "bytes " + strconv.Itoa(size-28) + "-" + strconv.Itoa(size-1) + "/" + sizeStr, htsconstants.BamEOF},
+		{"bytes=0-" + strconv.Itoa(size+1000), http.StatusPartialContent, "bytes 0-" + strconv.Itoa(size-1) + "/" + sizeStr, data},
+		{"bytes=" + sizeStr + "-", http.StatusRequestedRangeNotSatisfiable, "bytes */" + sizeStr, nil},
+	}
+
+	for _, tc := range fileBytesTC {
+		headers := map[string]string{}
+		if tc.rangeHeader != "" {
+			headers["Range"] = tc.rangeHeader
+		}
+		resp, body := requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, headers)
+		assert.Equal(t, tc.expStatus, resp.StatusCode, tc.rangeHeader)
+		assert.Equal(t, tc.expContentRange, resp.Header.Get("Content-Range"), tc.rangeHeader)
+		if tc.expBody != nil {
+			assert.Equal(t, tc.expBody, body, tc.rangeHeader)
+			assert.Equal(t, strconv.Itoa(len(tc.expBody)), resp.Header.Get("Content-Length"), tc.rangeHeader)
+		}
+		if tc.expStatus != http.StatusRequestedRangeNotSatisfiable {
+			assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"), tc.rangeHeader)
+			assert.NotEmpty(t, resp.Header.Get("ETag"), tc.rangeHeader)
+			assert.NotEmpty(t, resp.Header.Get("Last-Modified"), tc.rangeHeader)
+		}
+	}
+}
+
+func TestFileBytesMultiRange(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	data, _ := ioutil.ReadFile(fileBytesTestBAM)
+	resp, body := requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, map[string]string{"Range": "bytes=0-9,-28"})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	assert.Nil(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	expParts := [][]byte{data[:10], data[len(data)-28:]}
+	for _, expPart := range expParts {
+		part, err := reader.NextPart()
+		assert.Nil(t, err)
+		partBody, _ := ioutil.ReadAll(part)
+		assert.Equal(t, expPart, partBody)
+	}
+}
+
+func TestFileBytesConditional(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	data, _ := ioutil.ReadFile(fileBytesTestBAM)
+	resp, _ := requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, nil)
+	etag := resp.Header.Get("ETag")
+
+	// the range is only served if the file is still the version of the etag
+	resp, body := requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, map[string]string{"Range": "bytes=0-9", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, data[:10], body)
+	resp, body = requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, map[string]string{"Range": "bytes=0-9", "If-Range": "\"outdated\""})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, data, body)
+
+	resp, _ = requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	resp, _ = requestFileBytes(t, server.URL, fileBytesTestID, fileBytesTestBAM, map[string]string{"If-Match": "\"outdated\""})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+}
+
+var fileBytesNotFoundTC = []struct {
+	id       string
+	filePath string
+}{
+	{fileBytesTestID + "x", "../../data/test/sources/tabulamuris/A1-B000168-3_57_F-1-1_R2x.mus.Aligned.out.sorted.bam"},
+	{"tabulamuris.", "../../data/test/sources/tabulamuris/.mus.Aligned.out.sorted.bam"},
+	{fileBytesTestID, "../../data/test/sources/tabulamuris"},
+	{fileBytesTestID, "/etc/passwd"},
+	{"", "/etc/passwd"},
+	{"1000genomes.00", "../../data/test/sources/tabulamuris/A1-B000168-3_57_F-1-1_R2.mus.Aligned.out.sorted.bam"},
+	{fileBytesTestID, ""},
+}
+
+func TestFileBytesNotFound(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// files not resolved from the object id by a data source are not served,
+	// and their path is not echoed back
+	for _, tc := range fileBytesNotFoundTC {
+		resp, body := requestFileBytes(t, server.URL, tc.id, tc.filePath, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, tc.filePath)
+		if tc.filePath != "" {
+			assert.NotContains(t, string(body), tc.filePath, tc.filePath)
+		}
+	}
+}
+
+func TestFileBytesSampleAccess(t *testing.T) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"variants": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{
+						{
+							"pattern": "^cohort\\.(?P<accession>.*)$",
+							"path":    "../../data/test/sources/tabulamuris/{accession}.mus.Aligned.out.sorted.bam",
+							"sampleAccess": map[string][]string{
+								"*": {"S1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	defer setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// the raw bytes of an object hold every sample, so they are refused
+	// whichever samples the client may get
+	resp, body := requestFileBytes(t, server.URL, "cohort.A1-B000168-3_57_F-1-1_R2", fileBytesTestBAM, nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.NotContains(t, string(body), fileBytesTestBAM)
+}
@@ -2,20 +2,21 @@ package htsserver
 
 import (
 	"bufio"
-	"fmt"
+	"context"
+	"errors"
 	"io"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
 	"strconv"
 
-	"github.com/biogo/hts/bam"
-	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/biogo/hts/bgzf"
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
 	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
 	"github.com/ga4gh/htsget-refserver/internal/htsformats"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
 )
 
 func getReadsData(writer http.ResponseWriter, request *http.Request) {
@@ -28,308 +29,368 @@ func getReadsData(writer http.ResponseWriter, request *http.Request) {
 
 // getReadsData serves the actual data from AWS back to client
 func getReadsDataHandler(handler *requestHandler) {
-	fileURL, err := htsconfig.GetObjectPath(handler.HtsReq.GetEndpoint(), handler.HtsReq.ID())
+	fileURL, err := handler.HtsReq.GetObjectPath()
 	if err != nil {
+		htserror.WriteError(handler.Writer, err)
 		return
 	}
 
-	region := &htsformats.Region{
-		Name:  handler.HtsReq.ReferenceName(),
-		Start: handler.HtsReq.Start(),
-		End:   handler.HtsReq.End(),
+	if handler.HtsReq.HtsgetBlockClass() == "header" {
+		serveHeaderBlock(handler, fileURL)
+		return
 	}
 
-	args := getSamtoolsCmdArgs(region, handler.HtsReq, fileURL)
-	cmd := exec.Command("samtools", args...)
-	pipe, err := cmd.StdoutPipe()
+	// the unplaced unmapped reads are copied from the object as they are,
+	// unless they are filtered
+	if handler.HtsReq.UnplacedUnmappedReadsRequested() && streamsRawBAM(handler.HtsReq) && handler.HtsReq.AllReadsRequested() {
+		serveUnplacedUnmapped(handler, fileURL)
+		return
+	}
 
+	region, err := handler.HtsReq.Region()
 	if err != nil {
-		msg := err.Error()
-		htserror.InternalServerError(handler.Writer, &msg)
+		htserror.WriteError(handler.Writer, htserror.NewInvalidInput(err.Error(), err))
 		return
 	}
 
-	err = cmd.Start()
+	args, err := getSamtoolsCmdArgs(region, handler.HtsReq, fileURL)
 	if err != nil {
-		msg := err.Error()
-		htserror.InternalServerError(handler.Writer, &msg)
+		if handler.cancelled() {
+			return
+		}
+		htserror.WriteError(handler.Writer, err)
 		return
 	}
+	cmd := htsexec.Command(handler.HtsReq.Context(), "samtools", args...)
+	pipe, err := cmd.StdoutPipe()
 
-	reader := bufio.NewReader(pipe)
-
-	var eofLen int
-	if handler.HtsReq.HtsgetBlockClass() == "header" {
-		eofLen = htsconstants.BamHeaderEOFLen
-	} else {
-		eofLen = htsconstants.BamEOFLen
+	if err != nil {
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run samtools", err))
+		return
 	}
 
-	if (handler.HtsReq.AllFieldsRequested() && handler.HtsReq.AllTagsRequested()) || handler.HtsReq.HtsgetBlockClass() == "header" {
-		if handler.HtsReq.HtsgetBlockClass() != "header" { // remove header
-			headerLen, err := headerLen(handler.HtsReq.ID(), fileURL)
-			handler.Writer.Header().Set("header-len", strconv.FormatInt(headerLen, 10))
-			if err != nil {
-				msg := err.Error()
-				htserror.InternalServerError(handler.Writer, &msg)
-				return
-			}
-			headerBuf := make([]byte, headerLen)
-			io.ReadFull(reader, headerBuf)
-		}
-		if handler.HtsReq.HtsgetBlockID() != handler.HtsReq.HtsgetNumBlocks() { // remove EOF if current block is not the last block
-			bufSize := 65536
-			buf := make([]byte, bufSize)
-			n, err := io.ReadFull(reader, buf)
-			if err != nil && err.Error() != "unexpected EOF" {
-				msg := err.Error()
-				htserror.InternalServerError(handler.Writer, &msg)
-				return
-			}
+	err = cmd.Start()
+	if err != nil {
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run samtools", err))
+		return
+	}
+	defer cmd.Close()
 
-			eofBuf := make([]byte, eofLen)
-			for n == bufSize {
-				copy(eofBuf, buf[n-eofLen:])
-				handler.Writer.Write(buf[:n-eofLen])
-				n, err = io.ReadFull(reader, buf)
-				if err != nil && err.Error() != "unexpected EOF" {
-					msg := err.Error()
-					htserror.InternalServerError(handler.Writer, &msg)
-					return
-				}
-				if n == bufSize {
-					handler.Writer.Write(eofBuf)
-				}
-			}
+	reader := bufio.NewReader(pipe)
+	lastBlock := handler.HtsReq.HtsgetBlockID() == handler.HtsReq.HtsgetNumBlocks()
+	handler.startStream(htsconstants.FormatBam, -1)
 
-			if n >= eofLen {
-				handler.Writer.Write(buf[:n-eofLen])
-			} else {
-				handler.Writer.Write(eofBuf[:eofLen-n])
-			}
-		} else {
-			io.Copy(handler.Writer, reader)
-		}
+	var streamErr error
+	if streamsRawBAM(handler.HtsReq) {
+		streamErr = copyBAMRecords(handler.Writer, reader, lastBlock)
 	} else {
-		columns := make([]bool, 11)
-		for _, field := range handler.HtsReq.Fields() {
-			columns[htsconstants.BamFields[field]] = true
-		}
-
-		tmpPath := htsconfig.GetTempfilePath(handler.HtsReq.ID())
-		tmp, err := htsconfig.CreateTempfile(handler.HtsReq.ID())
+		// records are masked as they stream out of samtools, and re-encoded
+		// by a second samtools reading SAM from its stdin. only a line of SAM
+		// and the pipe buffers are held at any time, and no temp files are
+		// written, so the response starts streaming immediately
+		bamCmd := htsexec.Command(handler.HtsReq.Context(), "samtools", "view", "-b", "-")
+		bamIn, err := bamCmd.StdinPipe()
 		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
+			htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run samtools", err))
 			return
 		}
-
-		/* Write the BAM Header to the temporary SAM file */
-		tmpHeaderPath := htsconfig.GetTempfilePath(handler.HtsReq.ID() + ".header.bam")
-		headerCmd := exec.Command("samtools", "view", "-H", "-O", "SAM", "-o", tmpHeaderPath, fileURL)
-		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
-		}
-		err = headerCmd.Start()
-		headerCmd.Wait()
-		f, err := os.Open(tmpHeaderPath)
-		headerReader := bufio.NewReader(f)
-		hl, _, eof := headerReader.ReadLine()
-		for ; eof == nil; hl, _, eof = headerReader.ReadLine() {
-			_, err = tmp.Write([]byte(string(hl) + "\n"))
-			if err != nil {
-				msg := err.Error()
-				htserror.InternalServerError(handler.Writer, &msg)
-				return
-			}
-		}
-
-		/* Write the custom SAM Records to the temporary SAM file */
-		l, _, eof := reader.ReadLine()
-		for ; eof == nil; l, _, eof = reader.ReadLine() {
-			if l[0] != 64 {
-				samRecord := htsformats.NewSAMRecord(string(l))
-				newSamRecord := samRecord.CustomEmit(handler.HtsReq)
-				l = []byte(newSamRecord + "\n")
-			} else {
-				l = append(l, "\n"...)
-			}
-			_, err = tmp.Write(l)
-			if err != nil {
-				msg := err.Error()
-				htserror.InternalServerError(handler.Writer, &msg)
-				return
-			}
-		}
-
-		tmp.Close()
-		bamCmd := exec.Command("samtools", "view", "-b", tmpPath)
 		bamPipe, err := bamCmd.StdoutPipe()
 		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
+			htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run samtools", err))
 			return
 		}
 
 		err = bamCmd.Start()
 		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
+			htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run samtools", err))
 			return
 		}
-
-		// remove header bytes from 'body' class data streams
-		headerByteCount, _ := headerLen(handler.HtsReq.ID(), fileURL)
-		bamReader := bufio.NewReader(bamPipe)
-		headerBuf := make([]byte, headerByteCount)
-		io.ReadFull(bamReader, headerBuf)
-		io.Copy(handler.Writer, bamReader)
-
-		err = bamCmd.Wait()
-		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
-			return
+		defer bamCmd.Close()
+
+		maskErr := make(chan error, 1)
+		go func() {
+			err := htsformats.MaskSAMStream(handler.HtsReq, reader, bamIn)
+			bamIn.Close()
+			maskErr <- err
+		}()
+
+		streamErr = copyBAMRecords(handler.Writer, bufio.NewReader(bamPipe), lastBlock)
+		if streamErr != nil {
+			// no longer read, both samtools would block on a full pipe,
+			// and the masking with them
+			bamCmd.Close()
+			cmd.Close()
 		}
+		if err := <-maskErr; streamErr == nil {
+			streamErr = err
+		}
+		if err := bamCmd.Wait(); streamErr == nil {
+			streamErr = err
+		}
+	}
+	if streamErr != nil {
+		cmd.Close()
+	}
 
-		err = htsconfig.RemoveTempfile(tmp)
-		if err != nil {
-			msg := err.Error()
-			htserror.InternalServerError(handler.Writer, &msg)
+	// samtools failing part way, e.g. on a truncated object, leaves the
+	// response incomplete
+	if err := cmd.Wait(); streamErr == nil {
+		streamErr = err
+	}
+	if streamErr != nil {
+		if handler.cancelled() {
 			return
 		}
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("samtools failed", streamErr))
 	}
-	cmd.Wait()
 }
 
-func getTempPath(id string, blockID int) (string, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return "", err
+// copyBAMRecords copies the records of a BAM stream encoded by samtools,
+// without the header, which is served by its own block. the header is
+// measured in the stream itself, as samtools may add to the header of the
+// object (e.g. an @PG line)
+//
+// Arguments
+//	writer (io.Writer): destination
+//	reader (*bufio.Reader): BAM stream, starting with its header
+//	lastBlock (bool): whether the records are the last block of the response, keeping the EOF marker
+// Returns
+//	(error): if not nil, the stream is malformed, or reading or writing failed
+func copyBAMRecords(writer io.Writer, reader *bufio.Reader, lastBlock bool) error {
+	if err := htsformats.SkipBAMHeader(reader); err != nil {
+		return err
+	}
+	if !lastBlock { // remove EOF if current block is not the last block
+		return copyWithoutTail(writer, reader, htsconstants.BamEOFLen)
 	}
-	parent := filepath.Dir(cwd)
-	tempPath := parent + "/temp/" + id + "_" + strconv.Itoa(blockID)
+	_, err := io.Copy(writer, reader)
+	return err
+}
 
-	exists, err := fileExists(parent + "/temp")
+// serveHeaderBlock serves the header block of a BAM object, from the header
+// encoded once and held in the cache
+//
+// Arguments
+//	handler (*requestHandler): data request handler
+//	fileURL (string): local file path or url of the object
+func serveHeaderBlock(handler *requestHandler, fileURL string) {
+	lastBlock := handler.HtsReq.HtsgetBlockID() == handler.HtsReq.HtsgetNumBlocks()
+	header, err := headerBlockBytes(handler.HtsReq.Context(), fileURL, lastBlock)
 	if err != nil {
-		return "", err
-	}
-	if !exists {
-		os.Mkdir(parent+"/temp/", 0755)
-	} else {
-		isDir, err := isDir(parent + "/temp")
-		if err != nil {
-			return "", err
-		}
-		if !isDir {
-			os.Mkdir(parent+"/temp/", 0755)
+		if handler.cancelled() {
+			return
 		}
+		htserror.WriteError(handler.Writer, err)
+		return
 	}
-	return tempPath, nil
+	handler.startStream(htsconstants.FormatBam, int64(len(header)))
+	handler.Writer.Write(header)
 }
 
-func getSamtoolsCmdArgs(region *htsformats.Region, htsgetReq *htsrequest.HtsgetRequest, fileURL string) []string {
-	args := []string{"view", fileURL}
-	if htsgetReq.HtsgetBlockClass() == "header" {
-		args = append(args, "-H")
-		args = append(args, "-b")
-	} else {
-		if htsgetReq.AllFieldsRequested() && htsgetReq.AllTagsRequested() {
-			args = append(args, "-b")
+// serveUnplacedUnmapped serves the body block of the unplaced unmapped reads
+// of a BAM object. their records are read from the tail of the object, at the
+// offset given by its index, and compressed into new BGZF blocks
+//
+// Arguments
+//	handler (*requestHandler): data request handler
+//	fileURL (string): local file path or url of the object
+func serveUnplacedUnmapped(handler *requestHandler, fileURL string) {
+	records, err := htsformats.UnplacedUnmappedRecords(handler.HtsReq.Context(), fileURL)
+	if err != nil {
+		if handler.cancelled() {
+			return
 		}
-		if region.ExportSamtools() != "" {
-			args = append(args, region.ExportSamtools())
+		htserror.WriteError(handler.Writer, err)
+		return
+	}
+	defer records.Close()
+	lastBlock := handler.HtsReq.HtsgetBlockID() == handler.HtsReq.HtsgetNumBlocks()
+	handler.startStream(htsconstants.FormatBam, -1)
+
+	bgzfReader, bgzfWriter := io.Pipe()
+	encodeErr := make(chan error, 1)
+	go func() {
+		err := encodeBGZF(bgzfWriter, records)
+		bgzfWriter.CloseWithError(err)
+		encodeErr <- err
+	}()
+
+	var streamErr error
+	if !lastBlock { // remove EOF if current block is not the last block
+		streamErr = copyWithoutTail(handler.Writer, bgzfReader, htsconstants.BamEOFLen)
+	} else {
+		_, streamErr = io.Copy(handler.Writer, bgzfReader)
+	}
+	bgzfReader.Close()
+	if err := <-encodeErr; streamErr == nil {
+		streamErr = err
+	}
+	if streamErr != nil {
+		if handler.cancelled() {
+			return
 		}
+		htserror.WriteError(handler.Writer, streamErr)
 	}
-	return args
 }
 
-func samToBam(tempPath string) string {
-	bamPath := tempPath + "_bam"
-	cmd := exec.Command("samtools", "view", "-h", "-b", tempPath, "-o", bamPath)
-	cmd.Run()
-	return bamPath
+// encodeBGZF compresses a stream into BGZF blocks, ending with the BGZF end
+// of file marker
+//
+// Arguments
+//	writer (io.Writer): destination
+//	reader (io.Reader): uncompressed stream
+// Returns
+//	(error): if not nil, reading or writing failed
+func encodeBGZF(writer io.Writer, reader io.Reader) error {
+	bgzfWriter := bgzf.NewWriter(writer, 1)
+	_, err := io.Copy(bgzfWriter, reader)
+	closeErr := bgzfWriter.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-func headerLen(id string, fileURL string) (int64, error) {
-	cmd := exec.Command("samtools", "view", "-H", "-b", fileURL)
-	tmpHeader, err := htsconfig.CreateTempfile(id + "_header")
+// headerBlockBytes gets the bytes of the header block of a BAM object. the
+// header ends with an end marker, which is only kept if no body block follows
+//
+// Arguments
+//	ctx (context.Context): request context
+//	fileURL (string): local file path or url of the object
+//	lastBlock (bool): whether the header block is the last block of the response
+// Returns
+//	([]byte): header block bytes
+//	(error): if not nil, the header could not be encoded
+func headerBlockBytes(ctx context.Context, fileURL string, lastBlock bool) ([]byte, error) {
+	header, err := htscache.BAMHeader(ctx, fileURL)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-
-	cmd.Stdout = tmpHeader
-	cmd.Run()
-
-	fi, err := tmpHeader.Stat()
-	if err != nil {
-		return 0, err
+	if lastBlock {
+		return header, nil
 	}
-
-	size := fi.Size() - 12
-	tmpHeader.Close()
-	htsconfig.RemoveTempfile(tmpHeader)
-	return size, nil
+	if len(header) < htsconstants.BamHeaderEOFLen {
+		return nil, errors.New("could not encode header: too short")
+	}
+	return header[:len(header)-htsconstants.BamHeaderEOFLen], nil
 }
 
-func removeHeader(path string) error {
-	fin, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer fin.Close()
-	fin.Seek(0, 0)
-	b, err := bam.NewReader(fin, 0)
-	if err != nil {
-		return err
+// getSamtoolsCmdArgs gets the arguments of the samtools view command reading
+// the records of a body block. reads are filtered by samtools itself, so
+// filtered requests are still streamed as encoded by samtools
+//
+// Arguments
+//	region (*htsregion.Region): region of the body block
+//	htsgetReq (*htsrequest.HtsgetRequest): data request
+//	fileURL (string): local file path or url of the object
+// Returns
+//	([]string): samtools arguments
+//	(error): if not nil, the read groups of the requested samples could not be resolved
+func getSamtoolsCmdArgs(region *htsregion.Region, htsgetReq *htsrequest.HtsgetRequest, fileURL string) ([]string, error) {
+	args := []string{"view", fileURL}
+	if streamsRawBAM(htsgetReq) {
+		args = append(args, "-b")
+	} else {
+		// SAM with the header, to be masked and re-encoded as BAM
+		args = append(args, "-h")
 	}
 
-	b.Header()
-	b.Read()
-	lastChunk := b.LastChunk()
-	hLen := lastChunk.Begin.File
-
-	fDest, err := os.Create(path + "_copy")
-	if err != nil {
-		return err
+	var sampleReadGroups []string
+	if !htsgetReq.AllSamplesRequested() {
+		header, err := htscache.ReadsHeader(htsgetReq.Context(), fileURL)
+		if err != nil {
+			return nil, err
+		}
+		sampleReadGroups = header.SampleReadGroups(htsgetReq.Samples())
 	}
+	filterArgs, ok := getSamtoolsFilterArgs(htsgetReq, sampleReadGroups)
+	if !ok {
+		return nil, htserror.NewInvalidInput("no read group is of a requested sample", nil)
+	}
+	args = append(args, filterArgs...)
 
-	fin.Seek(hLen, 0)
-	io.Copy(fin, fDest)
-
-	os.Remove(path)
-	os.Rename(path+"_copy", path)
-	return nil
+	if region.ExportHtslib() != "" {
+		args = append(args, region.ExportHtslib())
+	}
+	return args, nil
 }
 
-func removeEOF(tempPath string) error {
-	fi, err := os.Stat(tempPath)
-	if err != nil {
-		return err
+// getSamtoolsFilterArgs gets the samtools view options keeping only the reads
+// matching the read filtering parameters of a request. requested samples are
+// kept by the ids of their read groups
+//
+// Arguments
+//	htsgetReq (*htsrequest.HtsgetRequest): request holding the read filters
+//	sampleReadGroups ([]string): ids of the read groups of the requested samples, nil if all samples are requested
+// Returns
+//	([]string): samtools options
+//	(bool): false if no read group is both requested and of a requested sample
+func getSamtoolsFilterArgs(htsgetReq *htsrequest.HtsgetRequest, sampleReadGroups []string) ([]string, bool) {
+	args := []string{}
+	if htsgetReq.RequiredFlags() != 0 {
+		args = append(args, "-f", strconv.Itoa(htsgetReq.RequiredFlags()))
+	}
+	if htsgetReq.ExcludedFlags() != 0 {
+		args = append(args, "-F", strconv.Itoa(htsgetReq.ExcludedFlags()))
+	}
+	if htsgetReq.MinMappingQuality() > 0 {
+		args = append(args, "-q", strconv.Itoa(htsgetReq.MinMappingQuality()))
 	}
-	return os.Truncate(tempPath, fi.Size()-28)
-}
 
-// exists returns whether the given file or directory exists.
-func fileExists(path string) (bool, error) {
-	_, err := os.Stat(path)
-	if err == nil || !os.IsNotExist(err) {
-		return true, err
+	readGroups := sampleReadGroups
+	if !htsgetReq.AllReadGroupsRequested() {
+		readGroups = []string{}
+		for _, readGroup := range htsgetReq.ReadGroups() {
+			if sampleReadGroups == nil || htsutils.IsItemInArray(readGroup, sampleReadGroups) {
+				readGroups = append(readGroups, readGroup)
+			}
+		}
+	}
+	if readGroups != nil && len(readGroups) == 0 {
+		return nil, false
+	}
+	for _, readGroup := range readGroups {
+		args = append(args, "-r", readGroup)
 	}
-	return false, nil
+	return args, true
 }
 
-// isDir returns whether the given path is directory
-func isDir(path string) (bool, error) {
-	src, err := os.Stat(path)
-	if err != nil {
-		return false, err
-	}
+// streamsRawBAM indicates whether the records of a body block are streamed
+// as encoded by samtools. records are otherwise streamed as SAM, to mask
+// fields and tags, or to drop the reads an earlier block of a split region,
+// or of the same reference sequence, holds
+func streamsRawBAM(htsgetReq *htsrequest.HtsgetRequest) bool {
+	return htsgetReq.AllFieldsRequested() && htsgetReq.AllTagsRequested() && !htsgetReq.HtsgetBlockSplit() && htsgetReq.HtsgetPrevRegion() == ""
+}
 
-	if src.Mode().IsRegular() {
-		fmt.Println(path + " already exist as a file!")
-		return false, nil
+// copyWithoutTail copies a stream, except for its last n bytes, e.g. the EOF
+// marker of a BAM stream that is not the last block of a response
+//
+// Arguments
+//	writer (io.Writer): destination
+//	reader (io.Reader): source stream
+//	n (int): number of bytes to leave out at the end of the stream
+// Returns
+//	(error): if not nil, reading or writing failed
+func copyWithoutTail(writer io.Writer, reader io.Reader, n int) error {
+	buf := make([]byte, 65536+n)
+	held := 0
+	for {
+		m, err := reader.Read(buf[held:])
+		held += m
+		if held > n {
+			if _, werr := writer.Write(buf[:held-n]); werr != nil {
+				return werr
+			}
+			copy(buf, buf[held-n:held])
+			held = n
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 	}
-	return true, nil
 }
+
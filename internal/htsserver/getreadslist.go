@@ -0,0 +1,15 @@
+package htsserver
+
+import (
+	"net/http"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+)
+
+func getReadsList(writer http.ResponseWriter, request *http.Request) {
+	newRequestHandler(
+		htsconstants.GetMethod,
+		htsconstants.APIEndpointReadsList,
+		listRequestHandler,
+	).handleRequest(writer, request)
+}
@@ -0,0 +1,15 @@
+package htsserver
+
+import (
+	"net/http"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+)
+
+func getReadsMetadata(writer http.ResponseWriter, request *http.Request) {
+	newRequestHandler(
+		htsconstants.GetMethod,
+		htsconstants.APIEndpointReadsMetadata,
+		readsMetadataRequestHandler,
+	).handleRequest(writer, request)
+}
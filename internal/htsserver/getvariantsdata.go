@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"io"
 	"net/http"
-	"os/exec"
+	"strings"
 
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
 	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
 	"github.com/ga4gh/htsget-refserver/internal/htsformats"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
 )
 
@@ -24,40 +27,81 @@ func getVariantsData(writer http.ResponseWriter, request *http.Request) {
 // getVariantsData serves the actual data from AWS back to client
 func getVariantsDataHandler(handler *requestHandler) {
 
-	fileURL, err := htsconfig.GetObjectPath(handler.HtsReq.GetEndpoint(), handler.HtsReq.ID())
+	fileURL, err := handler.HtsReq.GetObjectPath()
 	if err != nil {
+		htserror.WriteError(handler.Writer, err)
 		return
 	}
 
-	command, args := constructBcftoolsCommand(handler.HtsReq, fileURL)
-	cmd := exec.Command(command, args...)
+	mask, err := variantsMask(handler.HtsReq, fileURL)
+	if err != nil {
+		if handler.cancelled() {
+			return
+		}
+		htserror.WriteError(handler.Writer, err)
+		return
+	}
+
+	region, err := handler.HtsReq.Region()
+	if err != nil {
+		htserror.WriteError(handler.Writer, htserror.NewInvalidInput(err.Error(), err))
+		return
+	}
+
+	command, args := constructBcftoolsCommand(handler.HtsReq, region, mask, fileURL)
+	cmd := htsexec.Command(handler.HtsReq.Context(), command, args...)
 	pipe, err := cmd.StdoutPipe()
 
 	if err != nil {
-		msg := err.Error()
-		htserror.InternalServerError(handler.Writer, &msg)
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run bcftools", err))
 		return
 	}
 
 	err = cmd.Start()
 	if err != nil {
-		msg := err.Error()
-		htserror.InternalServerError(handler.Writer, &msg)
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("could not run bcftools", err))
 		return
 	}
 
+	defer cmd.Close()
+
+	handler.startStream(htsconstants.FormatVcf, -1)
 	reader := bufio.NewReader(pipe)
-	io.Copy(handler.Writer, reader)
-	cmd.Wait()
+	if mask != nil {
+		err = mask.MaskVCFStream(reader, handler.Writer)
+	} else {
+		_, err = io.Copy(handler.Writer, reader)
+	}
+	waitErr := cmd.Wait()
+	if err == nil {
+		err = waitErr
+	}
+	if err != nil {
+		if handler.cancelled() {
+			return
+		}
+		htserror.WriteError(handler.Writer, htserror.NewToolFailure("bcftools failed", err))
+	}
 }
 
-func constructBcftoolsCommand(htsgetReq *htsrequest.HtsgetRequest, fileURL string) (string, []string) {
+func constructBcftoolsCommand(htsgetReq *htsrequest.HtsgetRequest, region *htsregion.Region, mask *htsformats.VCFMask, fileURL string) (string, []string) {
 	command := "bcftools"
 	args := []string{"view", fileURL}
 
 	// translate "format" param into bcftools command
 	args = append(args, "-O", "v") // request uncompressed VCF
 
+	// translate "samples" param, and the samples the client may get, into
+	// bcftools command. bcftools recomputes INFO/AC and INFO/AN for the
+	// subset
+	if mask != nil && mask.Samples() != nil {
+		if len(mask.Samples()) == 0 {
+			args = append(args, "-G")
+		} else {
+			args = append(args, "-s", strings.Join(mask.Samples(), ","))
+		}
+	}
+
 	// translate "HtsgetBlockClass" param into bcftools command
 	if htsgetReq.HtsgetBlockClass() == "header" {
 		args = append(args, "-h")
@@ -66,13 +110,30 @@ func constructBcftoolsCommand(htsgetReq *htsrequest.HtsgetRequest, fileURL strin
 
 		// translate "referenceName", "start", "end" params into bcftools command
 		if htsgetReq.ReferenceNameRequested() {
-			region := &htsformats.Region{
-				Name:  htsgetReq.ReferenceName(),
-				Start: htsgetReq.Start(),
-				End:   htsgetReq.End(),
-			}
-			args = append(args, "-r", region.ExportBcftools())
+			args = append(args, "-r", region.ExportHtslib())
 		}
 	}
 	return command, args
 }
+
+// variantsMask gets the mask for the tags, notags and samples of a variants
+// request, from the header of the object. samples the client may not get,
+// per the sample access of the data source, are always removed
+//
+// Arguments
+//	htsgetReq (*htsrequest.HtsgetRequest): variants request
+//	fileURL (string): local file path or url of the object
+// Returns
+//	(*htsformats.VCFMask): mask for the lines of the object, nil if all tags and samples are requested and allowed
+//	(error): if not nil, the header could not be read, or a requested sample is not in the object
+func variantsMask(htsgetReq *htsrequest.HtsgetRequest, fileURL string) (*htsformats.VCFMask, error) {
+	allowedSamples, restricted := htsconfig.GetAllowedSamples(htsgetReq.GetEndpoint(), htsgetReq.ID(), htsgetReq.ClientSubject())
+	if htsgetReq.AllTagsRequested() && htsgetReq.AllSamplesRequested() && !restricted {
+		return nil, nil
+	}
+	header, err := htscache.VariantsHeader(htsgetReq.Context(), fileURL)
+	if err != nil {
+		return nil, err
+	}
+	return htsformats.NewVCFMask(htsgetReq, header, allowedSamples)
+}
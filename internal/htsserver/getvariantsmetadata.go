@@ -0,0 +1,15 @@
+package htsserver
+
+import (
+	"net/http"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+)
+
+func getVariantsMetadata(writer http.ResponseWriter, request *http.Request) {
+	newRequestHandler(
+		htsconstants.GetMethod,
+		htsconstants.APIEndpointVariantsMetadata,
+		variantsMetadataRequestHandler,
+	).handleRequest(writer, request)
+}
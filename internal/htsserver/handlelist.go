@@ -0,0 +1,41 @@
+package htsserver
+
+import (
+	"encoding/json"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htslisting"
+)
+
+// listResponse is the body of a listing response
+//
+// Attributes
+//	IDs ([]string): ids of the page
+//	NextPageToken (string): token requesting the next page, omitted on the last page
+type listResponse struct {
+	IDs           []string `json:"ids"`
+	NextPageToken string   `json:"nextPageToken,omitempty"`
+}
+
+func listRequestHandler(handler *requestHandler) {
+	htsgetReq := handler.HtsReq
+	ids, err := htslisting.ListIDs(htsgetReq.Context(), handler.endpoint)
+	if err != nil {
+		if !handler.cancelled() {
+			htserror.WriteError(handler.Writer, htserror.NewInternalError("could not list ids", err))
+		}
+		return
+	}
+	page, nextPageToken, err := htslisting.Page(ids, htsgetReq.Prefix(), htsgetReq.PageSize(), htsgetReq.PageToken())
+	if err != nil {
+		msg := err.Error()
+		htserror.InvalidInput(handler.Writer, &msg)
+		return
+	}
+
+	response := listResponse{IDs: page, NextPageToken: nextPageToken}
+	writer := handler.Writer
+	writer.Header().Set(htsconstants.ContentTypeHeader.String(), htsconstants.ContentTypeHeaderJSON.String())
+	json.NewEncoder(writer).Encode(response)
+}
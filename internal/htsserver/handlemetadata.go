@@ -0,0 +1,103 @@
+package htsserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsutils"
+)
+
+// readsMetadata is the body of a reads metadata response
+//
+// Attributes
+//	ID (string): requested object id
+//	References ([]*htscache.Reference): sequence dictionary (@SQ lines)
+//	ReadGroups ([]htscache.HeaderRecord): read groups (@RG lines)
+//	Programs ([]htscache.HeaderRecord): programs (@PG lines)
+type readsMetadata struct {
+	ID         string                  `json:"id"`
+	References []*htscache.Reference   `json:"references"`
+	ReadGroups []htscache.HeaderRecord `json:"readGroups"`
+	Programs   []htscache.HeaderRecord `json:"programs"`
+}
+
+// variantsMetadata is the body of a variants metadata response
+//
+// Attributes
+//	ID (string): requested object id
+//	References ([]*htscache.Reference): contigs (##contig lines)
+//	Samples ([]string): sample names (#CHROM line)
+//	Info ([]htscache.HeaderRecord): INFO field definitions (##INFO lines)
+//	Format ([]htscache.HeaderRecord): FORMAT field definitions (##FORMAT lines)
+type variantsMetadata struct {
+	ID         string                  `json:"id"`
+	References []*htscache.Reference   `json:"references"`
+	Samples    []string                `json:"samples"`
+	Info       []htscache.HeaderRecord `json:"info"`
+	Format     []htscache.HeaderRecord `json:"format"`
+}
+
+func readsMetadataRequestHandler(handler *requestHandler) {
+	header, ok := getMetadataHeader(handler, htscache.ReadsHeader)
+	if !ok {
+		return
+	}
+	writeMetadata(handler, readsMetadata{
+		ID:         handler.HtsReq.ID(),
+		References: header.References,
+		ReadGroups: header.ReadGroups,
+		Programs:   header.Programs,
+	})
+}
+
+func variantsMetadataRequestHandler(handler *requestHandler) {
+	header, ok := getMetadataHeader(handler, htscache.VariantsHeader)
+	if !ok {
+		return
+	}
+	// samples the client may not get are not disclosed
+	samples := header.Samples
+	if allowed, restricted := htsconfig.GetAllowedSamples(handler.endpoint, handler.HtsReq.ID(), handler.HtsReq.ClientSubject()); restricted {
+		samples = []string{}
+		for _, sample := range header.Samples {
+			if htsutils.IsItemInArray(sample, allowed) {
+				samples = append(samples, sample)
+			}
+		}
+	}
+	writeMetadata(handler, variantsMetadata{
+		ID:         handler.HtsReq.ID(),
+		References: header.References,
+		Samples:    samples,
+		Info:       header.Info,
+		Format:     header.Format,
+	})
+}
+
+// getMetadataHeader gets the parsed header of the requested object, writing
+// an error if it could not be read
+func getMetadataHeader(handler *requestHandler, loadHeader func(ctx context.Context, path string) (*htscache.Header, error)) (*htscache.Header, bool) {
+	path, err := handler.HtsReq.GetObjectPath()
+	if err != nil {
+		htserror.WriteError(handler.Writer, err)
+		return nil, false
+	}
+	header, err := loadHeader(handler.HtsReq.Context(), path)
+	if err != nil {
+		if !handler.cancelled() {
+			htserror.WriteError(handler.Writer, err)
+		}
+		return nil, false
+	}
+	return header, true
+}
+
+func writeMetadata(handler *requestHandler, metadata interface{}) {
+	writer := handler.Writer
+	writer.Header().Set(htsconstants.ContentTypeHeader.String(), htsconstants.ContentTypeHeaderJSON.String())
+	json.NewEncoder(writer).Encode(metadata)
+}
@@ -1,40 +1,303 @@
 package htsserver
 
 import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htscache"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
 	"github.com/ga4gh/htsget-refserver/internal/htsdao"
 	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htsformats"
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
 	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
 )
 
 func ticketRequestHandler(handler *requestHandler) {
 	dao, err := htsdao.GetDao(handler.HtsReq)
 	if err != nil {
-		msg := "Could not determine data source path/url from request id"
-		htserror.InternalServerError(handler.Writer, &msg)
+		htserror.WriteError(handler.Writer, err)
 		return
 	}
 
+	// requested samples are checked against the object, and the samples the
+	// client may get, before the client is sent to the data endpoint
+	_, samplesRestricted := htsconfig.GetAllowedSamples(handler.endpoint, handler.HtsReq.ID(), handler.HtsReq.ClientSubject())
+	if handler.endpoint == htsconstants.APIEndpointVariantsTicket && (!handler.HtsReq.AllSamplesRequested() || samplesRestricted) {
+		path, err := handler.HtsReq.GetObjectPath()
+		if err == nil {
+			_, err = variantsMask(handler.HtsReq, path)
+		}
+		if err != nil {
+			if !handler.cancelled() {
+				htserror.WriteError(handler.Writer, err)
+			}
+			return
+		}
+	}
+
 	var urls []*htsticket.URL
 	dataEndpoint, err := handler.HtsReq.ConstructDataEndpointURL()
 	if err != nil {
-		msg := "Could not construct data url"
-		htserror.InternalServerError(handler.Writer, &msg)
+		htserror.WriteError(handler.Writer, htserror.NewInternalError("Could not construct data url", err))
+		return
 	}
 
 	if handler.HtsReq.HeaderOnlyRequested() {
 		headers := htsticket.NewHeaders().SetBlockID("1").SetNumBlocks("1").SetClassHeader()
-		url := htsticket.NewURL().SetURL(dataEndpoint.String()).SetHeaders(headers).SetClassHeader()
+		url := headerBlockURL(handler, dataEndpoint, headers)
 		urls = append(urls, url)
-	} else if handler.HtsReq.AllFieldsRequested() && handler.HtsReq.AllTagsRequested() && handler.HtsReq.AllRegionsRequested() {
-		urls = dao.GetByteRangeUrls()
+	} else if handler.HtsReq.AllFieldsRequested() && handler.HtsReq.AllTagsRequested() && handler.HtsReq.AllReadsRequested() && !samplesRestricted && handler.HtsReq.AllRegionsRequested() {
+		urls, err = dao.GetByteRangeUrls()
+		if err != nil {
+			if !handler.cancelled() {
+				htserror.WriteError(handler.Writer, err)
+			}
+			return
+		}
+	} else if handler.HtsReq.RegionsRequested() {
+		urls, err = regionsBlockURLs(handler, dataEndpoint)
+		if err != nil {
+			if !handler.cancelled() {
+				htserror.WriteError(handler.Writer, err)
+			}
+			return
+		}
+	} else if bodyRegions := splitBodyRegion(handler); bodyRegions != nil {
+		// a large region is served as a header block followed by a body
+		// block per sub-region. blocks after the first body block skip the
+		// reads an earlier block holds
+		numBlocks := strconv.Itoa(len(bodyRegions) + 1)
+		headersBlock1 := htsticket.NewHeaders().SetBlockID("1").SetNumBlocks(numBlocks).SetClassHeader()
+		urlBlock1 := headerBlockURL(handler, dataEndpoint, headersBlock1)
+		urls = append(urls, urlBlock1)
+		for i, region := range bodyRegions {
+			headers := htsticket.NewHeaders().SetBlockID(strconv.Itoa(i + 2)).SetNumBlocks(numBlocks)
+			if i > 0 {
+				headers.SetSplitHeader()
+			}
+			blockURL := htsticket.NewURL().SetURL(regionDataEndpointURL(dataEndpoint, region)).SetHeaders(headers)
+			urls = append(urls, blockURL)
+		}
 	} else {
 		headersBlock1 := htsticket.NewHeaders().SetBlockID("1").SetNumBlocks("2").SetClassHeader()
-		urlBlock1 := htsticket.NewURL().SetURL(dataEndpoint.String()).SetHeaders(headersBlock1).SetClassHeader()
+		urlBlock1 := headerBlockURL(handler, dataEndpoint, headersBlock1)
 		headersBlock2 := htsticket.NewHeaders().SetBlockID("2").SetNumBlocks("2")
 		urlBlock2 := htsticket.NewURL().SetURL(dataEndpoint.String()).SetHeaders(headersBlock2)
 		urls = append(urls, urlBlock1)
 		urls = append(urls, urlBlock2)
 	}
 
+	// urls served by this service carry the trace context, so that data
+	// requests are stitched into the trace of this ticket request
+	traceparent := htstrace.Traceparent(handler.Request.Context())
+	if traceparent != "" {
+		for _, url := range urls {
+			if url.Headers != nil && strings.HasPrefix(url.URL, htsconfig.GetHost()) {
+				url.Headers.SetTraceparent(traceparent)
+			}
+		}
+	}
+
+	htsmetrics.ObserveTicketBlocks(handler.endpoint.String(), len(urls))
 	htsticket.FinalizeTicket(handler.HtsReq.Format(), urls, handler.Writer)
 }
+
+// headerBlockURL gets the url of the header block of a ticket. the header of
+// a BAM object no larger than the configured size is inlined as a data uri,
+// saving the client a request, otherwise the url points to the data endpoint
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+//	dataEndpoint (*url.URL): data url of the request
+//	headers (*htsticket.Headers): headers of the header block
+// Returns
+//	(*htsticket.URL): header block url
+func headerBlockURL(handler *requestHandler, dataEndpoint *url.URL, headers *htsticket.Headers) *htsticket.URL {
+	if header := inlineHeader(handler, headers.BlockID == headers.NumBlocks); header != nil {
+		return htsticket.NewURL().SetDataURI(htsconstants.MediaTypeBam, header).SetClassHeader()
+	}
+	return htsticket.NewURL().SetURL(dataEndpoint.String()).SetHeaders(headers).SetClassHeader()
+}
+
+// inlineHeader gets the bytes the data endpoint would serve for the header
+// block of a BAM object, if inlining is configured and the header is small
+// enough
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+//	last (bool): whether the header block is the last block of the ticket
+// Returns
+//	([]byte): header block bytes, nil if the header block is not inlined
+func inlineHeader(handler *requestHandler, last bool) []byte {
+	htsgetReq := handler.HtsReq
+	maxSize := htsconfig.GetInlineHeaderSize(handler.endpoint)
+	if maxSize <= 0 || handler.endpoint != htsconstants.APIEndpointReadsTicket || htsgetReq.Format() != htsconstants.FormatBam {
+		return nil
+	}
+	path, err := htsgetReq.GetObjectPath()
+	if err != nil {
+		return nil
+	}
+	header, err := headerBlockBytes(htsgetReq.Context(), path, last)
+	if err != nil || int64(len(header)) > maxSize {
+		return nil
+	}
+	return header
+}
+
+// splitBodyRegion splits the region requested from an indexed BAM object
+// into sub-regions of about the configured block size each, and into at least
+// the configured number of partitions, so that clients can download a large
+// region in parallel, and retry individual blocks
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+// Returns
+//	([]*htsregion.Region): consecutive sub-regions, nil if the region is not split
+func splitBodyRegion(handler *requestHandler) []*htsregion.Region {
+	htsgetReq := handler.HtsReq
+	if !htsgetReq.ReferenceNameRequested() {
+		return nil
+	}
+	region, err := htsgetReq.Region()
+	if err != nil {
+		return nil
+	}
+	return splitRegion(handler, region)
+}
+
+// splitRegion splits a region of an indexed BAM object into sub-regions, as
+// splitBodyRegion does for the requested region
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+//	region (*htsregion.Region): region to split
+// Returns
+//	([]*htsregion.Region): consecutive sub-regions, nil if the region is not split
+func splitRegion(handler *requestHandler, region *htsregion.Region) []*htsregion.Region {
+	htsgetReq := handler.HtsReq
+	if handler.endpoint != htsconstants.APIEndpointReadsTicket || region.Name == "*" {
+		return nil
+	}
+	path, err := htsgetReq.GetObjectPath()
+	if err != nil {
+		return nil
+	}
+	blockSize := htsconfig.GetBlockSize(handler.endpoint, htsgetReq.ID())
+	partitions := htsconfig.GetPartitions(handler.endpoint)
+	regions, err := htsformats.SplitRegion(htsgetReq.Context(), path, region, blockSize, partitions)
+	if err != nil || len(regions) < 2 {
+		return nil
+	}
+	return regions
+}
+
+// regionDataEndpointURL gets the data url of a sub-region, or of one region
+// of a multi-region request, replacing the requested reference name, start
+// and end
+//
+// Arguments
+//	dataEndpoint (*url.URL): data url of the request
+//	region (*htsregion.Region): region served by the url
+// Returns
+//	(string): data url of the region
+func regionDataEndpointURL(dataEndpoint *url.URL, region *htsregion.Region) string {
+	regionURL := *dataEndpoint
+	query := regionURL.Query()
+	query.Set("referenceName", region.Name)
+	query.Del("start")
+	query.Del("end")
+	if !region.OpenStart {
+		query.Set("start", strconv.FormatInt(region.Start, 10))
+	}
+	if !region.OpenEnd {
+		query.Set("end", strconv.FormatInt(region.End, 10))
+	}
+	regionURL.RawQuery = query.Encode()
+	return regionURL.String()
+}
+
+// readsPrevRegion checks whether a merged region of a reads request follows an
+// earlier region on the same reference sequence. regions are disjoint and
+// sorted, so a read of the region overlapping any earlier region of the
+// reference also overlaps the one right before
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+//	regions ([]*htsregion.Region): merged regions, sorted
+//	i (int): index of the region
+// Returns
+//	(bool): true if the region before is on the same reference sequence
+func readsPrevRegion(handler *requestHandler, regions []*htsregion.Region, i int) bool {
+	return handler.endpoint == htsconstants.APIEndpointReadsTicket && i > 0 && regions[i-1].Name == regions[i].Name
+}
+
+// regionsBlockURLs gets the urls of a multi-region request: a header block,
+// followed by the body blocks of the requested regions. regions are sorted in
+// the order of the reference sequences of the object and merged, so that no
+// part of a reference sequence is served twice, and large regions of indexed
+// BAM objects are split as a single region would be. a read spanning two
+// disjoint regions of a reference sequence is only served by the block of
+// the first, as later blocks of the reference skip the reads overlapping the
+// region before them
+//
+// Arguments
+//	handler (*requestHandler): ticket request handler
+//	dataEndpoint (*url.URL): data url of the request
+// Returns
+//	([]*htsticket.URL): urls of the header block and body blocks, in order
+//	(error): if not nil, the header of the object could not be read
+func regionsBlockURLs(handler *requestHandler, dataEndpoint *url.URL) ([]*htsticket.URL, error) {
+	htsgetReq := handler.HtsReq
+	regions, err := htsregion.ParseRegions(htsgetReq.Regions())
+	if err != nil {
+		return nil, htserror.NewInvalidInput(err.Error(), err)
+	}
+	path, err := htsgetReq.GetObjectPath()
+	if err != nil {
+		return nil, err
+	}
+	loadHeader := htscache.ReadsHeader
+	if handler.endpoint == htsconstants.APIEndpointVariantsTicket {
+		loadHeader = htscache.VariantsHeader
+	}
+	header, err := loadHeader(htsgetReq.Context(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	mergedRegions := htsregion.MergeRegions(regions, header.ReferenceNames)
+	bodyRegions := [][]*htsregion.Region{}
+	numBlocks := 1
+	for _, region := range mergedRegions {
+		subRegions := splitRegion(handler, region)
+		if subRegions == nil {
+			subRegions = []*htsregion.Region{region}
+		}
+		bodyRegions = append(bodyRegions, subRegions)
+		numBlocks += len(subRegions)
+	}
+
+	numBlocksString := strconv.Itoa(numBlocks)
+	headers := htsticket.NewHeaders().SetBlockID("1").SetNumBlocks(numBlocksString).SetClassHeader()
+	urls := []*htsticket.URL{headerBlockURL(handler, dataEndpoint, headers)}
+	for j, subRegions := range bodyRegions {
+		for i, region := range subRegions {
+			headers := htsticket.NewHeaders().SetBlockID(strconv.Itoa(len(urls) + 1)).SetNumBlocks(numBlocksString)
+			// blocks after the first body block of a split region skip the
+			// reads an earlier block holds
+			if i > 0 {
+				headers.SetSplitHeader()
+			} else if readsPrevRegion(handler, mergedRegions, j) {
+				headers.SetPrevRegionHeader(mergedRegions[j-1].String())
+			}
+			urls = append(urls, htsticket.NewURL().SetURL(regionDataEndpointURL(dataEndpoint, region)).SetHeaders(headers))
+		}
+	}
+	return urls, nil
+}
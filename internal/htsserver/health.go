@@ -0,0 +1,33 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htshealth"
+)
+
+// getHealthz reports whether the process is healthy (liveness)
+func getHealthz(writer http.ResponseWriter, request *http.Request) {
+	writeHealthReport(writer, htshealth.Liveness())
+}
+
+// getReadyz reports whether the service can serve htsget requests
+// (readiness)
+func getReadyz(writer http.ResponseWriter, request *http.Request) {
+	writeHealthReport(writer, htshealth.Readiness(request.Context()))
+}
+
+// writeHealthReport writes the JSON breakdown of health checks, with status
+// 200 if all checks passed, otherwise 503
+func writeHealthReport(writer http.ResponseWriter, report *htshealth.Report) {
+	writer.Header().Set(htsconstants.ContentTypeHeader.String(), htsconstants.ContentTypeHeaderJSON.String())
+	writer.Header().Set("Cache-Control", "no-store")
+	if report.OK() {
+		writer.WriteHeader(http.StatusOK)
+	} else {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(writer).Encode(report)
+}
@@ -0,0 +1,53 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htshealth"
+	"github.com/stretchr/testify/assert"
+)
+
+var healthTC = []struct {
+	endpoint  string
+	expChecks []string
+}{
+	{"/healthz", []string{"config", "tempdir"}},
+	{"/readyz", []string{"config", "tempdir", "samtools", "bcftools", "dataSource"}},
+}
+
+func TestHealth(t *testing.T) {
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, tc := range healthTC {
+		resp, err := http.Get(server.URL + tc.endpoint)
+		assert.Nil(t, err)
+		report := new(htshealth.Report)
+		err = json.NewDecoder(resp.Body).Decode(report)
+		resp.Body.Close()
+		assert.Nil(t, err)
+
+		assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+		if report.OK() {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		} else {
+			assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		}
+
+		names := map[string]bool{}
+		for _, check := range report.Checks {
+			names[check.Name] = true
+			assert.Contains(t, []string{htshealth.StatusOK, htshealth.StatusFail}, check.Status)
+			if check.Status == htshealth.StatusFail {
+				assert.NotEqual(t, "", check.Message)
+			}
+		}
+		for _, name := range tc.expChecks {
+			assert.True(t, names[name], name)
+		}
+	}
+}
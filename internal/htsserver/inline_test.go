@@ -0,0 +1,108 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/stretchr/testify/assert"
+)
+
+// setInlineHeaderConfig loads a configuration serving the tabulamuris test
+// objects, inlining header blocks up to the given size
+func setInlineHeaderConfig(inlineHeaderSize int) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"reads": map[string]interface{}{
+				"inlineHeaderSize": inlineHeaderSize,
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]string{
+						{
+							"pattern": "^tabulamuris\\.(?P<accession>.*)$",
+							"path":    "../../data/test/sources/tabulamuris/{accession}.mus.Aligned.out.sorted.bam",
+						},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+}
+
+// getHeaderBlock requests a ticket, and gets the url of its header block
+func getHeaderBlock(t *testing.T, query string) *htsticket.URL {
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2" + query)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticket := new(struct {
+		Htsget *htsticket.Container `json:"htsget"`
+	})
+	json.NewDecoder(resp.Body).Decode(ticket)
+	assert.NotEmpty(t, ticket.Htsget.URLS)
+	return ticket.Htsget.URLS[0]
+}
+
+func TestInlineHeaderDisabled(t *testing.T) {
+	setInlineHeaderConfig(0)
+	defer setListingConfig(false)
+
+	url := getHeaderBlock(t, "?class=header")
+	assert.True(t, strings.Contains(url.URL, "/reads/data/tabulamuris.A1-B000168-3_57_F-1-1_R2"))
+	assert.Equal(t, htsconstants.ClassHeader, url.Class)
+	assert.Equal(t, htsconstants.ClassHeader, url.Headers.Class)
+}
+
+var inlineHeaderTC = []struct {
+	query        string
+	expEOFMarker bool
+}{
+	{"?class=header", true},
+	{"?referenceName=1", false},
+}
+
+func TestInlineHeader(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools is not installed")
+	}
+	setInlineHeaderConfig(1000000)
+	defer setListingConfig(false)
+
+	prefix := "data:" + htsconstants.MediaTypeBam + ";base64,"
+	for _, tc := range inlineHeaderTC {
+		url := getHeaderBlock(t, tc.query)
+		assert.True(t, strings.HasPrefix(url.URL, prefix), tc.query)
+		assert.Nil(t, url.Headers)
+		assert.Equal(t, htsconstants.ClassHeader, url.Class)
+
+		// the inlined header is BGZF compressed, and only ends with the EOF
+		// marker if no body block follows
+		header, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(url.URL, prefix))
+		assert.Nil(t, err)
+		assert.True(t, bytes.HasPrefix(header, []byte{0x1f, 0x8b}), tc.query)
+		assert.Equal(t, tc.expEOFMarker, bytes.HasSuffix(header, htsconstants.BamEOF), tc.query)
+	}
+
+	// headers larger than the configured size are served by the data endpoint
+	setInlineHeaderConfig(1)
+	url := getHeaderBlock(t, "?class=header")
+	assert.False(t, strings.HasPrefix(url.URL, prefix))
+}
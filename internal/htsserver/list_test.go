@@ -0,0 +1,87 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setListingConfig loads a configuration serving reads from the test
+// sources, with listing enabled or not
+func setListingConfig(enabled bool) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"reads": map[string]interface{}{
+				"listing": map[string]interface{}{"enabled": enabled},
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]string{
+						{
+							"pattern": "^tabulamuris\\.(?P<accession>.*)$",
+							"path":    "../../data/test/sources/tabulamuris/{accession}.mus.Aligned.out.sorted.bam",
+						},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+}
+
+var listTC = []struct {
+	query       string
+	expStatus   int
+	expIDs      []string
+	expNextPage bool
+}{
+	{"", http.StatusOK, []string{"tabulamuris.A1-B000168-3_57_F-1-1_R2"}, false},
+	{"?prefix=tabulamuris.A1", http.StatusOK, []string{"tabulamuris.A1-B000168-3_57_F-1-1_R2"}, false},
+	{"?prefix=1000genomes.", http.StatusOK, []string{}, false},
+	{"?pageSize=1&pageToken=dGFidWxhbXVyaXMuQTE", http.StatusOK, []string{"tabulamuris.A1-B000168-3_57_F-1-1_R2"}, false},
+	{"?pageToken=dGFidWxhbXVyaXMuWg", http.StatusOK, []string{}, false},
+	{"?pageSize=0", http.StatusBadRequest, nil, false},
+	{"?pageToken=!", http.StatusBadRequest, nil, false},
+}
+
+func TestList(t *testing.T) {
+	setListingConfig(true)
+	defer setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, tc := range listTC {
+		resp, err := http.Get(server.URL + "/reads" + tc.query)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expStatus, resp.StatusCode, tc.query)
+		if tc.expStatus == http.StatusOK {
+			body := new(listResponse)
+			json.NewDecoder(resp.Body).Decode(body)
+			assert.Equal(t, "application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+			assert.Equal(t, tc.expIDs, body.IDs)
+			assert.Equal(t, tc.expNextPage, body.NextPageToken != "")
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestListDisabled(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/reads")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+}
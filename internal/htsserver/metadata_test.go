@@ -0,0 +1,48 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetadataNotFound(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, path := range []string{"/reads/tabulamuris.NoID/metadata", "/reads/NoDataSource.00001/metadata"} {
+		resp, err := http.Get(server.URL + path)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, path)
+	}
+}
+
+func TestReadsMetadata(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools is not installed")
+	}
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2/metadata")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	metadata := new(readsMetadata)
+	json.NewDecoder(resp.Body).Decode(metadata)
+	assert.Equal(t, "tabulamuris.A1-B000168-3_57_F-1-1_R2", metadata.ID)
+	assert.NotEmpty(t, metadata.References)
+	for _, reference := range metadata.References {
+		assert.NotEqual(t, "", reference.Name)
+		assert.True(t, reference.Length > 0)
+	}
+}
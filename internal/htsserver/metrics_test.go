@@ -0,0 +1,55 @@
+package htsserver
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var metricsTC = []struct {
+	endpoint string
+	expLines []string
+}{
+	{
+		"/reads/service-info",
+		[]string{
+			"htsget_requests_total{endpoint=\"/reads/service-info\"}",
+			"htsget_request_duration_seconds_count{endpoint=\"/reads/service-info\"}",
+			"htsget_bytes_streamed_total{endpoint=\"/reads/service-info\"}",
+		},
+	},
+	{
+		"/reads/NonExistentId",
+		[]string{
+			"htsget_requests_total{endpoint=\"/reads/{id}\"}",
+			"htsget_request_errors_total{endpoint=\"/reads/{id}\",error=\"NotFound\"}",
+		},
+	},
+}
+
+func TestMetrics(t *testing.T) {
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, tc := range metricsTC {
+		resp, err := http.Get(server.URL + tc.endpoint)
+		assert.Nil(t, err)
+		resp.Body.Close()
+
+		resp, err = http.Get(server.URL + "/metrics")
+		assert.Nil(t, err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4"))
+		for _, expLine := range tc.expLines {
+			assert.Contains(t, string(body), expLine+" ")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadFilterTicket(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// filtered reads are served by the data endpoint, not byte ranges of the
+	// object
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2?minMappingQuality=30&excludedFlags=0x400&readGroups=RG1")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticket := new(struct {
+		Htsget *htsticket.Container `json:"htsget"`
+	})
+	json.NewDecoder(resp.Body).Decode(ticket)
+	assert.True(t, len(ticket.Htsget.URLS) > 1)
+	for _, blockURL := range ticket.Htsget.URLS[1:] {
+		body, err := neturl.Parse(blockURL.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, "30", body.Query().Get("minMappingQuality"))
+		assert.Equal(t, "1024", body.Query().Get("excludedFlags"))
+		assert.Equal(t, "RG1", body.Query().Get("readGroups"))
+	}
+}
+
+var readFilterInvalidTC = []string{
+	"?requiredFlags=paired",
+	"?requiredFlags=1&excludedFlags=0x3",
+	"?minMappingQuality=256",
+	"?readGroups=RG1,,RG2",
+	"?samples=NA12878,NA12878",
+	"?samples=NA00000",
+}
+
+func TestReadFilterInvalid(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, query := range readFilterInvalidTC {
+		resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2" + query)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+var samtoolsFilterArgsTC = []struct {
+	scalarParams     map[string]string
+	listParams       map[string][]string
+	sampleReadGroups []string
+	expArgs          []string
+	expOK            bool
+}{
+	{nil, nil, nil, []string{}, true},
+	{map[string]string{"requiredFlags": "0x41", "excludedFlags": "1024"}, nil, nil, []string{"-f", "65", "-F", "1024"}, true},
+	{map[string]string{"minMappingQuality": "30"}, nil, nil, []string{"-q", "30"}, true},
+	{nil, map[string][]string{"readGroups": {"RG2", "RG3"}}, nil, []string{"-r", "RG2", "-r", "RG3"}, true},
+	// samples are kept by the read groups declaring them
+	{nil, map[string][]string{"samples": {"NA12878"}}, []string{"RG1", "RG2"}, []string{"-r", "RG1", "-r", "RG2"}, true},
+	{nil, map[string][]string{"samples": {"NA12878"}, "readGroups": {"RG2", "RG3"}}, []string{"RG1", "RG2"}, []string{"-r", "RG2"}, true},
+	{nil, map[string][]string{"samples": {"NA12891"}, "readGroups": {"RG1"}}, []string{"RG3"}, nil, false},
+}
+
+func TestGetSamtoolsFilterArgs(t *testing.T) {
+	for _, tc := range samtoolsFilterArgsTC {
+		htsgetReq := htsrequest.NewHtsgetRequest()
+		for key, value := range tc.scalarParams {
+			htsgetReq.AddScalarParam(key, value)
+		}
+		for key, value := range tc.listParams {
+			htsgetReq.AddListParam(key, value)
+		}
+		args, ok := getSamtoolsFilterArgs(htsgetReq, tc.sampleReadGroups)
+		assert.Equal(t, tc.expOK, ok)
+		assert.Equal(t, tc.expArgs, args)
+	}
+}
@@ -0,0 +1,164 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/biogo/hts/bam"
+	"github.com/biogo/hts/sam"
+	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionsTicket(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools is not installed")
+	}
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// regions are sorted, and overlapping regions merged, into one body block
+	// each
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2?regions=chr2:5000-6000,chr1:100-200,chr1:150-300")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticket := new(struct {
+		Htsget *htsticket.Container `json:"htsget"`
+	})
+	json.NewDecoder(resp.Body).Decode(ticket)
+	assert.Equal(t, 3, len(ticket.Htsget.URLS))
+	expRegions := [][]string{{"chr1", "100", "300"}, {"chr2", "5000", "6000"}}
+	for i, expRegion := range expRegions {
+		blockURL := ticket.Htsget.URLS[i+1]
+		body, err := neturl.Parse(blockURL.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, expRegion, []string{body.Query().Get("referenceName"), body.Query().Get("start"), body.Query().Get("end")})
+		assert.Equal(t, "", body.Query().Get("regions"))
+		assert.Equal(t, "3", blockURL.Headers.NumBlocks)
+	}
+}
+
+// downloadBlock gets the data of a ticket url from the test server, sending
+// the headers of the url
+func downloadBlock(t *testing.T, server *httptest.Server, blockURL *htsticket.URL) []byte {
+	if strings.HasPrefix(blockURL.URL, "data:") {
+		data, err := base64.StdEncoding.DecodeString(blockURL.URL[strings.Index(blockURL.URL, ",")+1:])
+		assert.Nil(t, err)
+		return data
+	}
+	u, err := neturl.Parse(blockURL.URL)
+	assert.Nil(t, err)
+	request, _ := http.NewRequest(http.MethodGet, server.URL+u.Path+"?"+u.RawQuery, nil)
+	headersJSON, _ := json.Marshal(blockURL.Headers)
+	headers := map[string]string{}
+	json.Unmarshal(headersJSON, &headers)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, blockURL.URL)
+	data, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	return data
+}
+
+func TestRegionsDisjointReads(t *testing.T) {
+	if _, err := exec.LookPath("samtools"); err != nil {
+		t.Skip("samtools is not installed")
+	}
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// nearby disjoint regions, both overlapped by the first read on chr1
+	file, err := os.Open("../../data/test/sources/tabulamuris/A1-B000168-3_57_F-1-1_R2.mus.Aligned.out.sorted.bam")
+	assert.Nil(t, err)
+	defer file.Close()
+	reader, err := bam.NewReader(file, 0)
+	assert.Nil(t, err)
+	var read *sam.Record
+	for read == nil {
+		record, err := reader.Read()
+		assert.Nil(t, err)
+		if record.Ref != nil && record.Ref.Name() == "chr1" {
+			read = record
+		}
+	}
+	start, end := read.Start(), read.End()
+	assert.True(t, end-start > 2)
+	regions := fmt.Sprintf("chr1:%d-%d,chr1:%d-%d", start, start+1, end-1, end)
+
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2?regions=" + regions)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticket := new(struct {
+		Htsget *htsticket.Container `json:"htsget"`
+	})
+	json.NewDecoder(resp.Body).Decode(ticket)
+	assert.Equal(t, 3, len(ticket.Htsget.URLS))
+
+	// the block of the second region skips the reads of the first
+	assert.Equal(t, "", ticket.Htsget.URLS[1].Headers.Previous)
+	assert.Equal(t, fmt.Sprintf("chr1:%d-%d", start, start+1), ticket.Htsget.URLS[2].Headers.Previous)
+
+	var data bytes.Buffer
+	for _, blockURL := range ticket.Htsget.URLS {
+		data.Write(downloadBlock(t, server, blockURL))
+	}
+	reader, err = bam.NewReader(&data, 0)
+	assert.Nil(t, err)
+	served := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		served[fmt.Sprintf("%s %d %d", record.Name, record.Flags, record.Pos)]++
+	}
+	assert.Equal(t, 1, served[fmt.Sprintf("%s %d %d", read.Name, read.Flags, read.Pos)])
+	for record, count := range served {
+		assert.Equal(t, 1, count, record)
+	}
+}
+
+var regionsInvalidTC = []string{
+	"?regions=chr1:200-100",
+	"?regions=chr1:100-200,:5-10",
+	"?regions=chr1:99999999999999999999-",
+	"?regions=*:100-200",
+	"?regions=chr1:100-200&referenceName=chr1",
+	"?regions=chr1&class=header",
+}
+
+func TestRegionsInvalid(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, query := range regionsInvalidTC {
+		resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2" + query)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
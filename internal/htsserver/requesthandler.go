@@ -1,10 +1,16 @@
 package htsserver
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
 	"github.com/ga4gh/htsget-refserver/internal/htsrequest"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
 )
 
 type requestHandler struct {
@@ -25,8 +31,13 @@ func newRequestHandler(method htsconstants.HTTPMethod, endpoint htsconstants.API
 }
 
 func (reqHandler *requestHandler) stage(writer http.ResponseWriter, request *http.Request) error {
-	htsgetReq, err := htsrequest.SetAllParameters(reqHandler.method, reqHandler.endpoint, writer, request)
+	ctx, span := htstrace.Start(request.Context(), "stage", htstrace.SpanKindInternal)
+	defer span.End()
+
+	htsgetReq, err := htsrequest.SetAllParameters(reqHandler.method, reqHandler.endpoint, writer, request.WithContext(ctx))
+	recordHtsgetRequest(request, htsgetReq)
 	if err != nil {
+		span.SetError(err)
 		return err
 	}
 
@@ -37,14 +48,89 @@ func (reqHandler *requestHandler) stage(writer http.ResponseWriter, request *htt
 }
 
 func (reqHandler *requestHandler) execute() {
+	ctx, span := htstrace.Start(reqHandler.Request.Context(), "execute", htstrace.SpanKindInternal)
+	defer span.End()
+
+	reqHandler.Request = reqHandler.Request.WithContext(ctx)
+	reqHandler.HtsReq.SetContext(ctx)
 	reqHandler.handlerFunction(reqHandler)
 }
 
+// cancelled indicates whether the request context is done, that is, the
+// client disconnected or the response can no longer be written. there is no
+// point in continuing to produce the response, or writing an error
+func (reqHandler *requestHandler) cancelled() bool {
+	return reqHandler.Request.Context().Err() != nil
+}
+
 func (reqHandler *requestHandler) handleRequest(writer http.ResponseWriter, request *http.Request) error {
+	start := time.Now()
+	respWriter, ok := writer.(*responseWriter)
+	if !ok {
+		respWriter = newResponseWriter(writer)
+		writer = respWriter
+	}
+
+	// continue the trace of the client (e.g. the ticket request that issued
+	// a data url), or start a new one
+	ctx := htstrace.Extract(request.Context(), request.Header)
+	ctx, span := htstrace.Start(ctx, reqHandler.method.String()+" "+reqHandler.endpoint.String(), htstrace.SpanKindServer)
+	span.SetAttribute("http.method", reqHandler.method.String())
+	span.SetAttribute("http.route", reqHandler.endpoint.String())
+
+	// the request context is cancelled when the client disconnects, or when
+	// the response can no longer be written. subprocesses, remote requests
+	// and temp files scoped to it are then abandoned and cleaned up
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	respWriter.cancelOnWriteError(cancel)
+	request = request.WithContext(ctx)
+
+	defer func() {
+		span.SetAttribute("http.status_code", respWriter.status)
+		if respWriter.htsgetError != "" {
+			span.SetError(errors.New(respWriter.htsgetError + ": " + respWriter.htsgetErrorMessage))
+		} else if respWriter.writeError != nil {
+			span.SetError(respWriter.writeError)
+		} else if request.Context().Err() != nil {
+			span.SetError(request.Context().Err())
+		}
+		span.End()
+		htsmetrics.ObserveRequest(reqHandler.endpoint.String(), respWriter.htsgetError, respWriter.bytesWritten, start)
+	}()
+
 	stagingErr := reqHandler.stage(writer, request)
 	if stagingErr != nil {
 		return stagingErr
 	}
 	reqHandler.execute()
+
+	// the server closes the connection, or resets the stream, without
+	// completing the response
+	if respWriter.mustAbort() {
+		panic(http.ErrAbortHandler)
+	}
 	return nil
 }
+
+// startStream sets the headers of a data response before its body is
+// written: the media type of the format, and the content length if it is
+// known. otherwise, the response is chunked, and clients accepting trailers
+// are told an error raised part way will be reported in the Htsget-Error
+// trailer
+//
+//	Type: requestHandler
+// Arguments
+//	format (string): format of the data, e.g. BAM
+//	contentLength (int64): number of bytes of the body, -1 if unknown
+func (reqHandler *requestHandler) startStream(format string, contentLength int64) {
+	header := reqHandler.Writer.Header()
+	header.Set("Content-Type", htsconstants.MediaType(format))
+	if contentLength >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+		return
+	}
+	if respWriter, ok := reqHandler.Writer.(*responseWriter); ok && acceptsTrailers(reqHandler.Request) {
+		respWriter.declareErrorTrailer()
+	}
+}
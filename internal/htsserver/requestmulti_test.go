@@ -88,8 +88,8 @@ func downloadFilepart(server *httptest.Server, i int, ticketURL *htsticket.URL,
 	request, _ := http.NewRequest("GET", ticketURL.URL, nil)
 
 	h := ticketURL.Headers
-	headerKeys := []string{"HtsgetBlockId", "HtsgetNumBlocks", "Range", "HtsgetBlockClass", "HtsgetFilePath"}
-	headerVals := []string{h.BlockID, h.NumBlocks, h.Range, h.Class, h.FilePath}
+	headerKeys := []string{"HtsgetBlockId", "HtsgetNumBlocks", "Range", "HtsgetBlockClass", "HtsgetFilePath", "HtsgetObjectId"}
+	headerVals := []string{h.BlockID, h.NumBlocks, h.Range, h.Class, h.FilePath, h.ObjectID}
 	for a := range headerKeys {
 		if headerVals[a] != "" {
 			request.Header.Set(headerKeys[a], headerVals[a])
@@ -152,11 +152,11 @@ func TestHTTPRequestMulti(t *testing.T) {
 	for _, tc := range httpRequestMultiTC {
 		// create the temp outputfile that htsget data response blocks will be
 		// written to
-		outputFilepath := htsconfig.GetTempfilePath("testoutput")
 		outputFile, err := htsconfig.CreateTempfile("testoutput")
 		if err != nil {
 			t.Fatal(err)
 		}
+		outputFilepath := outputFile.Name()
 
 		// get the htsget ticket from an initial request
 		writer := bufio.NewWriter(outputFile)
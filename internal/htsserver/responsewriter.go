@@ -0,0 +1,115 @@
+package htsserver
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// trailerHtsgetError trailer reporting an htsget error raised after a data
+// response started streaming, sent to clients accepting trailers
+const trailerHtsgetError = "Htsget-Error"
+
+// responseWriter wraps the http.ResponseWriter passed to route handlers,
+// keeping track of the status code, number of bytes written, and the htsget
+// error (if any) written to the response. an error written after the status
+// has been sent (e.g. a tool failing part way through a data stream) cannot
+// change the status: its body is discarded, and it is reported in the
+// Htsget-Error trailer, or by aborting the response
+type responseWriter struct {
+	http.ResponseWriter
+	status             int
+	bytesWritten       int64
+	wroteHeader        bool
+	failedLate         bool
+	htsgetError        string
+	htsgetErrorMessage string
+	writeError         error
+	cancel             context.CancelFunc
+}
+
+func newResponseWriter(writer http.ResponseWriter) *responseWriter {
+	respWriter := new(responseWriter)
+	respWriter.ResponseWriter = writer
+	respWriter.status = http.StatusOK
+	return respWriter
+}
+
+func (respWriter *responseWriter) WriteHeader(status int) {
+	if respWriter.wroteHeader {
+		if status >= http.StatusBadRequest {
+			respWriter.failedLate = true
+		}
+		return
+	}
+	respWriter.status = status
+	respWriter.wroteHeader = true
+	respWriter.ResponseWriter.WriteHeader(status)
+}
+
+func (respWriter *responseWriter) Write(p []byte) (int, error) {
+	if respWriter.failedLate {
+		return len(p), nil
+	}
+	if !respWriter.wroteHeader {
+		respWriter.WriteHeader(http.StatusOK)
+	}
+	n, err := respWriter.ResponseWriter.Write(p)
+	respWriter.bytesWritten += int64(n)
+	if err != nil && respWriter.writeError == nil {
+		respWriter.writeError = err
+		if respWriter.cancel != nil {
+			respWriter.cancel()
+		}
+	}
+	return n, err
+}
+
+// cancelOnWriteError cancels the request context as soon as a write to the
+// client fails (e.g. the client disconnected, or the write timeout passed),
+// so that the work producing the response is abandoned
+func (respWriter *responseWriter) cancelOnWriteError(cancel context.CancelFunc) {
+	respWriter.cancel = cancel
+}
+
+// Flush sends any buffered data to the client, if supported by the wrapped
+// ResponseWriter
+func (respWriter *responseWriter) Flush() {
+	if flusher, ok := respWriter.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// RecordHtsgetError implements htserror.ErrorRecorder. the detail is kept
+// for the access log, while the trailer only reports the client message
+func (respWriter *responseWriter) RecordHtsgetError(err string, message string, detail string) {
+	respWriter.htsgetError = err
+	respWriter.htsgetErrorMessage = detail
+	if respWriter.wroteHeader {
+		respWriter.Header().Set(trailerHtsgetError, err+": "+message)
+	}
+}
+
+// declareErrorTrailer announces the Htsget-Error trailer before the status
+// is sent, so that an error raised while streaming can be reported in it
+func (respWriter *responseWriter) declareErrorTrailer() {
+	respWriter.Header().Set("Trailer", trailerHtsgetError)
+}
+
+// mustAbort indicates whether an error was raised after the status was sent,
+// and cannot be reported in a trailer. the response must then be aborted, so
+// that the client does not mistake the truncated body for a complete one
+func (respWriter *responseWriter) mustAbort() bool {
+	return respWriter.failedLate && respWriter.Header().Get("Trailer") != trailerHtsgetError
+}
+
+// acceptsTrailers indicates whether a client accepts trailers in a chunked
+// response, as announced by the TE: trailers request header
+func acceptsTrailers(request *http.Request) bool {
+	for _, te := range strings.Split(request.Header.Get("TE"), ",") {
+		if strings.EqualFold(strings.TrimSpace(te), "trailers") {
+			return true
+		}
+	}
+	return false
+}
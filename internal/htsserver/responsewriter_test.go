@@ -0,0 +1,125 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htserror"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// newStreamTestServer serves a data response streaming the given body,
+// failing with a tool failure once it is written if fail is set
+func newStreamTestServer(body string, contentLength int64, fail bool) *httptest.Server {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(accessLog)
+	router.Get("/stream", func(writer http.ResponseWriter, request *http.Request) {
+		newRequestHandler(
+			htsconstants.GetMethod,
+			htsconstants.APIEndpointReadsServiceInfo,
+			func(handler *requestHandler) {
+				handler.startStream(htsconstants.FormatBam, contentLength)
+				if body != "" {
+					handler.Writer.Write([]byte(body))
+				}
+				if fail {
+					htserror.WriteError(handler.Writer, htserror.NewToolFailure("samtools failed", errors.New("exit status 1")))
+				}
+			},
+		).handleRequest(writer, request)
+	})
+	return httptest.NewServer(router)
+}
+
+func TestStreamHeaders(t *testing.T) {
+	server := newStreamTestServer("BAM\x01", 4, false)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "BAM\x01", string(body))
+	assert.Equal(t, htsconstants.MediaTypeBam, resp.Header.Get("Content-Type"))
+	assert.Equal(t, int64(4), resp.ContentLength)
+}
+
+func TestStreamEarlyFailure(t *testing.T) {
+	server := newStreamTestServer("", -1, true)
+	defer server.Close()
+
+	// nothing was streamed, the error is the response
+	resp, err := http.Get(server.URL + "/stream")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	errBody := make(map[string]map[string]string)
+	json.NewDecoder(resp.Body).Decode(&errBody)
+	assert.Equal(t, "InternalServerError", errBody["htsget"]["error"])
+}
+
+func TestStreamLateFailureTrailer(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	htslog.SetLogger(htslog.NewLogger(buffer, htslog.LevelInfo))
+	defer htslog.SetLogger(htslog.NewLogger(new(bytes.Buffer), htslog.LevelError))
+	server := newStreamTestServer("partial", -1, true)
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	request.Header.Set("TE", "trailers")
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "partial", string(body))
+	assert.Equal(t, "InternalServerError: samtools failed", resp.Trailer.Get(trailerHtsgetError))
+
+	// the cause of the failure is only logged
+	server.Close()
+	entry := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "samtools failed: exit status 1", entry["htsget_error_message"])
+}
+
+func TestStreamLateFailureAbort(t *testing.T) {
+	server := newStreamTestServer("partial", -1, true)
+	defer server.Close()
+
+	// the client not accepting trailers, the response is cut short: either
+	// before the status, or part way through the body
+	resp, err := http.Get(server.URL + "/stream")
+	if err == nil {
+		defer resp.Body.Close()
+		_, err = ioutil.ReadAll(resp.Body)
+	}
+	assert.NotNil(t, err)
+}
+
+func TestStreamCompleteTrailer(t *testing.T) {
+	server := newStreamTestServer("complete", -1, false)
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	request.Header.Set("TE", "trailers")
+	resp, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, "complete", string(body))
+	assert.Equal(t, "", resp.Trailer.Get(trailerHtsgetError))
+}
@@ -7,13 +7,17 @@ import (
 	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
 
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 )
 
 // SetRouter sets up and returns a go-chi router to caller
 func SetRouter() (*chi.Mux, error) {
 	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(accessLog)
 
 	// serve index.html at root of api
 	staticPath, err := filepath.Abs("./")
@@ -30,6 +34,10 @@ func SetRouter() (*chi.Mux, error) {
 		router.Get(htsconstants.APIEndpointReadsTicket.String(), getReadsTicket)
 		router.Get(htsconstants.APIEndpointReadsData.String(), getReadsData)
 		router.Get(htsconstants.APIEndpointReadsServiceInfo.String(), getReadsServiceInfo)
+		router.Get(htsconstants.APIEndpointReadsMetadata.String(), getReadsMetadata)
+		if htsconfig.IsListingEnabled(htsconstants.APIEndpointReadsList) {
+			router.Get(htsconstants.APIEndpointReadsList.String(), getReadsList)
+		}
 	}
 
 	// if variants enabled, add variants routes
@@ -37,8 +45,15 @@ func SetRouter() (*chi.Mux, error) {
 		router.Get(htsconstants.APIEndpointVariantsTicket.String(), getVariantsTicket)
 		router.Get(htsconstants.APIEndpointVariantsData.String(), getVariantsData)
 		router.Get(htsconstants.APIEndpointVariantsServiceInfo.String(), getVariantsServiceInfo)
+		router.Get(htsconstants.APIEndpointVariantsMetadata.String(), getVariantsMetadata)
+		if htsconfig.IsListingEnabled(htsconstants.APIEndpointVariantsList) {
+			router.Get(htsconstants.APIEndpointVariantsList.String(), getVariantsList)
+		}
 	}
 
 	router.Get(htsconstants.APIEndpointFileBytes.String(), getFileBytes)
+	router.Get(htsconstants.APIEndpointMetrics.String(), htsmetrics.Handler)
+	router.Get(htsconstants.APIEndpointHealthz.String(), getHealthz)
+	router.Get(htsconstants.APIEndpointReadyz.String(), getReadyz)
 	return router, err
 }
@@ -0,0 +1,133 @@
+package htsserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/stretchr/testify/assert"
+)
+
+// setVariantsSamplesConfig loads a configuration serving the test cohort VCF,
+// with the given sample access, nil if clients may get all samples
+func setVariantsSamplesConfig(sampleAccess map[string][]string) {
+	source := map[string]interface{}{
+		"pattern": "^cohort\\.(?P<accession>.*)$",
+		"path":    "../../data/test/sources/variants/{accession}.vcf",
+	}
+	if sampleAccess != nil {
+		source["sampleAccess"] = sampleAccess
+	}
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"variants": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]interface{}{source},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+}
+
+// getVariantsRecords requests the body of the test cohort from the variants
+// data endpoint, and gets the INFO and sample columns of its records
+func getVariantsRecords(t *testing.T, query string) [][]string {
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/variants/data/cohort.cohort" + query)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, query)
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+	records := [][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			records = append(records, strings.Split(line, "\t")[7:])
+		}
+	}
+	return records
+}
+
+// infoValues gets the values of the allele counts and frequencies of an INFO
+// column, in the order AC, AN, AF, empty if absent
+func infoValues(info string) []string {
+	values := map[string]string{}
+	for _, field := range strings.Split(info, ";") {
+		if i := strings.Index(field, "="); i >= 0 {
+			values[field[:i]] = field[i+1:]
+		}
+	}
+	return []string{values["AC"], values["AN"], values["AF"]}
+}
+
+var variantsSamplesTC = []struct {
+	query      string
+	expInfo    [][]string
+	expSamples int
+}{
+	{"", [][]string{{"4", "6", "0.667"}, {"1", "6", "0.167"}}, 3},
+	{"?samples=S3,S1", [][]string{{"3", "4", "0.75"}, {"0", "4", "0"}}, 2},
+	{"?samples=S2", [][]string{{"1", "2", "0.5"}, {"1", "2", "0.5"}}, 1},
+}
+
+func TestVariantsSamplesInfo(t *testing.T) {
+	if _, err := exec.LookPath("bcftools"); err != nil {
+		t.Skip("bcftools is not installed")
+	}
+	setVariantsSamplesConfig(nil)
+	defer setListingConfig(false)
+
+	// the allele counts and frequencies of a subset are those of its samples
+	for _, tc := range variantsSamplesTC {
+		records := getVariantsRecords(t, tc.query)
+		assert.Equal(t, len(tc.expInfo), len(records), tc.query)
+		for i, record := range records {
+			assert.Equal(t, tc.expInfo[i], infoValues(record[0]), tc.query)
+			assert.Equal(t, tc.expSamples, len(record)-2, tc.query)
+		}
+	}
+}
+
+var variantsSampleAccessTC = []struct {
+	allowedSamples []string
+	expInfo        [][]string
+	expSamples     int
+}{
+	{[]string{"S2"}, [][]string{{"1", "2", "0.5"}, {"1", "2", "0.5"}}, 1},
+	// without genotypes, there are no counts to return, nor a FORMAT column
+	{[]string{}, [][]string{{"", "", ""}, {"", "", ""}}, -1},
+}
+
+func TestVariantsSampleAccessInfo(t *testing.T) {
+	if _, err := exec.LookPath("bcftools"); err != nil {
+		t.Skip("bcftools is not installed")
+	}
+	defer setListingConfig(false)
+
+	// the allele counts and frequencies are those of the samples the client
+	// may get, not of the whole cohort
+	for _, tc := range variantsSampleAccessTC {
+		setVariantsSamplesConfig(map[string][]string{"*": tc.allowedSamples})
+		records := getVariantsRecords(t, "")
+		assert.Equal(t, len(tc.expInfo), len(records), tc.allowedSamples)
+		for i, record := range records {
+			assert.Equal(t, tc.expInfo[i], infoValues(record[0]), tc.allowedSamples)
+			assert.Equal(t, tc.expSamples, len(record)-2, tc.allowedSamples)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package htsserver
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+)
+
+// NewServer instantiates the http server for the configured port, with the
+// configured read, write and idle timeouts, and TLS settings if enabled
+//
+// Arguments
+//	handler (http.Handler): router serving all requests
+// Returns
+//	(*http.Server): the unstarted server
+//	(error): if not nil, the TLS settings are invalid
+func NewServer(handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:         ":" + htsconfig.GetPort(),
+		Handler:      handler,
+		ReadTimeout:  htsconfig.GetReadTimeout(),
+		WriteTimeout: htsconfig.GetWriteTimeout(),
+		IdleTimeout:  htsconfig.GetIdleTimeout(),
+	}
+	if htsconfig.IsTLSEnabled() {
+		tlsConfig, err := newTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		server.TLSConfig = tlsConfig
+	}
+	return server, nil
+}
+
+// ListenAndServe serves requests on the server address, over HTTPS if TLS
+// is enabled, otherwise over plain HTTP
+//
+// Arguments
+//	server (*http.Server): server instantiated by NewServer
+// Returns
+//	(error): the error that stopped the server
+func ListenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// certificates are already loaded into the TLS configuration
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// Shutdown gracefully stops the server. new connections are refused, and
+// in-flight requests are given until the timeout to complete. once it has
+// passed, any samtools/bcftools subprocesses still running are killed and
+// the remaining connections closed. finally, temporary files left behind by
+// interrupted requests are removed
+//
+// Arguments
+//	server (*http.Server): running server
+//	timeout (time.Duration): time given to in-flight requests to complete
+// Returns
+//	(error): if not nil, in-flight requests did not complete before the timeout
+func Shutdown(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		nKilled := htsexec.KillAll()
+		server.Close()
+		htslog.Warn("in-flight requests did not complete before the shutdown timeout", htslog.Fields{
+			"timeout_s":           timeout.Seconds(),
+			"subprocesses_killed": nKilled,
+		})
+	}
+
+	nRemoved := htsconfig.RemoveAllTempfiles()
+	if nRemoved > 0 {
+		htslog.Info("removed temporary files left by interrupted requests", htslog.Fields{
+			"tempfiles_removed": nRemoved,
+		})
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package htsserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsexec"
+	"github.com/stretchr/testify/assert"
+)
+
+// startTestServer starts a server with the handler on a free local port
+func startTestServer(handler http.HandlerFunc) (*http.Server, string) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	server, _ := NewServer(handler)
+	go server.Serve(listener)
+	return server, "http://" + listener.Addr().String()
+}
+
+func TestNewServer(t *testing.T) {
+	server, err := NewServer(http.NotFoundHandler())
+	assert.Nil(t, err)
+	assert.Nil(t, server.TLSConfig)
+	assert.Equal(t, ":"+htsconfig.GetPort(), server.Addr)
+	assert.Equal(t, htsconfig.GetReadTimeout(), server.ReadTimeout)
+	assert.Equal(t, htsconfig.GetWriteTimeout(), server.WriteTimeout)
+	assert.Equal(t, htsconfig.GetIdleTimeout(), server.IdleTimeout)
+}
+
+func TestShutdownDrainsRequests(t *testing.T) {
+	started := make(chan bool)
+	server, url := startTestServer(func(writer http.ResponseWriter, request *http.Request) {
+		started <- true
+		time.Sleep(200 * time.Millisecond)
+		writer.Write([]byte("complete"))
+	})
+
+	statusCodes := make(chan int)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			statusCodes <- 0
+			return
+		}
+		resp.Body.Close()
+		statusCodes <- resp.StatusCode
+	}()
+
+	<-started
+	assert.Nil(t, Shutdown(server, 5*time.Second))
+	assert.Equal(t, http.StatusOK, <-statusCodes)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	started := make(chan bool)
+	waitErrs := make(chan error, 1)
+	var tmp *os.File
+	server, url := startTestServer(func(writer http.ResponseWriter, request *http.Request) {
+		tmp, _ = htsconfig.CreateTempfile("server-test-shutdown")
+		cmd := htsexec.Command(context.Background(), "sleep", "30")
+		cmd.Start()
+		started <- true
+		waitErrs <- cmd.Wait()
+	})
+
+	go func() {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	assert.NotNil(t, Shutdown(server, 100*time.Millisecond))
+	assert.NotNil(t, <-waitErrs)
+	_, err := os.Stat(tmp.Name())
+	assert.True(t, os.IsNotExist(err))
+}
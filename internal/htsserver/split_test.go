@@ -0,0 +1,68 @@
+package htsserver
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsregion"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var copyWithoutTailTC = []struct {
+	input string
+	n     int
+	exp   string
+}{
+	{"abcdefghij", 3, "abcdefg"},
+	{"abcdefghij", 0, "abcdefghij"},
+	{"abc", 3, ""},
+	{"ab", 3, ""},
+	{strings.Repeat("x", 200000) + "EOF", 3, strings.Repeat("x", 200000)},
+}
+
+func TestCopyWithoutTail(t *testing.T) {
+	for _, tc := range copyWithoutTailTC {
+		var buf bytes.Buffer
+		err := copyWithoutTail(&buf, strings.NewReader(tc.input), tc.n)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.exp, buf.String())
+	}
+}
+
+var regionDataEndpointURLTC = []struct {
+	rawURL string
+	region *htsregion.Region
+	exp    string
+}{
+	{
+		"http://localhost:3000/reads/data/A1?referenceName=chr1&start=0&end=100",
+		htsregion.NewRegion("chr1", 0, 49),
+		"http://localhost:3000/reads/data/A1?end=49&referenceName=chr1&start=0",
+	},
+	{
+		"http://localhost:3000/reads/data/A1?referenceName=chr1",
+		&htsregion.Region{Name: "chr1", Start: 50, OpenEnd: true},
+		"http://localhost:3000/reads/data/A1?referenceName=chr1&start=50",
+	},
+	{
+		"http://localhost:3000/reads/data/A1?referenceName=chr1&start=10",
+		&htsregion.Region{Name: "chr1", End: 49, OpenStart: true},
+		"http://localhost:3000/reads/data/A1?end=49&referenceName=chr1",
+	},
+	{
+		"http://localhost:3000/variants/data/A1?samples=S1",
+		htsregion.NewRegion("chr2", 5000, 6000),
+		"http://localhost:3000/variants/data/A1?end=6000&referenceName=chr2&samples=S1&start=5000",
+	},
+}
+
+func TestRegionDataEndpointURL(t *testing.T) {
+	for _, tc := range regionDataEndpointURLTC {
+		dataEndpoint, _ := url.Parse(tc.rawURL)
+		assert.Equal(t, tc.exp, regionDataEndpointURL(dataEndpoint, tc.region))
+		assert.Equal(t, tc.rawURL, dataEndpoint.String())
+	}
+}
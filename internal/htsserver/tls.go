@@ -0,0 +1,117 @@
+package htsserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+)
+
+// tlsVersions maps configurable minimum versions to TLS protocol versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsCipherSuites maps configurable cipher suite names to TLS 1.0-1.2 cipher
+// suites. TLS 1.3 suites are not configurable
+var tlsCipherSuites = map[string]uint16{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384":       tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384":         tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305":        tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256": tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305":          tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256":   tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256":       tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256":         tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	"TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	"TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA":          tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	"TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA":            tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	"TLS_RSA_WITH_AES_128_GCM_SHA256":               tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_RSA_WITH_AES_256_GCM_SHA384":               tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+}
+
+// tlsClientAuthTypes maps configurable client certificate policies to client
+// authentication types. client certificates are always verified against the
+// client CA bundle when presented
+var tlsClientAuthTypes = map[string]tls.ClientAuthType{
+	"":         tls.NoClientCert,
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+// newTLSConfig builds the server TLS configuration from the "tls" server
+// props: the server certificate and key, minimum protocol version, allowed
+// cipher suites, and client certificate verification
+//
+// Returns
+//	(*tls.Config): server TLS configuration
+//	(error): if not nil, the configured settings or files are invalid
+func newTLSConfig() (*tls.Config, error) {
+	certificate, err := tls.LoadX509KeyPair(htsconfig.GetTLSCertFile(), htsconfig.GetTLSKeyFile())
+	if err != nil {
+		return nil, errors.New("could not load TLS certificate/key: " + err.Error())
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+	}
+
+	minVersion, ok := tlsVersions[htsconfig.GetTLSMinVersion()]
+	if !ok {
+		return nil, errors.New("unsupported TLS minVersion: " + htsconfig.GetTLSMinVersion())
+	}
+	tlsConfig.MinVersion = minVersion
+
+	for _, name := range htsconfig.GetTLSCipherSuites() {
+		cipherSuite, ok := tlsCipherSuites[strings.ToUpper(name)]
+		if !ok {
+			return nil, errors.New("unsupported TLS cipher suite: " + name)
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, cipherSuite)
+	}
+
+	clientAuth, ok := tlsClientAuthTypes[htsconfig.GetTLSClientAuth()]
+	if !ok {
+		return nil, errors.New("unsupported TLS clientAuth: " + htsconfig.GetTLSClientAuth())
+	}
+	tlsConfig.ClientAuth = clientAuth
+	if clientAuth != tls.NoClientCert {
+		clientCAs, err := loadCertPool(htsconfig.GetTLSClientCAFile())
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+	}
+	return tlsConfig, nil
+}
+
+// loadCertPool loads a PEM-encoded CA bundle into a certificate pool
+//
+// Arguments
+//	path (string): path to the CA bundle
+// Returns
+//	(*x509.CertPool): pool of CA certificates
+//	(error): if not nil, the bundle could not be read or held no certificates
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, errors.New("TLS clientCA must be set to verify client certificates")
+	}
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("could not read TLS clientCA: " + err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in TLS clientCA: " + path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,216 @@
+package htsserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htslog"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPKI certificates and keys issued by a test CA, written as PEM files
+type testPKI struct {
+	dir        string
+	caPool     *x509.CertPool
+	client     tls.Certificate
+	caFile     string
+	serverCert string
+	serverKey  string
+}
+
+// issueCertificate creates a certificate signed by the parent (self-signed
+// if parent is nil), returning the certificate and its private key
+func issueCertificate(template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key
+}
+
+// writePEM writes a certificate or private key to a PEM file
+func writePEM(path string, blockType string, der []byte) {
+	ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+}
+
+func newTestPKI() *testPKI {
+	pki := new(testPKI)
+	pki.dir, _ = ioutil.TempDir("", "htsserver-tls")
+	notAfter := time.Now().Add(time.Hour)
+
+	caCert, caKey := issueCertificate(&x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "htsget test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	pki.caPool = x509.NewCertPool()
+	pki.caPool.AddCert(caCert)
+	pki.caFile = filepath.Join(pki.dir, "ca.pem")
+	writePEM(pki.caFile, "CERTIFICATE", caCert.Raw)
+
+	serverCert, serverKey := issueCertificate(&x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, caKey)
+	pki.serverCert = filepath.Join(pki.dir, "server.pem")
+	pki.serverKey = filepath.Join(pki.dir, "server-key.pem")
+	writePEM(pki.serverCert, "CERTIFICATE", serverCert.Raw)
+	serverKeyDER, _ := x509.MarshalECPrivateKey(serverKey)
+	writePEM(pki.serverKey, "EC PRIVATE KEY", serverKeyDER)
+
+	clientCert, clientKey := issueCertificate(&x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "researcher", Organization: []string{"Hospital"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, caKey)
+	pki.client = tls.Certificate{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}
+	return pki
+}
+
+// setTLSProps loads a configuration with the given "tls" server props
+func setTLSProps(tlsProps map[string]interface{}) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{"tls": tlsProps},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+}
+
+// resetTLSProps loads the default configuration
+func resetTLSProps() {
+	htsconfig.SetConfigFile(nil)
+	htsconfig.LoadConfig()
+}
+
+var newTLSConfigErrorTC = []struct {
+	tlsProps map[string]interface{}
+}{
+	{map[string]interface{}{"minVersion": "1.4"}},
+	{map[string]interface{}{"cipherSuites": []string{"TLS_RSA_WITH_RC4_128_SHA"}}},
+	{map[string]interface{}{"clientAuth": "always"}},
+	{map[string]interface{}{"clientAuth": "require"}},
+	{map[string]interface{}{"clientAuth": "require", "clientCA": "nonexistent.pem"}},
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	pki := newTestPKI()
+	defer os.RemoveAll(pki.dir)
+	defer resetTLSProps()
+
+	setTLSProps(map[string]interface{}{"cert": pki.serverCert, "key": "nonexistent.pem"})
+	_, err := NewServer(http.NotFoundHandler())
+	assert.NotNil(t, err)
+
+	for _, tc := range newTLSConfigErrorTC {
+		tc.tlsProps["cert"] = pki.serverCert
+		tc.tlsProps["key"] = pki.serverKey
+		setTLSProps(tc.tlsProps)
+		_, err := NewServer(http.NotFoundHandler())
+		assert.NotNil(t, err, tc.tlsProps)
+	}
+}
+
+var mutualTLSTC = []struct {
+	tlsProps         map[string]interface{}
+	clientCert       bool
+	clientMaxVersion uint16
+	expOK            bool
+	expSubject       string
+}{
+	// server-only TLS, client certificates are not requested
+	{map[string]interface{}{}, true, 0, true, ""},
+	// client certificate verified if presented
+	{map[string]interface{}{"clientAuth": "optional"}, false, 0, true, ""},
+	{map[string]interface{}{"clientAuth": "optional"}, true, 0, true, "CN=researcher,O=Hospital"},
+	// client certificate required
+	{map[string]interface{}{"clientAuth": "require"}, true, 0, true, "CN=researcher,O=Hospital"},
+	{map[string]interface{}{"clientAuth": "require"}, false, 0, false, ""},
+	// minimum protocol version
+	{map[string]interface{}{"minVersion": "1.3"}, false, tls.VersionTLS12, false, ""},
+	{map[string]interface{}{"minVersion": "1.2", "cipherSuites": []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}, false, tls.VersionTLS12, true, ""},
+}
+
+func TestMutualTLS(t *testing.T) {
+	pki := newTestPKI()
+	defer os.RemoveAll(pki.dir)
+	defer resetTLSProps()
+	defer htslog.SetLogger(htslog.GetLogger())
+
+	for _, tc := range mutualTLSTC {
+		tc.tlsProps["cert"] = pki.serverCert
+		tc.tlsProps["key"] = pki.serverKey
+		tc.tlsProps["clientCA"] = pki.caFile
+		setTLSProps(tc.tlsProps)
+
+		var logBuffer bytes.Buffer
+		htslog.SetLogger(htslog.NewLogger(&logBuffer, htslog.LevelInfo))
+
+		router, _ := SetRouter()
+		server, err := NewServer(router)
+		assert.Nil(t, err)
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		go server.ServeTLS(listener, "", "")
+
+		clientTLSConfig := &tls.Config{RootCAs: pki.caPool, MaxVersion: tc.clientMaxVersion}
+		if tc.clientCert {
+			clientTLSConfig.Certificates = []tls.Certificate{pki.client}
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLSConfig}}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/reads/service-info")
+		if err == nil {
+			resp.Body.Close()
+		}
+		// wait for the access log entry to be written
+		client.CloseIdleConnections()
+		server.Shutdown(context.Background())
+
+		if tc.expOK {
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			entry := map[string]interface{}{}
+			json.Unmarshal(logBuffer.Bytes(), &entry)
+			if tc.expSubject != "" {
+				assert.Equal(t, tc.expSubject, entry["client_subject"])
+			} else {
+				assert.Nil(t, entry["client_subject"])
+			}
+		} else {
+			assert.NotNil(t, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package htsserver
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
+	"github.com/stretchr/testify/assert"
+)
+
+// exportedSpan subset of an OTLP JSON span received by the mock collector
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+}
+
+// mockCollector is a local OTLP/HTTP collector recording all exported spans
+type mockCollector struct {
+	mutex sync.Mutex
+	spans []exportedSpan
+}
+
+func (collector *mockCollector) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	exportRequest := struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []exportedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}{}
+	json.Unmarshal(body, &exportRequest)
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	for _, resourceSpans := range exportRequest.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			collector.spans = append(collector.spans, scopeSpans.Spans...)
+		}
+	}
+}
+
+var tracingTC = []struct {
+	endpoint    string
+	traceparent string
+	expServer   string
+	expTraceID  string
+	expParentID string
+}{
+	{
+		"/reads/service-info",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"GET /reads/service-info",
+		"4bf92f3577b34da6a3ce929d0e0e4736",
+		"00f067aa0ba902b7",
+	},
+	{
+		"/variants/service-info",
+		"",
+		"GET /variants/service-info",
+		"",
+		"",
+	},
+}
+
+func TestTracing(t *testing.T) {
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	for _, tc := range tracingTC {
+		collector := new(mockCollector)
+		collectorServer := httptest.NewServer(collector)
+		htstrace.Configure(collectorServer.URL, "htsget-test")
+
+		request, _ := http.NewRequest(http.MethodGet, server.URL+tc.endpoint, nil)
+		if tc.traceparent != "" {
+			request.Header.Set(htstrace.TraceparentHeader, tc.traceparent)
+		}
+		resp, err := http.DefaultClient.Do(request)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		htstrace.Shutdown(context.Background())
+		collectorServer.Close()
+
+		spansByName := map[string]exportedSpan{}
+		for _, span := range collector.spans {
+			spansByName[span.Name] = span
+		}
+		serverSpan, ok := spansByName[tc.expServer]
+		assert.True(t, ok)
+		assert.Equal(t, tc.expParentID, serverSpan.ParentSpanID)
+		if tc.expTraceID != "" {
+			assert.Equal(t, tc.expTraceID, serverSpan.TraceID)
+		}
+		for _, name := range []string{"stage", "execute"} {
+			assert.Equal(t, serverSpan.TraceID, spansByName[name].TraceID)
+			assert.Equal(t, serverSpan.SpanID, spansByName[name].ParentSpanID)
+		}
+	}
+}
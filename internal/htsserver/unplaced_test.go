@@ -0,0 +1,136 @@
+package htsserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strconv"
+	"testing"
+
+	"github.com/biogo/hts/bgzf"
+	"github.com/ga4gh/htsget-refserver/internal/htsconfig"
+	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
+	"github.com/ga4gh/htsget-refserver/internal/htsticket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnplacedUnmappedTicket(t *testing.T) {
+	setListingConfig(false)
+	router, _ := SetRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/reads/tabulamuris.A1-B000168-3_57_F-1-1_R2?referenceName=*")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	ticket := new(struct {
+		Htsget *htsticket.Container `json:"htsget"`
+	})
+	json.NewDecoder(resp.Body).Decode(ticket)
+
+	// unplaced reads are served by a single body block, following the header
+	assert.Equal(t, 2, len(ticket.Htsget.URLS))
+	body, err := neturl.Parse(ticket.Htsget.URLS[1].URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "*", body.Query().Get("referenceName"))
+	assert.Equal(t, "2", ticket.Htsget.URLS[1].Headers.BlockID)
+
+	// a missing object is not found, rather than missing its index
+	resp, err = http.Get(server.URL + "/reads/tabulamuris.missing?referenceName=*")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+var unplacedUnmappedDataTC = []struct {
+	blockID      string
+	numBlocks    string
+	expEOFMarker bool
+}{
+	{"2", "2", true},
+	{"2", "3", false},
+}
+
+func TestUnplacedUnmappedData(t *testing.T) {
+	setListingConfig(false)
+	for _, tc := range unplacedUnmappedDataTC {
+		handler, recorder := newTestRequestHandler(htsconstants.APIEndpointReadsData, map[string]string{
+			"id":              "tabulamuris.A1-B000168-3_57_F-1-1_R2",
+			"referenceName":   "*",
+			"HtsgetBlockId":   tc.blockID,
+			"HtsgetNumBlocks": tc.numBlocks,
+		})
+		getReadsDataHandler(handler)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, htsconstants.MediaTypeBam, recorder.Header().Get("Content-Type"))
+
+		// the test object has no unplaced reads, the block holds no records
+		body := recorder.Body.Bytes()
+		assert.Equal(t, tc.expEOFMarker, bytes.HasSuffix(body, htsconstants.BamEOF), tc.numBlocks)
+		reader, err := bgzf.NewReader(bytes.NewReader(body), 0)
+		assert.Nil(t, err)
+		records, err := ioutil.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Empty(t, records)
+	}
+
+	// missing objects are not found
+	handler, recorder := newTestRequestHandler(htsconstants.APIEndpointReadsData, map[string]string{
+		"id":              "tabulamuris.missing",
+		"referenceName":   "*",
+		"HtsgetBlockId":   "2",
+		"HtsgetNumBlocks": "2",
+	})
+	getReadsDataHandler(handler)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestUnplacedUnmappedDataFixture(t *testing.T) {
+	configJSON, _ := json.Marshal(map[string]interface{}{
+		"htsgetconfig": map[string]interface{}{
+			"props": map[string]interface{}{
+				"cache": map[string]interface{}{"enabled": false},
+			},
+			"reads": map[string]interface{}{
+				"dataSourceRegistry": map[string]interface{}{
+					"sources": []map[string]string{
+						{
+							"pattern": "^unplaced\\.(?P<accession>.*)$",
+							"path":    "../../data/test/sources/unplaced/{accession}.unplaced.bam",
+						},
+					},
+				},
+			},
+		},
+	})
+	newConfig := new(htsconfig.Configuration)
+	json.Unmarshal(configJSON, newConfig)
+	htsconfig.SetConfigFile(newConfig)
+	htsconfig.LoadConfig()
+	defer setListingConfig(false)
+
+	handler, recorder := newTestRequestHandler(htsconstants.APIEndpointReadsData, map[string]string{
+		"id":              "unplaced.A1-B000168-3_57_F-1-1_R2",
+		"referenceName":   "*",
+		"HtsgetBlockId":   "2",
+		"HtsgetNumBlocks": "2",
+	})
+	getReadsDataHandler(handler)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// the block holds the unplaced reads following the placed reads
+	body := recorder.Body.Bytes()
+	assert.True(t, bytes.HasSuffix(body, htsconstants.BamEOF))
+	reader, err := bgzf.NewReader(bytes.NewReader(body), 0)
+	assert.Nil(t, err)
+	records, err := ioutil.ReadAll(reader)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, string(records), "unplaced"+strconv.Itoa(i)+"\x00")
+	}
+	assert.NotContains(t, string(records), "unplaced10")
+}
@@ -16,6 +16,12 @@ type Headers struct {
 	Range     string `json:"Range,omitempty"`
 	Class     string `json:"HtsgetBlockClass,omitempty"`
 	FilePath  string `json:"HtsgetFilePath,omitempty"`
+	ObjectID  string `json:"HtsgetObjectId,omitempty"`   // id the file path resolves from
+	Split     string `json:"HtsgetBlockSplit,omitempty"` // block continues a split region
+	Previous  string `json:"HtsgetPrevRegion,omitempty"` // region of an earlier block on the reference
+	// W3C trace context of the ticket request, continuing the trace when the
+	// url is requested
+	Traceparent string `json:"traceparent,omitempty"`
 }
 
 // NewHeaders instantiates an empty headers object
@@ -61,9 +67,37 @@ func (headers *Headers) SetClassBody() *Headers {
 	return headers
 }
 
+// SetSplitHeader assigns the Split header value, indicating the data download
+// url continues a region split across several body blocks
+func (headers *Headers) SetSplitHeader() *Headers {
+	headers.Split = "true"
+	return headers
+}
+
+// SetPrevRegionHeader assigns the Previous header value, the region served by
+// an earlier body block on the same reference sequence
+func (headers *Headers) SetPrevRegionHeader(region string) *Headers {
+	headers.Previous = region
+	return headers
+}
+
 // SetFilePathHeader assigns the FilePath header, informing the data or
 // file bytes endpoint of which file to stream back to client
 func (headers *Headers) SetFilePathHeader(filePath string) *Headers {
 	headers.FilePath = filePath
 	return headers
 }
+
+// SetObjectIDHeader assigns the ObjectID header, informing the file bytes
+// endpoint of the object id the file path was resolved from
+func (headers *Headers) SetObjectIDHeader(id string) *Headers {
+	headers.ObjectID = id
+	return headers
+}
+
+// SetTraceparent assigns the W3C Trace Context traceparent header, so that
+// the data download request continues the trace of the ticket request
+func (headers *Headers) SetTraceparent(traceparent string) *Headers {
+	headers.Traceparent = traceparent
+	return headers
+}
@@ -4,6 +4,8 @@
 package htsticket
 
 import (
+	"encoding/base64"
+
 	"github.com/ga4gh/htsget-refserver/internal/htsconstants"
 )
 
@@ -25,6 +27,13 @@ func (urlObj *URL) SetURL(url string) *URL {
 	return urlObj
 }
 
+// SetDataURI assigns a data uri embedding the filepart as base64, so that the
+// client does not need to make a request to download it
+func (urlObj *URL) SetDataURI(mediaType string, data []byte) *URL {
+	urlObj.URL = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	return urlObj
+}
+
 // SetHeaders assign all headers necessary to access the data
 func (urlObj *URL) SetHeaders(headers *Headers) *URL {
 	urlObj.Headers = headers
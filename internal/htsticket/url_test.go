@@ -61,3 +61,8 @@ func TestUrlSetClass(t *testing.T) {
 		assert.Equal(t, exp[i], url.Class)
 	}
 }
+
+func TestUrlSetDataURI(t *testing.T) {
+	url := NewURL().SetDataURI(htsconstants.MediaTypeBam, []byte("BAM\x01"))
+	assert.Equal(t, "data:application/vnd.ga4gh.bam;base64,QkFNAQ==", url.URL)
+}
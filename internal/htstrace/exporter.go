@@ -0,0 +1,291 @@
+// Package htstrace records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP collector
+//
+// Module exporter batches completed spans and sends them to an OTLP/HTTP
+// collector, JSON encoded
+package htstrace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// otlpTracesPath path of the OTLP/HTTP traces endpoint on the collector
+const otlpTracesPath = "/v1/traces"
+
+// exporterBatchSize number of queued spans that triggers an export
+const exporterBatchSize = 256
+
+// exporterMaxQueueSize number of queued spans beyond which new spans are
+// dropped, so that an unavailable collector cannot exhaust memory
+const exporterMaxQueueSize = 8192
+
+// exporterInterval maximum time spans remain queued before being exported
+const exporterInterval = 5 * time.Second
+
+// Exporter queues completed spans and exports them in batches to an OTLP/HTTP
+// collector
+type Exporter struct {
+	mutex       sync.Mutex
+	url         string
+	serviceName string
+	client      *http.Client
+	queue       []*Span
+	flushSignal chan struct{}
+	stopSignal  chan struct{}
+	stopped     chan struct{}
+}
+
+// NewExporter instantiates an Exporter sending spans to the collector at
+// endpoint (e.g. "http://localhost:4318"), and starts its export loop
+func NewExporter(endpoint string, serviceName string) *Exporter {
+	exporter := new(Exporter)
+	exporter.url = strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+	exporter.serviceName = serviceName
+	exporter.client = &http.Client{Timeout: 10 * time.Second}
+	exporter.flushSignal = make(chan struct{}, 1)
+	exporter.stopSignal = make(chan struct{})
+	exporter.stopped = make(chan struct{})
+	go exporter.loop()
+	return exporter
+}
+
+// enqueue adds an ended span to the export queue
+func (exporter *Exporter) enqueue(span *Span) {
+	exporter.mutex.Lock()
+	defer exporter.mutex.Unlock()
+	if len(exporter.queue) >= exporterMaxQueueSize {
+		return
+	}
+	exporter.queue = append(exporter.queue, span)
+	if len(exporter.queue) >= exporterBatchSize {
+		select {
+		case exporter.flushSignal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// loop exports queued spans periodically, or when a full batch is queued,
+// until the exporter is shut down
+func (exporter *Exporter) loop() {
+	defer close(exporter.stopped)
+	ticker := time.NewTicker(exporterInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			exporter.Flush(context.Background())
+		case <-exporter.flushSignal:
+			exporter.Flush(context.Background())
+		case <-exporter.stopSignal:
+			return
+		}
+	}
+}
+
+// Flush immediately exports all queued spans
+func (exporter *Exporter) Flush(ctx context.Context) error {
+	exporter.mutex.Lock()
+	spans := exporter.queue
+	exporter.queue = nil
+	exporter.mutex.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(exporter.newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, exporter.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	response, err := exporter.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return errors.New("collector responded with status " + response.Status)
+	}
+	return nil
+}
+
+// Shutdown stops the export loop and exports any remaining spans
+func (exporter *Exporter) Shutdown(ctx context.Context) error {
+	close(exporter.stopSignal)
+	<-exporter.stopped
+	return exporter.Flush(ctx)
+}
+
+/* **************************************************
+ * OTLP JSON ENCODING
+ * ************************************************** */
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    statusCode `json:"code,omitempty"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// newOTLPKeyValue encodes a single attribute, converting the value to the
+// matching OTLP value type (unrecognized types are encoded as strings)
+func newOTLPKeyValue(key string, value interface{}) otlpKeyValue {
+	keyValue := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		keyValue.Value.StringValue = &v
+	case int:
+		s := strconv.Itoa(v)
+		keyValue.Value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		keyValue.Value.IntValue = &s
+	case float64:
+		keyValue.Value.DoubleValue = &v
+	case bool:
+		keyValue.Value.BoolValue = &v
+	default:
+		s, _ := json.Marshal(v)
+		str := string(s)
+		keyValue.Value.StringValue = &str
+	}
+	return keyValue
+}
+
+// newExportRequest encodes spans as an OTLP trace export request
+func (exporter *Exporter) newExportRequest(spans []*Span) *otlpExportRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mutex.Lock()
+		otlpSpan := otlpSpan{
+			TraceID:           span.spanContext.TraceID.String(),
+			SpanID:            span.spanContext.SpanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:            otlpStatus{Code: span.status, Message: span.statusMessage},
+		}
+		if span.parentSpanID.IsValid() {
+			otlpSpan.ParentSpanID = span.parentSpanID.String()
+		}
+		for key, value := range span.attributes {
+			otlpSpan.Attributes = append(otlpSpan.Attributes, newOTLPKeyValue(key, value))
+		}
+		span.mutex.Unlock()
+		otlpSpans = append(otlpSpans, otlpSpan)
+	}
+
+	return &otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource: otlpResource{
+					Attributes: []otlpKeyValue{newOTLPKeyValue("service.name", exporter.serviceName)},
+				},
+				ScopeSpans: []otlpScopeSpans{
+					{
+						Scope: otlpScope{Name: exporter.serviceName},
+						Spans: otlpSpans,
+					},
+				},
+			},
+		},
+	}
+}
+
+/* **************************************************
+ * EXPORTER SINGLETON
+ * ************************************************** */
+
+// exporterSingleton (*Exporter): exporter receiving all ended spans. tracing
+// is disabled while nil
+var exporterSingleton *Exporter
+
+var exporterSingletonMutex sync.RWMutex
+
+// getExporter gets the configured exporter, or nil if tracing is disabled
+func getExporter() *Exporter {
+	exporterSingletonMutex.RLock()
+	defer exporterSingletonMutex.RUnlock()
+	return exporterSingleton
+}
+
+// Configure enables tracing, exporting spans to the OTLP/HTTP collector at
+// endpoint. an empty endpoint leaves tracing disabled
+func Configure(endpoint string, serviceName string) {
+	if endpoint == "" {
+		return
+	}
+	exporterSingletonMutex.Lock()
+	defer exporterSingletonMutex.Unlock()
+	exporterSingleton = NewExporter(endpoint, serviceName)
+}
+
+// Shutdown disables tracing, exporting any remaining queued spans
+func Shutdown(ctx context.Context) error {
+	exporterSingletonMutex.Lock()
+	exporter := exporterSingleton
+	exporterSingleton = nil
+	exporterSingletonMutex.Unlock()
+	if exporter == nil {
+		return nil
+	}
+	return exporter.Shutdown(ctx)
+}
@@ -0,0 +1,83 @@
+// Package htstrace records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP collector
+//
+// Module exporter_test tests module exporter
+package htstrace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockCollector records the OTLP export requests it receives
+type mockCollector struct {
+	mutex    sync.Mutex
+	paths    []string
+	requests []*otlpExportRequest
+}
+
+func (collector *mockCollector) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	exportRequest := new(otlpExportRequest)
+	json.Unmarshal(body, exportRequest)
+	collector.mutex.Lock()
+	collector.paths = append(collector.paths, request.URL.Path)
+	collector.requests = append(collector.requests, exportRequest)
+	collector.mutex.Unlock()
+}
+
+func TestStartDisabled(t *testing.T) {
+	ctx, span := Start(context.Background(), "disabled", SpanKindInternal)
+	assert.Nil(t, span)
+	assert.Equal(t, "", Traceparent(ctx))
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("error"))
+	span.End()
+}
+
+func TestExport(t *testing.T) {
+	collector := new(mockCollector)
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	Configure(server.URL, "htsget-test")
+	ctx, parent := Start(context.Background(), "parent", SpanKindServer)
+	_, child := Start(ctx, "child", SpanKindInternal)
+	child.SetAttribute("htsget.id", "object1")
+	child.SetAttribute("blocks", 2)
+	child.SetError(errors.New("samtools failed"))
+	child.End()
+	parent.End()
+	Shutdown(context.Background())
+
+	assert.Equal(t, []string{"/v1/traces"}, collector.paths)
+	resourceSpans := collector.requests[0].ResourceSpans[0]
+	assert.Equal(t, "service.name", resourceSpans.Resource.Attributes[0].Key)
+	assert.Equal(t, "htsget-test", *resourceSpans.Resource.Attributes[0].Value.StringValue)
+
+	spans := resourceSpans.ScopeSpans[0].Spans
+	assert.Equal(t, 2, len(spans))
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, "parent", spans[1].Name)
+	assert.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, "", spans[1].ParentSpanID)
+	assert.Equal(t, SpanKindServer, spans[1].Kind)
+	assert.Equal(t, statusCodeError, spans[0].Status.Code)
+	assert.Equal(t, "samtools failed", spans[0].Status.Message)
+
+	attributes := map[string]otlpAnyValue{}
+	for _, keyValue := range spans[0].Attributes {
+		attributes[keyValue.Key] = keyValue.Value
+	}
+	assert.Equal(t, "object1", *attributes["htsget.id"].StringValue)
+	assert.Equal(t, "2", *attributes["blocks"].IntValue)
+}
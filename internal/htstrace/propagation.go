@@ -0,0 +1,83 @@
+// Package htstrace records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP collector
+//
+// Module propagation reads and writes the W3C Trace Context "traceparent"
+// header, so that traces continue across requests
+package htstrace
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader name of the W3C Trace Context header
+const TraceparentHeader = "traceparent"
+
+// traceparentVersion the only W3C Trace Context version produced
+const traceparentVersion = "00"
+
+// traceparentFlagsSampled trace flags marking the trace as sampled
+const traceparentFlagsSampled = "01"
+
+// FormatTraceparent renders a span context as a traceparent header value.
+// returns an empty string if the span context is invalid
+func FormatTraceparent(spanContext SpanContext) string {
+	if !spanContext.IsValid() {
+		return ""
+	}
+	return traceparentVersion + "-" + spanContext.TraceID.String() + "-" + spanContext.SpanID.String() + "-" + traceparentFlagsSampled
+}
+
+// ParseTraceparent parses a traceparent header value into a span context.
+// the returned span context is invalid if the value is malformed
+func ParseTraceparent(value string) SpanContext {
+	var spanContext SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}
+	}
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return SpanContext{}
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return SpanContext{}
+	}
+	copy(spanContext.TraceID[:], traceID)
+	copy(spanContext.SpanID[:], spanID)
+	if !spanContext.IsValid() {
+		return SpanContext{}
+	}
+	return spanContext
+}
+
+// Traceparent gets the traceparent header value for the span carried by ctx,
+// or an empty string if there is none
+func Traceparent(ctx context.Context) string {
+	return FormatTraceparent(SpanContextFromContext(ctx))
+}
+
+// Extract returns a copy of ctx carrying the remote span context found in the
+// traceparent header, if present and valid
+func Extract(ctx context.Context, header http.Header) context.Context {
+	spanContext := ParseTraceparent(header.Get(TraceparentHeader))
+	if !spanContext.IsValid() {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, spanContext)
+}
+
+// Inject sets the traceparent header for the span carried by ctx, so that
+// the receiving service can continue the trace
+func Inject(ctx context.Context, header http.Header) {
+	traceparent := Traceparent(ctx)
+	if traceparent != "" {
+		header.Set(TraceparentHeader, traceparent)
+	}
+}
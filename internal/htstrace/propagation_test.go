@@ -0,0 +1,61 @@
+// Package htstrace records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP collector
+//
+// Module propagation_test tests module propagation
+package htstrace
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var parseTraceparentTC = []struct {
+	value      string
+	expValid   bool
+	expTraceID string
+	expSpanID  string
+}{
+	{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		true,
+		"4bf92f3577b34da6a3ce929d0e0e4736",
+		"00f067aa0ba902b7",
+	},
+	{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, "", ""},
+	{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, "", ""},
+	{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, "", ""},
+	{"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", false, "", ""},
+	{"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, "", ""},
+	{"", false, "", ""},
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tc := range parseTraceparentTC {
+		spanContext := ParseTraceparent(tc.value)
+		assert.Equal(t, tc.expValid, spanContext.IsValid())
+		if tc.expValid {
+			assert.Equal(t, tc.expTraceID, spanContext.TraceID.String())
+			assert.Equal(t, tc.expSpanID, spanContext.SpanID.String())
+			assert.Equal(t, tc.value, FormatTraceparent(spanContext))
+		}
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	for _, tc := range parseTraceparentTC {
+		incoming := http.Header{}
+		incoming.Set(TraceparentHeader, tc.value)
+		ctx := Extract(context.Background(), incoming)
+
+		outgoing := http.Header{}
+		Inject(ctx, outgoing)
+		if tc.expValid {
+			assert.Equal(t, tc.value, outgoing.Get(TraceparentHeader))
+		} else {
+			assert.Equal(t, "", outgoing.Get(TraceparentHeader))
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package htstrace records OpenTelemetry-compatible trace spans and exports
+// them to an OTLP collector
+//
+// Module span contains trace/span identifiers, spans, and their propagation
+// through request contexts
+package htstrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// TraceID uniquely identifies a trace (a tree of spans)
+type TraceID [16]byte
+
+// SpanID uniquely identifies a span within a trace
+type SpanID [8]byte
+
+// String gets the lowercase hex representation of a TraceID
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that a TraceID is not all zeroes
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String gets the lowercase hex representation of a SpanID
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid checks that a SpanID is not all zeroes
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+// SpanKind enum for the role of a span, as defined by OpenTelemetry
+type SpanKind int
+
+// enum values for SpanKind (values match the OTLP protocol)
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// statusCode enum for span status (values match the OTLP protocol)
+type statusCode int
+
+// enum values for statusCode
+const (
+	statusCodeUnset statusCode = 0
+	statusCodeError statusCode = 2
+)
+
+// SpanContext holds the identifiers of a span that are propagated to child
+// spans, both in-process and across requests
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid checks that both identifiers of the span context are set
+func (spanContext SpanContext) IsValid() bool {
+	return spanContext.TraceID.IsValid() && spanContext.SpanID.IsValid()
+}
+
+// Span is a single timed operation within a trace. all methods are safe to
+// call on a nil Span, which is returned when tracing is disabled
+type Span struct {
+	mutex         sync.Mutex
+	spanContext   SpanContext
+	parentSpanID  SpanID
+	name          string
+	kind          SpanKind
+	start         time.Time
+	end           time.Time
+	attributes    map[string]interface{}
+	status        statusCode
+	statusMessage string
+	ended         bool
+}
+
+// SpanContext gets the propagated identifiers of the span
+func (span *Span) SpanContext() SpanContext {
+	if span == nil {
+		return SpanContext{}
+	}
+	return span.spanContext
+}
+
+// SetAttribute attaches a key-value attribute to the span. values may be
+// strings, integers, floats or booleans
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.attributes[key] = value
+}
+
+// SetError marks the span as failed with the error's message. nil errors are
+// ignored
+func (span *Span) SetError(err error) {
+	if span == nil || err == nil {
+		return
+	}
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	span.status = statusCodeError
+	span.statusMessage = err.Error()
+}
+
+// End completes the span and queues it for export. calling End more than
+// once has no effect
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.end = time.Now()
+	span.mutex.Unlock()
+
+	if exporter := getExporter(); exporter != nil {
+		exporter.enqueue(span)
+	}
+}
+
+type ctxKeySpanContext int
+
+// spanContextKey is the context key holding the current SpanContext
+const spanContextKey ctxKeySpanContext = 0
+
+// ContextWithSpanContext returns a copy of ctx carrying spanContext as the
+// parent of spans subsequently started from it
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, spanContext)
+}
+
+// SpanContextFromContext gets the current SpanContext carried by ctx, which
+// is invalid (zero) if there is none
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	spanContext, _ := ctx.Value(spanContextKey).(SpanContext)
+	return spanContext
+}
+
+// Start begins a new span as a child of the span carried by ctx (or as the
+// root of a new trace if there is none), returning a context carrying the new
+// span. if tracing is disabled, ctx is returned unchanged with a nil Span
+//
+// Arguments
+//	ctx (context.Context): context carrying the parent span, if any
+//	name (string): name of the operation
+//	kind (SpanKind): role of the span
+// Returns
+//	(context.Context): context carrying the new span
+//	(*Span): the started span, to be ended by the caller
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if getExporter() == nil {
+		return ctx, nil
+	}
+
+	span := new(Span)
+	span.name = name
+	span.kind = kind
+	span.start = time.Now()
+	span.attributes = make(map[string]interface{})
+
+	parent := SpanContextFromContext(ctx)
+	if parent.IsValid() {
+		span.spanContext.TraceID = parent.TraceID
+		span.parentSpanID = parent.SpanID
+	} else {
+		span.spanContext.TraceID = newTraceID()
+	}
+	span.spanContext.SpanID = newSpanID()
+	return ContextWithSpanContext(ctx, span.spanContext), span
+}
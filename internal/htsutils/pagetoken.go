@@ -0,0 +1,37 @@
+// Package htsutils provides general, high-level, reusable functions
+//
+// Module pagetoken encodes and decodes the opaque tokens clients pass to
+// request the next page of a listing
+package htsutils
+
+import (
+	"encoding/base64"
+	"errors"
+)
+
+// EncodePageToken encodes the last item of a page as the token requesting
+// the next page
+//
+// Arguments
+//	last (string): last item of the current page
+// Returns
+//	(string): opaque page token
+func EncodePageToken(last string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(last))
+}
+
+// DecodePageToken decodes a page token into the last item of the previous
+// page
+//
+// Arguments
+//	token (string): opaque page token
+// Returns
+//	(string): last item of the previous page
+//	(error): if not nil, the token was not issued by EncodePageToken
+func DecodePageToken(token string) (string, error) {
+	last, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(last) == 0 {
+		return "", errors.New("invalid page token")
+	}
+	return string(last), nil
+}
@@ -0,0 +1,102 @@
+// Package htsutils provides general, high-level, reusable functions
+//
+// Module remote contains operations for making requests to remote (url)
+// data sources
+package htsutils
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ga4gh/htsget-refserver/internal/htsmetrics"
+	"github.com/ga4gh/htsget-refserver/internal/htstrace"
+)
+
+// HeadObject makes a HEAD request for a remote object. the request is traced
+// as a child of the span carried by ctx, and its latency recorded
+//
+// Arguments
+//	ctx (context.Context): request context carrying the current trace span
+//	objURL (string): url of the remote object
+// Returns
+//	(*http.Response): response to the HEAD request
+//	(error): if not nil, the request could not be made
+func HeadObject(ctx context.Context, objURL string) (*http.Response, error) {
+	ctx, span := htstrace.Start(ctx, "HEAD", htstrace.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("http.url", objURL)
+
+	request, err := http.NewRequest(http.MethodHead, objURL, nil)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	htstrace.Inject(ctx, request.Header)
+
+	start := time.Now()
+	res, err := http.DefaultClient.Do(request)
+	htsmetrics.ObserveUpstreamHead(start)
+	span.SetError(err)
+	if res != nil {
+		res.Body.Close()
+		span.SetAttribute("http.status_code", res.StatusCode)
+	}
+	return res, err
+}
+
+// GetObject makes a GET request for a remote object. the request is traced
+// as a child of the span carried by ctx. the caller must close the response
+// body
+//
+// Arguments
+//	ctx (context.Context): request context carrying the current trace span
+//	objURL (string): url of the remote object
+// Returns
+//	(*http.Response): response to the GET request
+//	(error): if not nil, the request could not be made
+func GetObject(ctx context.Context, objURL string) (*http.Response, error) {
+	return getObject(ctx, objURL, "")
+}
+
+// GetObjectFrom makes a GET request for the bytes of a remote object from
+// an offset to its end. the caller must close the response body
+//
+// Arguments
+//	ctx (context.Context): request context carrying the current trace span
+//	objURL (string): url of the remote object
+//	start (int64): offset of the first byte requested
+// Returns
+//	(*http.Response): response to the GET request, 206 if the range is served
+//	(error): if not nil, the request could not be made
+func GetObjectFrom(ctx context.Context, objURL string, start int64) (*http.Response, error) {
+	return getObject(ctx, objURL, "bytes="+strconv.FormatInt(start, 10)+"-")
+}
+
+// getObject makes a traced GET request for a remote object, for a byte range
+// of it if byteRange is set
+func getObject(ctx context.Context, objURL string, byteRange string) (*http.Response, error) {
+	ctx, span := htstrace.Start(ctx, "GET", htstrace.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("http.url", objURL)
+
+	request, err := http.NewRequest(http.MethodGet, objURL, nil)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	if byteRange != "" {
+		request.Header.Set("Range", byteRange)
+	}
+	htstrace.Inject(ctx, request.Header)
+
+	res, err := http.DefaultClient.Do(request)
+	span.SetError(err)
+	if res != nil {
+		span.SetAttribute("http.status_code", res.StatusCode)
+	}
+	return res, err
+}